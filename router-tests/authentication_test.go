@@ -2,12 +2,17 @@ package integration_test
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/require"
 	"github.com/wundergraph/cosmo/router-tests/jwks"
 	"github.com/wundergraph/cosmo/router-tests/testenv"
@@ -243,6 +248,61 @@ func TestAuthentication(t *testing.T) {
 			require.Equal(t, `{"errors":[{"message":"Unauthorized"}],"data":null,"extensions":{"authorization":{"missingScopes":[{"coordinate":{"typeName":"Employee","fieldName":"startDate"},"required":[["read:employee","read:private"],["read:all"]]}],"actualScopes":["read:employee"]}}}`, string(data))
 		})
 	})
+	t.Run("scopes from configured claims", func(t *testing.T) {
+		t.Parallel()
+
+		authenticators, authServer := configureAuth(t)
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				core.WithAccessController(core.NewAccessController(authenticators, false)),
+				core.WithAuthorizationConfig(&config.AuthorizationConfiguration{
+					RejectOperationIfUnauthorized: true,
+					Scopes: config.AuthorizationScopes{
+						ClaimPaths: []string{"permissions", "realm_access.roles"},
+						PrefixMappings: []config.ScopePrefixMapping{
+							{From: "employees-api:", To: ""},
+						},
+					},
+				}),
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			token, err := authServer.Token(map[string]any{
+				"scope":       "read:all",
+				"permissions": []string{"employees-api:read:employee"},
+				"realm_access": map[string]any{
+					"roles": []string{"read:private"},
+				},
+			})
+			require.NoError(t, err)
+			header := http.Header{
+				"Authorization": []string{"Bearer " + token},
+			}
+			res, err := xEnv.MakeRequest(http.MethodPost, "/graphql", header, strings.NewReader(employeesQueryRequiringClaims))
+			require.NoError(t, err)
+			defer res.Body.Close()
+			require.Equal(t, http.StatusOK, res.StatusCode)
+			data, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			require.Equal(t, `{"data":{"employees":[{"id":1,"startDate":"January 2020"},{"id":2,"startDate":"July 2022"},{"id":3,"startDate":"June 2021"},{"id":4,"startDate":"July 2022"},{"id":5,"startDate":"July 2022"},{"id":7,"startDate":"September 2022"},{"id":8,"startDate":"September 2022"},{"id":10,"startDate":"November 2022"},{"id":11,"startDate":"November 2022"},{"id":12,"startDate":"December 2022"}]}}`, string(data))
+
+			// The "scope" claim is ignored if other claims are configured
+			token, err = authServer.Token(map[string]any{
+				"scope":       "read:all",
+				"permissions": []string{"employees-api:read:employee"},
+			})
+			require.NoError(t, err)
+			header = http.Header{
+				"Authorization": []string{"Bearer " + token},
+			}
+			res, err = xEnv.MakeRequest(http.MethodPost, "/graphql", header, strings.NewReader(employeesQueryRequiringClaims))
+			require.NoError(t, err)
+			defer res.Body.Close()
+			require.Equal(t, http.StatusOK, res.StatusCode)
+			data, err = io.ReadAll(res.Body)
+			require.NoError(t, err)
+			require.Equal(t, `{"errors":[{"message":"Unauthorized"}],"data":null,"extensions":{"authorization":{"missingScopes":[{"coordinate":{"typeName":"Employee","fieldName":"startDate"},"required":[["read:employee","read:private"],["read:all"]]}],"actualScopes":["read:employee"]}}}`, string(bytes.TrimSpace(data)))
+		})
+	})
 	t.Run("reject unauthorized no scope", func(t *testing.T) {
 		t.Parallel()
 
@@ -662,6 +722,317 @@ func TestAuthenticationWithCustomHeaders(t *testing.T) {
 	})
 }
 
+func TestAuthenticationClaimValidation(t *testing.T) {
+	t.Parallel()
+
+	authServer, err := jwks.NewServer(t)
+	require.NoError(t, err)
+	t.Cleanup(authServer.Close)
+	tokenDecoder, err := authentication.NewValidatingJwksTokenDecoder(authServer.JWKSURL(), time.Second*5, authentication.TokenValidationOptions{
+		Issuer:         "https://auth.example.com",
+		Audiences:      []string{"employees-graph", "cosmo-router"},
+		RequiredClaims: []string{"sub"},
+		Leeway:         time.Minute,
+	})
+	require.NoError(t, err)
+	authenticator, err := authentication.NewHttpHeaderAuthenticator(authentication.HttpHeaderAuthenticatorOptions{
+		Name:         jwksName,
+		URL:          authServer.JWKSURL(),
+		TokenDecoder: tokenDecoder,
+	})
+	require.NoError(t, err)
+	authenticators := []authentication.Authenticator{authenticator}
+
+	validClaims := func() map[string]any {
+		return map[string]any{
+			"iss": "https://auth.example.com",
+			"aud": []string{"cosmo-router"},
+			"sub": "user",
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		claims   func(claims map[string]any)
+		expected string
+	}{
+		{
+			name:     "wrong issuer",
+			claims:   func(claims map[string]any) { claims["iss"] = "https://other.example.com" },
+			expected: `{"errors":[{"message":"unauthorized: token issuer is not accepted"}]}`,
+		},
+		{
+			name:     "token minted for another service",
+			claims:   func(claims map[string]any) { claims["aud"] = "billing-service" },
+			expected: `{"errors":[{"message":"unauthorized: token audience is not accepted"}]}`,
+		},
+		{
+			name:     "missing required claim",
+			claims:   func(claims map[string]any) { delete(claims, "sub") },
+			expected: `{"errors":[{"message":"unauthorized: token is missing the required claim 'sub'"}]}`,
+		},
+		{
+			name:     "expired beyond leeway",
+			claims:   func(claims map[string]any) { claims["exp"] = time.Now().Add(-2 * time.Minute).Unix() },
+			expected: `{"errors":[{"message":"unauthorized: token is expired"}]}`,
+		},
+	}
+
+	testenv.Run(t, &testenv.Config{
+		RouterOptions: []core.Option{
+			core.WithAccessController(core.NewAccessController(authenticators, false)),
+		},
+	}, func(t *testing.T, xEnv *testenv.Environment) {
+		for _, tc := range testCases {
+			claims := validClaims()
+			tc.claims(claims)
+			token, err := authServer.Token(claims)
+			require.NoError(t, err)
+			header := http.Header{
+				"Authorization": []string{"Bearer " + token},
+			}
+			res, err := xEnv.MakeRequest(http.MethodPost, "/graphql", header, strings.NewReader(employeesQuery))
+			require.NoError(t, err)
+			require.Equal(t, http.StatusUnauthorized, res.StatusCode, tc.name)
+			data, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			require.NoError(t, res.Body.Close())
+			require.Equal(t, tc.expected, string(data), tc.name)
+		}
+
+		// Expired within the leeway
+		claims := validClaims()
+		claims["exp"] = time.Now().Add(-30 * time.Second).Unix()
+		token, err := authServer.Token(claims)
+		require.NoError(t, err)
+		header := http.Header{
+			"Authorization": []string{"Bearer " + token},
+		}
+		res, err := xEnv.MakeRequest(http.MethodPost, "/graphql", header, strings.NewReader(employeesQuery))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, jwksName, res.Header.Get(xAuthenticatedByHeader))
+		data, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, employeesExpectedData, string(data))
+	})
+
+	t.Run("algorithm not allowed", func(t *testing.T) {
+		t.Parallel()
+
+		tokenDecoder, err := authentication.NewValidatingJwksTokenDecoder(authServer.JWKSURL(), time.Second*5, authentication.TokenValidationOptions{
+			AllowedAlgorithms: []string{"ES256"},
+		})
+		require.NoError(t, err)
+		authenticator, err := authentication.NewHttpHeaderAuthenticator(authentication.HttpHeaderAuthenticatorOptions{
+			Name:         jwksName,
+			URL:          authServer.JWKSURL(),
+			TokenDecoder: tokenDecoder,
+		})
+		require.NoError(t, err)
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				core.WithAccessController(core.NewAccessController([]authentication.Authenticator{authenticator}, false)),
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			token, err := authServer.Token(nil)
+			require.NoError(t, err)
+			header := http.Header{
+				"Authorization": []string{"Bearer " + token},
+			}
+			res, err := xEnv.MakeRequest(http.MethodPost, "/graphql", header, strings.NewReader(employeesQuery))
+			require.NoError(t, err)
+			defer res.Body.Close()
+			require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+			data, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			require.Equal(t, `{"errors":[{"message":"unauthorized: token signing algorithm 'RS256' is not allowed"}]}`, string(data))
+		})
+	})
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	t.Parallel()
+
+	const apiKey = "my-secret-api-key"
+	hash := sha256.Sum256([]byte(apiKey))
+	tokenDecoder, err := authentication.NewAPIKeyTokenDecoder([]authentication.APIKey{
+		{
+			Name:   "ci",
+			Hash:   hex.EncodeToString(hash[:]),
+			Scopes: []string{"read:all"},
+		},
+	})
+	require.NoError(t, err)
+	authenticator, err := authentication.NewHttpHeaderAuthenticator(authentication.HttpHeaderAuthenticatorOptions{
+		Name:                "api-keys",
+		HeaderNames:         []string{"X-API-Key"},
+		HeaderValuePrefixes: []string{""},
+		TokenDecoder:        tokenDecoder,
+	})
+	require.NoError(t, err)
+
+	testenv.Run(t, &testenv.Config{
+		RouterOptions: []core.Option{
+			core.WithAccessController(core.NewAccessController([]authentication.Authenticator{authenticator}, false)),
+		},
+	}, func(t *testing.T, xEnv *testenv.Environment) {
+		// The scopes of the key are used to authorize the fields
+		header := http.Header{
+			"X-API-Key": []string{apiKey},
+		}
+		res, err := xEnv.MakeRequest(http.MethodPost, "/graphql", header, strings.NewReader(employeesQueryRequiringClaims))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, "api-keys", res.Header.Get(xAuthenticatedByHeader))
+		data, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, `{"data":{"employees":[{"id":1,"startDate":"January 2020"},{"id":2,"startDate":"July 2022"},{"id":3,"startDate":"June 2021"},{"id":4,"startDate":"July 2022"},{"id":5,"startDate":"July 2022"},{"id":7,"startDate":"September 2022"},{"id":8,"startDate":"September 2022"},{"id":10,"startDate":"November 2022"},{"id":11,"startDate":"November 2022"},{"id":12,"startDate":"December 2022"}]}}`, string(data))
+
+		header = http.Header{
+			"X-API-Key": []string{"unknown-key"},
+		}
+		res, err = xEnv.MakeRequest(http.MethodPost, "/graphql", header, strings.NewReader(employeesQuery))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		data, err = io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, unauthorizedExpectedData, string(data))
+
+		// Authentication isn't required, so requests without a key are anonymous
+		res, err = xEnv.MakeRequest(http.MethodPost, "/graphql", nil, strings.NewReader(employeesQuery))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, "", res.Header.Get(xAuthenticatedByHeader))
+		data, err = io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, employeesExpectedData, string(data))
+	})
+}
+
+func TestSharedSecretAuthentication(t *testing.T) {
+	t.Parallel()
+
+	secret := []byte("my-local-secret")
+	tokenDecoder, err := authentication.NewSharedSecretTokenDecoder(secret, authentication.TokenValidationOptions{})
+	require.NoError(t, err)
+	authenticator, err := authentication.NewHttpHeaderAuthenticator(authentication.HttpHeaderAuthenticatorOptions{
+		Name:         "shared-secret",
+		TokenDecoder: tokenDecoder,
+	})
+	require.NoError(t, err)
+
+	testenv.Run(t, &testenv.Config{
+		RouterOptions: []core.Option{
+			core.WithAccessController(core.NewAccessController([]authentication.Authenticator{authenticator}, false)),
+		},
+	}, func(t *testing.T, xEnv *testenv.Environment) {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "developer"}).SignedString(secret)
+		require.NoError(t, err)
+		header := http.Header{
+			"Authorization": []string{"Bearer " + token},
+		}
+		res, err := xEnv.MakeRequest(http.MethodPost, "/graphql", header, strings.NewReader(employeesQuery))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, "shared-secret", res.Header.Get(xAuthenticatedByHeader))
+		data, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, employeesExpectedData, string(data))
+
+		// Only HS256 is accepted by default
+		token, err = jwt.NewWithClaims(jwt.SigningMethodHS512, jwt.MapClaims{"sub": "developer"}).SignedString(secret)
+		require.NoError(t, err)
+		header = http.Header{
+			"Authorization": []string{"Bearer " + token},
+		}
+		res, err = xEnv.MakeRequest(http.MethodPost, "/graphql", header, strings.NewReader(employeesQuery))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		data, err = io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, `{"errors":[{"message":"unauthorized: token signing algorithm 'HS512' is not allowed"}]}`, string(data))
+	})
+}
+
+func TestIntrospectionAuthentication(t *testing.T) {
+	t.Parallel()
+
+	var introspections atomic.Int32
+	authServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		introspections.Add(1)
+		clientID, clientSecret, ok := r.BasicAuth()
+		if !ok || clientID != "cosmo-router" || clientSecret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.PostFormValue("token") {
+		case "active-token":
+			_, _ = w.Write([]byte(`{"active":true,"sub":"user","scope":"read:employee read:private"}`))
+		default:
+			_, _ = w.Write([]byte(`{"active":false}`))
+		}
+	}))
+	t.Cleanup(authServer.Close)
+
+	tokenDecoder, err := authentication.NewIntrospectionTokenDecoder(authentication.IntrospectionTokenDecoderOptions{
+		URL:          authServer.URL,
+		ClientID:     "cosmo-router",
+		ClientSecret: "secret",
+		CacheTTL:     time.Minute,
+	})
+	require.NoError(t, err)
+	authenticator, err := authentication.NewHttpHeaderAuthenticator(authentication.HttpHeaderAuthenticatorOptions{
+		Name:         "introspection",
+		URL:          authServer.URL,
+		TokenDecoder: tokenDecoder,
+	})
+	require.NoError(t, err)
+	t.Cleanup(authenticator.Close)
+
+	testenv.Run(t, &testenv.Config{
+		RouterOptions: []core.Option{
+			core.WithAccessController(core.NewAccessController([]authentication.Authenticator{authenticator}, false)),
+		},
+	}, func(t *testing.T, xEnv *testenv.Environment) {
+		header := http.Header{
+			"Authorization": []string{"Bearer active-token"},
+		}
+		for i := 0; i < 2; i++ {
+			res, err := xEnv.MakeRequest(http.MethodPost, "/graphql", header, strings.NewReader(employeesQuery))
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, res.StatusCode)
+			require.Equal(t, "introspection", res.Header.Get(xAuthenticatedByHeader))
+			data, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			require.NoError(t, res.Body.Close())
+			require.Equal(t, employeesExpectedData, string(data))
+			// The result is cached, the cache is eventually consistent
+			time.Sleep(50 * time.Millisecond)
+		}
+		require.Equal(t, int32(1), introspections.Load())
+
+		header = http.Header{
+			"Authorization": []string{"Bearer revoked-token"},
+		}
+		res, err := xEnv.MakeRequest(http.MethodPost, "/graphql", header, strings.NewReader(employeesQuery))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		data, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, `{"errors":[{"message":"unauthorized: token is not active"}]}`, string(data))
+	})
+}
+
 func TestAuthorization(t *testing.T) {
 	t.Parallel()
 
@@ -735,6 +1106,57 @@ func TestAuthorization(t *testing.T) {
 			require.Equal(t, employeesExpectedData, string(data))
 		})
 	})
+
+	t.Run("field policies", func(t *testing.T) {
+		t.Parallel()
+
+		authenticators, authServer := configureAuth(t)
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				core.WithAccessController(core.NewAccessController(authenticators, false)),
+				core.WithAuthorizationConfig(&config.AuthorizationConfiguration{
+					FieldPolicies: []config.FieldPolicy{
+						{
+							Name:       "own-employee-or-hr",
+							Coordinate: "Query.employee",
+							Allow: []config.FieldPolicyCondition{
+								{Claims: []config.FieldPolicyClaim{{Path: "employee_id", Argument: "id"}}},
+								{Claims: []config.FieldPolicyClaim{{Path: "roles", Values: []string{"hr"}}}},
+							},
+						},
+					},
+				}),
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			makeRequest := func(claims map[string]any, query string) string {
+				header := http.Header{}
+				if claims != nil {
+					token, err := authServer.Token(claims)
+					require.NoError(t, err)
+					header.Set("Authorization", "Bearer "+token)
+				}
+				res, err := xEnv.MakeRequest(http.MethodPost, "/graphql", header, strings.NewReader(query))
+				require.NoError(t, err)
+				defer res.Body.Close()
+				require.Equal(t, http.StatusOK, res.StatusCode)
+				data, err := io.ReadAll(res.Body)
+				require.NoError(t, err)
+				return string(bytes.TrimSpace(data))
+			}
+
+			// The argument is compared with the claim, inline arguments and variables are supported
+			require.Equal(t, `{"data":{"employee":{"id":1}}}`, makeRequest(map[string]any{"employee_id": 1}, `{"query":"{ employee(id: 1) { id } }"}`))
+			require.Equal(t, `{"data":{"employee":{"id":1}}}`, makeRequest(map[string]any{"employee_id": "1"}, `{"query":"query($id: Int!) { employee(id: $id) { id } }","variables":{"id":1}}`))
+			require.Equal(t, `{"data":{"employee":{"id":2}}}`, makeRequest(map[string]any{"roles": []string{"hr"}}, `{"query":"{ employee(id: 2) { id } }"}`))
+
+			denied := `{"errors":[{"message":"Unauthorized to load field 'Query.employee', Reason: denied by policy 'own-employee-or-hr'.","path":["employee"]}],"data":{"employee":null},"extensions":{"authorization":{"deniedPolicies":[{"coordinate":{"typeName":"Query","fieldName":"employee"},"policy":"own-employee-or-hr"}],"actualScopes":[]}}}`
+			require.Equal(t, denied, makeRequest(map[string]any{"employee_id": 1}, `{"query":"{ employee(id: 2) { id } }"}`))
+			require.Equal(t, denied, makeRequest(map[string]any{"roles": []string{"engineer"}}, `{"query":"{ employee(id: 2) { id } }"}`))
+
+			// Fields with a policy require authentication
+			require.Equal(t, `{"errors":[{"message":"Unauthorized to load field 'Query.employee', Reason: not authenticated.","path":["employee"]}],"data":{"employee":null}}`, makeRequest(nil, `{"query":"{ employee(id: 1) { id } }"}`))
+		})
+	})
 }
 
 func TestAuthenticationMultipleProviders(t *testing.T) {
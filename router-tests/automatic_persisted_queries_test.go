@@ -0,0 +1,148 @@
+package integration_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/wundergraph/cosmo/router-tests/testenv"
+	"github.com/wundergraph/cosmo/router/core"
+	"github.com/wundergraph/cosmo/router/pkg/config"
+)
+
+func TestAutomaticPersistedQueries(t *testing.T) {
+	t.Parallel()
+
+	// sha256 of the query
+	const (
+		query     = `{ employees { id } }`
+		queryHash = "f022f7f59c46e4f0b27804cde60077c9710c34d486df371dbb4504da0ecccee1"
+	)
+
+	automaticPersistedQueries := core.WithPersistedOperationsConfig(config.PersistedOperationsConfig{
+		AutomaticPersistedQueries: config.AutomaticPersistedQueriesConfig{
+			Enabled: true,
+			Storage: config.CacheStorage{
+				Provider: core.CacheStorageProviderMemory,
+				MaxSize:  10 * 1024 * 1024,
+			},
+		},
+	})
+
+	t.Run("unknown hash is answered with PersistedQueryNotFound", func(t *testing.T) {
+		t.Parallel()
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				automaticPersistedQueries,
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			res, err := xEnv.MakeGraphQLRequest(testenv.GraphQLRequest{
+				Extensions: []byte(`{"persistedQuery": {"version": 1, "sha256Hash": "` + queryHash + `"}}`),
+			})
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, res.Response.StatusCode)
+			require.Equal(t, `{"errors":[{"message":"PersistedQueryNotFound","extensions":{"code":"PERSISTED_QUERY_NOT_FOUND"}}],"data":null}`, res.Body)
+		})
+	})
+
+	t.Run("query is registered with its hash", func(t *testing.T) {
+		t.Parallel()
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				automaticPersistedQueries,
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+				Query:      query,
+				Extensions: []byte(`{"persistedQuery": {"version": 1, "sha256Hash": "` + queryHash + `"}}`),
+			})
+			require.JSONEq(t, employeesIDData, res.Body)
+
+			res = xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+				Extensions: []byte(`{"persistedQuery": {"version": 1, "sha256Hash": "` + queryHash + `"}}`),
+			})
+			require.JSONEq(t, employeesIDData, res.Body)
+
+			res, err := xEnv.MakeGraphQLRequestOverGET(testenv.GraphQLRequest{
+				Extensions: []byte(`{"persistedQuery": {"version": 1, "sha256Hash": "` + queryHash + `"}}`),
+			})
+			require.NoError(t, err)
+			require.JSONEq(t, employeesIDData, res.Body)
+		})
+	})
+
+	t.Run("query with a wrong hash is rejected", func(t *testing.T) {
+		t.Parallel()
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				automaticPersistedQueries,
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			res, err := xEnv.MakeGraphQLRequest(testenv.GraphQLRequest{
+				Query:      `{ employees { id tag } }`,
+				Extensions: []byte(`{"persistedQuery": {"version": 1, "sha256Hash": "` + queryHash + `"}}`),
+			})
+			require.NoError(t, err)
+			require.Equal(t, http.StatusBadRequest, res.Response.StatusCode)
+			require.Equal(t, `{"errors":[{"message":"provided sha does not match query"}]}`, res.Body)
+
+			res, err = xEnv.MakeGraphQLRequest(testenv.GraphQLRequest{
+				Extensions: []byte(`{"persistedQuery": {"version": 1, "sha256Hash": "` + queryHash + `"}}`),
+			})
+			require.NoError(t, err)
+			require.Contains(t, res.Body, "PersistedQueryNotFound")
+		})
+	})
+
+	t.Run("registered queries don't skip the limits of persisted operations", func(t *testing.T) {
+		t.Parallel()
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				automaticPersistedQueries,
+			},
+			ModifySecurityConfiguration: func(securityConfiguration *config.SecurityConfiguration) {
+				securityConfiguration.DepthLimit.Enabled = true
+				securityConfiguration.DepthLimit.Limit = 1
+				securityConfiguration.DepthLimit.CacheSize = 1024
+				securityConfiguration.DepthLimit.IgnorePersistedOperations = true
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			res, err := xEnv.MakeGraphQLRequest(testenv.GraphQLRequest{
+				Query:      query,
+				Extensions: []byte(`{"persistedQuery": {"version": 1, "sha256Hash": "` + queryHash + `"}}`),
+			})
+			require.NoError(t, err)
+			require.Equal(t, http.StatusBadRequest, res.Response.StatusCode)
+			require.Contains(t, res.Body, "exceeds the max query depth allowed (1)")
+
+			res, err = xEnv.MakeGraphQLRequest(testenv.GraphQLRequest{
+				Extensions: []byte(`{"persistedQuery": {"version": 1, "sha256Hash": "` + queryHash + `"}}`),
+			})
+			require.NoError(t, err)
+			require.Equal(t, http.StatusBadRequest, res.Response.StatusCode)
+			require.Contains(t, res.Body, "exceeds the max query depth allowed (1)")
+		})
+	})
+
+	t.Run("persisted operations of the CDN are served", func(t *testing.T) {
+		t.Parallel()
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				automaticPersistedQueries,
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+				OperationName: []byte(`"Employees"`),
+				Extensions:    []byte(`{"persistedQuery": {"version": 1, "sha256Hash": "dc67510fb4289672bea757e862d6b00e83db5d3cbbcfb15260601b6f29bb2b8f"}}`),
+				Header:        http.Header{"graphql-client-name": []string{"my-client"}},
+			})
+			require.Equal(t, `{"data":{"employees":[{"id":1},{"id":2},{"id":3},{"id":4},{"id":5},{"id":7},{"id":8},{"id":10},{"id":11},{"id":12}]}}`, res.Body)
+		})
+	})
+}
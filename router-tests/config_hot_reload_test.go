@@ -3,6 +3,10 @@ package integration
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -44,6 +48,20 @@ func (c *ConfigPollerMock) Stop(_ context.Context) error {
 	return nil
 }
 
+// unavailableConfigPoller simulates a storage provider that is down
+type unavailableConfigPoller struct{}
+
+func (c *unavailableConfigPoller) Subscribe(_ context.Context, _ func(newConfig *nodev1.RouterConfig, oldVersion string) error) {
+}
+
+func (c *unavailableConfigPoller) GetRouterConfig(_ context.Context) (*routerconfig.Response, error) {
+	return nil, errors.New("storage provider unavailable")
+}
+
+func (c *unavailableConfigPoller) Stop(_ context.Context) error {
+	return nil
+}
+
 func TestConfigHotReload(t *testing.T) {
 
 	t.Parallel()
@@ -351,6 +369,118 @@ func TestConfigHotReload(t *testing.T) {
 		})
 	})
 
+	t.Run("Swap config plans the most used operations before serving traffic", func(t *testing.T) {
+
+		t.Parallel()
+
+		pm := ConfigPollerMock{
+			ready: make(chan struct{}),
+		}
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				core.WithConfigVersionHeader(true),
+			},
+			ModifyEngineExecutionConfiguration: func(engineExecutionConfiguration *config.EngineExecutionConfiguration) {
+				engineExecutionConfiguration.ExecutionPlanCacheWarmup = config.ExecutionPlanCacheWarmupConfiguration{
+					Enabled:       true,
+					TopOperations: 10,
+					Workers:       2,
+					Timeout:       10 * time.Second,
+				}
+			},
+			RouterConfig: &testenv.RouterConfig{
+				ConfigPollerFactory: func(config *nodev1.RouterConfig) configpoller.ConfigPoller {
+					pm.initConfig = config
+					return &pm
+				},
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+				Query: `{ employees { id } }`,
+			})
+			require.Equal(t, "MISS", res.Response.Header.Get("X-WG-Execution-Plan-Cache"))
+			require.JSONEq(t, employeesIDData, res.Body)
+
+			// Wait for the config poller to be ready
+			<-pm.ready
+
+			pm.initConfig.Version = "updated"
+			require.NoError(t, pm.updateConfig(pm.initConfig, "old-1"))
+
+			res = xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+				Query: `{ employees { id } }`,
+			})
+			require.Equal(t, "updated", res.Response.Header.Get("X-Router-Config-Version"))
+			require.Equal(t, "HIT", res.Response.Header.Get("X-WG-Execution-Plan-Cache"))
+			require.JSONEq(t, employeesIDData, res.Body)
+
+			res = xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+				Query: `{ employees { id tag } }`,
+			})
+			require.Equal(t, "MISS", res.Response.Header.Get("X-WG-Execution-Plan-Cache"))
+		})
+	})
+
+}
+
+func TestExecutionConfigFallbackStorage(t *testing.T) {
+	t.Parallel()
+
+	fallbackStoragePath := filepath.Join(t.TempDir(), "execution-config.json")
+	fallbackStorage := core.WithConfigPollerConfig(&core.RouterConfigPollerConfig{
+		ExecutionConfig: config.ExecutionConfig{
+			FallbackStorage: config.ExecutionConfigFallbackStorage{
+				Enabled: true,
+				Path:    fallbackStoragePath,
+			},
+		},
+		GraphSignKey: "secret",
+	})
+
+	// The first router stores the execution config that it applied
+	testenv.Run(t, &testenv.Config{
+		RouterOptions: []core.Option{
+			fallbackStorage,
+		},
+		RouterConfig: &testenv.RouterConfig{
+			ConfigPollerFactory: func(config *nodev1.RouterConfig) configpoller.ConfigPoller {
+				return &ConfigPollerMock{
+					initConfig: config,
+					ready:      make(chan struct{}),
+				}
+			},
+		},
+	}, func(t *testing.T, xEnv *testenv.Environment) {
+		require.FileExists(t, fallbackStoragePath)
+	})
+
+	// The second router starts with the stored execution config because the storage provider is down
+	testenv.Run(t, &testenv.Config{
+		RouterOptions: []core.Option{
+			fallbackStorage,
+			core.WithConfigVersionHeader(true),
+		},
+		RouterConfig: &testenv.RouterConfig{
+			ConfigPollerFactory: func(config *nodev1.RouterConfig) configpoller.ConfigPoller {
+				return &unavailableConfigPoller{}
+			},
+		},
+	}, func(t *testing.T, xEnv *testenv.Environment) {
+		res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+			Query: `{ employees { id } }`,
+		})
+		require.Equal(t, xEnv.RouterConfigVersionMain(), res.Response.Header.Get("X-Router-Config-Version"))
+		require.JSONEq(t, employeesIDData, res.Body)
+
+		readiness, err := xEnv.RouterClient.Get(xEnv.RouterURL + "/health/ready")
+		require.NoError(t, err)
+		defer readiness.Body.Close()
+		body, err := io.ReadAll(readiness.Body)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, readiness.StatusCode)
+		require.Equal(t, "OK\nexecution_config: fallback (version "+xEnv.RouterConfigVersionMain()+")", string(body))
+	})
 }
 
 func BenchmarkConfigHotReload(b *testing.B) {
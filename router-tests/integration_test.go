@@ -996,6 +996,83 @@ func TestBlockNonPersistedOperations(t *testing.T) {
 	})
 }
 
+func TestOperationRules(t *testing.T) {
+	t.Parallel()
+
+	rules := []config.OperationRule{
+		{
+			Name:   "admin-console-may-update-tags",
+			Action: "allow",
+			Match: config.OperationRuleMatch{
+				RootFields:  []string{"Mutation.updateEmployeeTag"},
+				ClientNames: []string{"admin-console"},
+			},
+		},
+		{
+			Name:   "no-tag-updates",
+			Action: "deny",
+			Match: config.OperationRuleMatch{
+				RootFields: []string{"Mutation.updateEmployeeTag"},
+			},
+		},
+	}
+
+	t.Run("deny root field", func(t *testing.T) {
+		t.Parallel()
+		testenv.Run(t, &testenv.Config{
+			ModifySecurityConfiguration: func(securityConfiguration *config.SecurityConfiguration) {
+				securityConfiguration.OperationRules = rules
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+				Query: `mutation { updateEmployeeTag(id: 1, tag: "test") { id tag } }`,
+			})
+			require.Equal(t, http.StatusOK, res.Response.StatusCode)
+			require.Equal(t, `{"errors":[{"message":"operation is blocked by rule 'no-tag-updates'","extensions":{"code":"OPERATION_BLOCKED","rule":"no-tag-updates"}}],"data":null}`, res.Body)
+		})
+	})
+	t.Run("deny root field in inline fragment", func(t *testing.T) {
+		t.Parallel()
+		testenv.Run(t, &testenv.Config{
+			ModifySecurityConfiguration: func(securityConfiguration *config.SecurityConfiguration) {
+				securityConfiguration.OperationRules = rules
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+				Query: `mutation { ... on Mutation { updateEmployeeTag(id: 1, tag: "test") { id tag } } }`,
+			})
+			require.Equal(t, `{"errors":[{"message":"operation is blocked by rule 'no-tag-updates'","extensions":{"code":"OPERATION_BLOCKED","rule":"no-tag-updates"}}],"data":null}`, res.Body)
+		})
+	})
+	t.Run("allow client", func(t *testing.T) {
+		t.Parallel()
+		testenv.Run(t, &testenv.Config{
+			ModifySecurityConfiguration: func(securityConfiguration *config.SecurityConfiguration) {
+				securityConfiguration.OperationRules = rules
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+				Query:  `mutation { updateEmployeeTag(id: 1, tag: "test") { id tag } }`,
+				Header: http.Header{"graphql-client-name": []string{"admin-console"}},
+			})
+			require.Equal(t, `{"data":{"updateEmployeeTag":{"id":1,"tag":"test"}}}`, res.Body)
+		})
+	})
+	t.Run("no matching rule", func(t *testing.T) {
+		t.Parallel()
+		testenv.Run(t, &testenv.Config{
+			ModifySecurityConfiguration: func(securityConfiguration *config.SecurityConfiguration) {
+				securityConfiguration.OperationRules = rules
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+				Query: `{ employee(id: 1) { id } }`,
+			})
+			require.Equal(t, `{"data":{"employee":{"id":1}}}`, res.Body)
+		})
+	})
+}
+
 func TestRequestBodySizeLimit(t *testing.T) {
 	t.Parallel()
 	testenv.Run(t, &testenv.Config{
@@ -1183,6 +1260,101 @@ func TestQueryDepthLimit(t *testing.T) {
 	})
 }
 
+func TestComplexityLimits(t *testing.T) {
+	t.Parallel()
+
+	t.Run("field count blocks queries over the limit", func(t *testing.T) {
+		t.Parallel()
+
+		exporter := tracetest.NewInMemoryExporter(t)
+		testenv.Run(t, &testenv.Config{
+			TraceExporter: exporter,
+			ModifySecurityConfiguration: func(securityConfiguration *config.SecurityConfiguration) {
+				securityConfiguration.ComplexityLimits.FieldCount.Enabled = true
+				securityConfiguration.ComplexityLimits.FieldCount.Limit = 4
+				securityConfiguration.ComplexityLimits.FieldCount.CacheSize = 1024
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			res, _ := xEnv.MakeGraphQLRequest(testenv.GraphQLRequest{
+				Query: `{ employee(id:1) { id details { forename surname } } }`,
+			})
+			require.Equal(t, 400, res.Response.StatusCode)
+			require.Equal(t, `{"errors":[{"message":"The field count 5 exceeds the max field count allowed (4)"}]}`, res.Body)
+
+			testSpan := requireSpanWithName(t, exporter, "Operation - Validate")
+			require.Contains(t, testSpan.Attributes(), otel.WgQueryFieldCount.Int(5))
+			require.Contains(t, testSpan.Attributes(), otel.WgQueryFieldCountCacheHit.Bool(false))
+			exporter.Reset()
+
+			res, _ = xEnv.MakeGraphQLRequest(testenv.GraphQLRequest{
+				Query: `{ employee(id:1) { id details { forename surname } } }`,
+			})
+			require.Equal(t, 400, res.Response.StatusCode)
+
+			testSpan = requireSpanWithName(t, exporter, "Operation - Validate")
+			require.Contains(t, testSpan.Attributes(), otel.WgQueryFieldCountCacheHit.Bool(true))
+
+			successRes := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+				Query: `query { employees { id } }`,
+			})
+			require.JSONEq(t, employeesIDData, successRes.Body)
+		})
+	})
+
+	t.Run("alias and root field count block queries over the limit", func(t *testing.T) {
+		t.Parallel()
+
+		testenv.Run(t, &testenv.Config{
+			ModifySecurityConfiguration: func(securityConfiguration *config.SecurityConfiguration) {
+				securityConfiguration.ComplexityLimits.AliasCount.Enabled = true
+				securityConfiguration.ComplexityLimits.AliasCount.Limit = 1
+				securityConfiguration.ComplexityLimits.RootFieldCount.Enabled = true
+				securityConfiguration.ComplexityLimits.RootFieldCount.Limit = 2
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			res, _ := xEnv.MakeGraphQLRequest(testenv.GraphQLRequest{
+				Query: `{ a: employee(id:1) { id } b: employee(id:2) { id } }`,
+			})
+			require.Equal(t, 400, res.Response.StatusCode)
+			require.Equal(t, `{"errors":[{"message":"The alias count 2 exceeds the max alias count allowed (1)"}]}`, res.Body)
+
+			res, _ = xEnv.MakeGraphQLRequest(testenv.GraphQLRequest{
+				Query: `{ employees { id } a: employees { id } employee(id:1) { id } }`,
+			})
+			require.Equal(t, 400, res.Response.StatusCode)
+			require.Equal(t, `{"errors":[{"message":"The root field count 3 exceeds the max root field count allowed (2)"}]}`, res.Body)
+		})
+	})
+
+	t.Run("token count doesn't block persisted queries if IgnorePersistedOperations set", func(t *testing.T) {
+		t.Parallel()
+
+		testenv.Run(t, &testenv.Config{
+			ModifySecurityConfiguration: func(securityConfiguration *config.SecurityConfiguration) {
+				securityConfiguration.ComplexityLimits.TokenCount.Enabled = true
+				securityConfiguration.ComplexityLimits.TokenCount.Limit = 4
+				securityConfiguration.ComplexityLimits.TokenCount.IgnorePersistedOperations = true
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			res, _ := xEnv.MakeGraphQLRequest(testenv.GraphQLRequest{
+				Query: `{ employees { id } }`,
+			})
+			require.Equal(t, 400, res.Response.StatusCode)
+			require.Equal(t, `{"errors":[{"message":"The token count 5 exceeds the max token count allowed (4)"}]}`, res.Body)
+
+			header := make(http.Header)
+			header.Add("graphql-client-name", "my-client")
+			res, _ = xEnv.MakeGraphQLRequestOverGET(testenv.GraphQLRequest{
+				OperationName: []byte(`Find`),
+				Variables:     []byte(`{"criteria":  {"nationality":  "GERMAN"   }}`),
+				Extensions:    []byte(`{"persistedQuery": {"version": 1, "sha256Hash": "e33580cf6276de9a75fb3b1c4b7580fec2a1c8facd13f3487bf6c7c3f854f7e3"}}`),
+				Header:        header,
+			})
+			require.Equal(t, 200, res.Response.StatusCode)
+		})
+	})
+}
+
 func requireSpanWithName(t *testing.T, exporter *tracetest2.InMemoryExporter, name string) trace.ReadOnlySpan {
 	sn := exporter.GetSpans().Snapshots()
 	var testSpan trace.ReadOnlySpan
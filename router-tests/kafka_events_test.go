@@ -21,6 +21,7 @@ import (
 	"github.com/twmb/franz-go/pkg/kgo"
 	"github.com/wundergraph/cosmo/router-tests/testenv"
 	"github.com/wundergraph/cosmo/router/pkg/config"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestLocalKafka(t *testing.T) {
@@ -885,6 +886,208 @@ func TestKafkaEvents(t *testing.T) {
 			wg.Wait()
 		})
 	})
+
+	t.Run("subscribe with timestamp start offset receives records produced before the subscription", func(t *testing.T) {
+
+		topics := []string{"employeeUpdated", "employeeUpdatedTwo"}
+		start := time.Now()
+
+		testenv.Run(t, &testenv.Config{
+			KafkaSeeds: seeds,
+			ModifyEventsConfiguration: func(eventsConfiguration *config.EventsConfiguration) {
+				for i := range eventsConfiguration.Providers.Kafka {
+					eventsConfiguration.Providers.Kafka[i].Consumer = config.KafkaConsumerConfiguration{
+						StartOffset:    "timestamp",
+						StartTimestamp: start,
+					}
+				}
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+
+			ensureTopicExists(t, xEnv, topics...)
+
+			produceKafkaMessage(t, xEnv, topics[0], `{"__typename":"Employee","id": 1,"update":{"name":"foo"}}`)
+
+			var subscriptionOne struct {
+				employeeUpdatedMyKafka struct {
+					ID      float64 `graphql:"id"`
+					Details struct {
+						Forename string `graphql:"forename"`
+						Surname  string `graphql:"surname"`
+					} `graphql:"details"`
+				} `graphql:"employeeUpdatedMyKafka(employeeID: 3)"`
+			}
+
+			surl := xEnv.GraphQLSubscriptionURL()
+			client := graphql.NewSubscriptionClient(surl)
+			t.Cleanup(func() {
+				_ = client.Close()
+			})
+
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			subscriptionOneID, err := client.Subscribe(&subscriptionOne, nil, func(dataValue []byte, errValue error) error {
+				defer wg.Done()
+				require.NoError(t, errValue)
+				require.JSONEq(t, `{"employeeUpdatedMyKafka":{"id":1,"details":{"forename":"Jens","surname":"Neuse"}}}`, string(dataValue))
+				return nil
+			})
+			require.NoError(t, err)
+			require.NotEmpty(t, subscriptionOneID)
+
+			go func() {
+				clientErr := client.Run()
+				require.NoError(t, clientErr)
+			}()
+
+			go func() {
+				wg.Wait()
+				require.NoError(t, client.Close())
+			}()
+
+			xEnv.WaitForSubscriptionCount(1, time.Second*10)
+			xEnv.WaitForMessagesSent(1, time.Second*10)
+			xEnv.WaitForSubscriptionCount(0, time.Second*10)
+			xEnv.WaitForConnectionCount(0, time.Second*10)
+		})
+	})
+
+	t.Run("subscribe with consumer group fans out the records through NATS", func(t *testing.T) {
+
+		topics := []string{"employeeUpdated", "employeeUpdatedTwo"}
+
+		testenv.Run(t, &testenv.Config{
+			KafkaSeeds: seeds,
+			ModifyEventsConfiguration: func(eventsConfiguration *config.EventsConfiguration) {
+				for i := range eventsConfiguration.Providers.Kafka {
+					eventsConfiguration.Providers.Kafka[i].Consumer = config.KafkaConsumerConfiguration{
+						StartOffset: "earliest",
+						Group: &config.KafkaConsumerGroupConfiguration{
+							// A new group per test, the group has no committed offsets
+							ID:               fmt.Sprintf("cosmo-router-%d", time.Now().UnixNano()),
+							FanOutProviderID: "default",
+						},
+					}
+				}
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+
+			ensureTopicExists(t, xEnv, topics...)
+
+			var subscriptionOne struct {
+				employeeUpdatedMyKafka struct {
+					ID      float64 `graphql:"id"`
+					Details struct {
+						Forename string `graphql:"forename"`
+						Surname  string `graphql:"surname"`
+					} `graphql:"details"`
+				} `graphql:"employeeUpdatedMyKafka(employeeID: 3)"`
+			}
+
+			surl := xEnv.GraphQLSubscriptionURL()
+			client := graphql.NewSubscriptionClient(surl)
+			t.Cleanup(func() {
+				_ = client.Close()
+			})
+
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+
+			subscriptionOneID, err := client.Subscribe(&subscriptionOne, nil, func(dataValue []byte, errValue error) error {
+				defer wg.Done()
+				require.NoError(t, errValue)
+				require.JSONEq(t, `{"employeeUpdatedMyKafka":{"id":1,"details":{"forename":"Jens","surname":"Neuse"}}}`, string(dataValue))
+				return nil
+			})
+			require.NoError(t, err)
+			require.NotEmpty(t, subscriptionOneID)
+
+			go func() {
+				clientErr := client.Run()
+				require.NoError(t, clientErr)
+			}()
+
+			go func() {
+				wg.Wait()
+				require.NoError(t, client.Close())
+			}()
+
+			xEnv.WaitForSubscriptionCount(1, time.Second*10)
+
+			produceKafkaMessage(t, xEnv, topics[0], `{"__typename":"Employee","id": 1,"update":{"name":"foo"}}`)
+
+			xEnv.WaitForMessagesSent(1, time.Second*10)
+			xEnv.WaitForSubscriptionCount(0, time.Second*10)
+			xEnv.WaitForConnectionCount(0, time.Second*10)
+		})
+	})
+
+	t.Run("publish sets the record key and headers", func(t *testing.T) {
+
+		topics := []string{"employeeUpdated", "employeeUpdatedTwo"}
+		exporter := tracetest.NewInMemoryExporter(t)
+
+		testenv.Run(t, &testenv.Config{
+			KafkaSeeds:    seeds,
+			TraceExporter: exporter,
+			ModifyEventsConfiguration: func(eventsConfiguration *config.EventsConfiguration) {
+				for i := range eventsConfiguration.Providers.Kafka {
+					eventsConfiguration.Providers.Kafka[i].Producer = config.KafkaProducerConfiguration{
+						Acks:        "all",
+						Compression: "zstd",
+						Linger:      time.Millisecond * 5,
+						Keys: []config.KafkaProducerKey{
+							{Topic: "employeeUpdated", Argument: "employeeID"},
+						},
+						PropagateHeaders: []string{"X-Tenant-ID"},
+					}
+				}
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+
+			ensureTopicExists(t, xEnv, topics...)
+
+			consumer, err := kgo.NewClient(
+				kgo.SeedBrokers(seeds...),
+				kgo.ConsumeTopics(topics[0]),
+				kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+			)
+			require.NoError(t, err)
+			t.Cleanup(consumer.Close)
+
+			res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+				Query: `mutation UpdateEmployeeKafka($update: UpdateEmployeeInput!) {
+							updateEmployeeMyKafka(employeeID: 3, update: $update) {success}
+						}`,
+				Variables: json.RawMessage(`{"update":{"name":"Stefan Avramovic","email":"avramovic@wundergraph.com"}}`),
+				Header: http.Header{
+					"X-Tenant-ID": []string{"tenant-1"},
+				},
+			})
+			require.JSONEq(t, `{"data":{"updateEmployeeMyKafka": {"success": true}}}`, res.Body)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			fetches := consumer.PollRecords(ctx, 1)
+			require.NoError(t, fetches.Err())
+
+			records := fetches.Records()
+			require.Len(t, records, 1)
+
+			record := records[0]
+			require.Equal(t, "3", string(record.Key))
+			require.JSONEq(t, `{"employeeID":3,"update":{"name":"Stefan Avramovic","email":"avramovic@wundergraph.com"}}`, string(record.Value))
+
+			headers := make(map[string]string, len(record.Headers))
+			for _, header := range record.Headers {
+				headers[header.Key] = string(header.Value)
+			}
+			require.Equal(t, "tenant-1", headers["X-Tenant-ID"])
+			require.NotEmpty(t, headers["traceparent"])
+		})
+	})
 }
 
 func ensureTopicExists(t *testing.T, xEnv *testenv.Environment, topics ...string) {
@@ -751,6 +751,94 @@ func TestNatsEvents(t *testing.T) {
 		})
 	})
 
+	t.Run("subscribe with ephemeral consumers delivers every message to each subscription", func(t *testing.T) {
+		t.Parallel()
+
+		testenv.Run(t, &testenv.Config{
+			ModifyEventsConfiguration: func(eventsConfiguration *config.EventsConfiguration) {
+				for i := range eventsConfiguration.Providers.Nats {
+					eventsConfiguration.Providers.Nats[i].JetStream = config.NatsJetStreamConfiguration{
+						EphemeralConsumers: true,
+						DeliverPolicy:      "new",
+						FetchMaxWait:       time.Second,
+					}
+				}
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			type subscriptionPayload struct {
+				Data struct {
+					EmployeeUpdatedNatsStream struct {
+						ID float64 `graphql:"id"`
+					} `graphql:"employeeUpdatedNatsStream(id: 12)"`
+				} `json:"data"`
+			}
+
+			js, err := jetstream.New(xEnv.NatsConnectionDefault)
+			require.NoError(t, err)
+
+			_, err = js.CreateOrUpdateStream(xEnv.Context, jetstream.StreamConfig{
+				Name:     "streamName",
+				Subjects: []string{"employeeUpdated.>"},
+				Storage:  jetstream.MemoryStorage,
+			})
+			require.NoError(t, err)
+
+			// conn.Close() is called in a cleanup defined in the function
+			conn := xEnv.InitGraphQLWebSocketConnection(nil, nil, nil)
+			for _, id := range []string{"1", "2"} {
+				err = conn.WriteJSON(&testenv.WebSocketMessage{
+					ID:      id,
+					Type:    "subscribe",
+					Payload: []byte(`{"query":"subscription { employeeUpdatedNatsStream(id: 12) { id }}"}`),
+				})
+				require.NoError(t, err)
+			}
+
+			xEnv.WaitForSubscriptionCount(2, time.Second*5)
+
+			err = xEnv.NatsConnectionDefault.Publish("employeeUpdated.12", []byte(`{"id":13,"__typename":"Employee"}`))
+			require.NoError(t, err)
+
+			err = xEnv.NatsConnectionDefault.Flush()
+			require.NoError(t, err)
+
+			// Every subscription has its own consumer, so both subscriptions receive the message
+			received := map[string]float64{}
+			for i := 0; i < 2; i++ {
+				var msg testenv.WebSocketMessage
+				var payload subscriptionPayload
+				err = conn.ReadJSON(&msg)
+				require.NoError(t, err)
+				require.Equal(t, "next", msg.Type)
+				err = json.Unmarshal(msg.Payload, &payload)
+				require.NoError(t, err)
+				received[msg.ID] = payload.Data.EmployeeUpdatedNatsStream.ID
+			}
+			require.Equal(t, map[string]float64{"1": 13, "2": 13}, received)
+
+			stream, err := js.Stream(xEnv.Context, "streamName")
+			require.NoError(t, err)
+			info, err := stream.Info(xEnv.Context)
+			require.NoError(t, err)
+			require.Equal(t, 2, info.State.Consumers)
+
+			// The ephemeral consumers are deleted when the subscriptions complete
+			for _, id := range []string{"1", "2"} {
+				err = conn.WriteJSON(&testenv.WebSocketMessage{
+					ID:   id,
+					Type: "complete",
+				})
+				require.NoError(t, err)
+			}
+			xEnv.WaitForSubscriptionCount(0, time.Second*10)
+
+			require.Eventually(t, func() bool {
+				info, err := stream.Info(xEnv.Context)
+				return err == nil && info.State.Consumers == 0
+			}, time.Second*10, time.Millisecond*100)
+		})
+	})
+
 	t.Run("subscribe ws with filter", func(t *testing.T) {
 		t.Parallel()
 
@@ -0,0 +1,57 @@
+package integration_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/wundergraph/cosmo/router-tests/testenv"
+	"github.com/wundergraph/cosmo/router/core"
+	"github.com/wundergraph/cosmo/router/pkg/config"
+)
+
+func TestPersistedOperationsWarmup(t *testing.T) {
+	t.Parallel()
+
+	const sha256Hash = "dc67510fb4289672bea757e862d6b00e83db5d3cbbcfb15260601b6f29bb2b8f"
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "my-client"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "my-client", sha256Hash+".json"), []byte(`{"version":1,"body":"query Employees { employees { id } }"}`), 0o644))
+
+	testenv.Run(t, &testenv.Config{
+		RouterOptions: []core.Option{
+			core.WithStorageProviders(config.StorageProviders{
+				FileSystem: []config.FileSystemStorageProvider{
+					{
+						ID:   "local",
+						Path: dir,
+					},
+				},
+			}),
+			core.WithPersistedOperationsConfig(config.PersistedOperationsConfig{
+				Storage: config.PersistedOperationsStorageConfig{
+					ProviderID: "local",
+				},
+				Warmup: config.CacheWarmupConfiguration{
+					Enabled: true,
+					Workers: 2,
+					Timeout: 10 * time.Second,
+				},
+			}),
+		},
+	}, func(t *testing.T, xEnv *testenv.Environment) {
+		res, err := xEnv.MakeGraphQLRequest(testenv.GraphQLRequest{
+			OperationName: []byte(`"Employees"`),
+			Extensions:    []byte(`{"persistedQuery": {"version": 1, "sha256Hash": "` + sha256Hash + `"}}`),
+			Header:        http.Header{"graphql-client-name": []string{"my-client"}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "HIT", res.Response.Header.Get("X-WG-Execution-Plan-Cache"))
+		require.Equal(t, `{"data":{"employees":[{"id":1},{"id":2},{"id":3},{"id":4},{"id":5},{"id":7},{"id":8},{"id":10},{"id":11},{"id":12}]}}`, res.Body)
+	})
+}
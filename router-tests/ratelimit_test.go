@@ -255,6 +255,225 @@ func TestRateLimit(t *testing.T) {
 			require.Equal(t, `{"errors":[{"message":"Rate limit exceeded"}],"data":null,"extensions":{"rateLimit":{"requestRate":2,"remaining":0,"retryAfterMs":1234,"resetAfterMs":1234}}}`, res.Body)
 		})
 	})
+	t.Run("enabled - separate budget per client name", func(t *testing.T) {
+		t.Parallel()
+		key := uuid.New().String()
+		t.Cleanup(func() {
+			client := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "test"})
+			keys, err := client.Keys(context.Background(), key+"*").Result()
+			require.NoError(t, err)
+			if len(keys) > 0 {
+				require.NoError(t, client.Del(context.Background(), keys...).Err())
+			}
+		})
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				core.WithRateLimitConfig(&config.RateLimitConfiguration{
+					Enabled:  true,
+					Strategy: "simple",
+					SimpleStrategy: config.RateLimitSimpleStrategy{
+						Rate:                    3,
+						Burst:                   3,
+						Period:                  time.Second * 2,
+						RejectExceedingRequests: true,
+					},
+					Keys: []config.RateLimitKey{
+						{Expression: "client_name", Rate: 1, Burst: 1},
+					},
+					Storage: config.RedisConfiguration{
+						Url:       "redis://localhost:6379",
+						KeyPrefix: key,
+					},
+					Debug: true,
+				}),
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			request := func(clientName string) string {
+				res, err := xEnv.MakeGraphQLRequest(testenv.GraphQLRequest{
+					Query:     `query ($n:Int!) { employee(id:$n) { id details { forename surname } } }`,
+					Variables: json.RawMessage(`{"n":1}`),
+					Header:    http.Header{"Graphql-Client-Name": []string{clientName}},
+				})
+				require.NoError(t, err)
+				return res.Body
+			}
+			require.Equal(t, `{"data":{"employee":{"id":1,"details":{"forename":"Jens","surname":"Neuse"}}},"extensions":{"rateLimit":{"requestRate":1,"remaining":0,"retryAfterMs":1234,"resetAfterMs":1234}}}`, request("a"))
+			require.Equal(t, `{"errors":[{"message":"Rate limit exceeded"}],"data":null,"extensions":{"rateLimit":{"requestRate":1,"remaining":0,"retryAfterMs":1234,"resetAfterMs":1234}}}`, request("a"))
+			require.Equal(t, `{"data":{"employee":{"id":1,"details":{"forename":"Jens","surname":"Neuse"}}},"extensions":{"rateLimit":{"requestRate":1,"remaining":0,"retryAfterMs":1234,"resetAfterMs":1234}}}`, request("b"))
+		})
+	})
+	t.Run("enabled - rotating key values are limited by the global limit", func(t *testing.T) {
+		t.Parallel()
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				core.WithRateLimitConfig(&config.RateLimitConfiguration{
+					Enabled:  true,
+					Backend:  "memory",
+					Strategy: "simple",
+					SimpleStrategy: config.RateLimitSimpleStrategy{
+						Rate:                    2,
+						Burst:                   2,
+						Period:                  time.Minute,
+						RejectExceedingRequests: true,
+					},
+					Keys: []config.RateLimitKey{
+						{Expression: "header:X-Tenant-ID", Rate: 1, Burst: 1},
+					},
+					Debug: true,
+				}),
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			request := func(tenant string) string {
+				res, err := xEnv.MakeGraphQLRequest(testenv.GraphQLRequest{
+					Query:     `query ($n:Int!) { employee(id:$n) { id details { forename surname } } }`,
+					Variables: json.RawMessage(`{"n":1}`),
+					Header:    http.Header{"X-Tenant-ID": []string{tenant}},
+				})
+				require.NoError(t, err)
+				return res.Body
+			}
+			require.Equal(t, `{"data":{"employee":{"id":1,"details":{"forename":"Jens","surname":"Neuse"}}},"extensions":{"rateLimit":{"requestRate":1,"remaining":0,"retryAfterMs":1234,"resetAfterMs":1234}}}`, request("a"))
+			require.Equal(t, `{"data":{"employee":{"id":1,"details":{"forename":"Jens","surname":"Neuse"}}},"extensions":{"rateLimit":{"requestRate":1,"remaining":0,"retryAfterMs":1234,"resetAfterMs":1234}}}`, request("b"))
+			require.Equal(t, `{"errors":[{"message":"Rate limit exceeded"}],"data":null,"extensions":{"rateLimit":{"requestRate":1,"remaining":0,"retryAfterMs":1234,"resetAfterMs":1234}}}`, request("c"))
+		})
+	})
+	t.Run("enabled - sliding window and token bucket", func(t *testing.T) {
+		t.Parallel()
+
+		for _, strategy := range []string{"sliding_window", "token_bucket"} {
+			strategy := strategy
+			t.Run(strategy, func(t *testing.T) {
+				t.Parallel()
+				key := uuid.New().String()
+				t.Cleanup(func() {
+					client := redis.NewClient(&redis.Options{Addr: "localhost:6379", Password: "test"})
+					keys, err := client.Keys(context.Background(), "*"+key+"*").Result()
+					require.NoError(t, err)
+					if len(keys) > 0 {
+						require.NoError(t, client.Del(context.Background(), keys...).Err())
+					}
+				})
+				testenv.Run(t, &testenv.Config{
+					RouterOptions: []core.Option{
+						core.WithRateLimitConfig(&config.RateLimitConfiguration{
+							Enabled:  true,
+							Strategy: strategy,
+							SimpleStrategy: config.RateLimitSimpleStrategy{
+								Rate:                    1,
+								Burst:                   1,
+								Period:                  time.Minute,
+								RejectExceedingRequests: true,
+							},
+							Storage: config.RedisConfiguration{
+								Url:       "redis://localhost:6379",
+								KeyPrefix: key,
+							},
+							Debug: true,
+						}),
+					},
+				}, func(t *testing.T, xEnv *testenv.Environment) {
+					res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+						Query:     `query ($n:Int!) { employee(id:$n) { id details { forename surname } } }`,
+						Variables: json.RawMessage(`{"n":1}`),
+					})
+					require.Equal(t, `{"data":{"employee":{"id":1,"details":{"forename":"Jens","surname":"Neuse"}}},"extensions":{"rateLimit":{"requestRate":1,"remaining":0,"retryAfterMs":1234,"resetAfterMs":1234}}}`, res.Body)
+					res = xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+						Query:     `query ($n:Int!) { employee(id:$n) { id details { forename surname } } }`,
+						Variables: json.RawMessage(`{"n":1}`),
+					})
+					require.Equal(t, `{"errors":[{"message":"Rate limit exceeded"}],"data":null,"extensions":{"rateLimit":{"requestRate":1,"remaining":0,"retryAfterMs":1234,"resetAfterMs":1234}}}`, res.Body)
+				})
+			})
+		}
+	})
+	t.Run("enabled - memory backend does not require redis", func(t *testing.T) {
+		t.Parallel()
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				core.WithRateLimitConfig(&config.RateLimitConfiguration{
+					Enabled:  true,
+					Backend:  "memory",
+					Strategy: "simple",
+					SimpleStrategy: config.RateLimitSimpleStrategy{
+						Rate:                    1,
+						Burst:                   1,
+						Period:                  time.Minute,
+						RejectExceedingRequests: true,
+					},
+					Storage: config.RedisConfiguration{
+						Url:       "redis://localhost:1",
+						KeyPrefix: "memory",
+					},
+					Debug: true,
+				}),
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+				Query:     `query ($n:Int!) { employee(id:$n) { id details { forename surname } } }`,
+				Variables: json.RawMessage(`{"n":1}`),
+			})
+			require.Equal(t, `{"data":{"employee":{"id":1,"details":{"forename":"Jens","surname":"Neuse"}}},"extensions":{"rateLimit":{"requestRate":1,"remaining":0,"retryAfterMs":1234,"resetAfterMs":1234}}}`, res.Body)
+			res = xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+				Query:     `query ($n:Int!) { employee(id:$n) { id details { forename surname } } }`,
+				Variables: json.RawMessage(`{"n":1}`),
+			})
+			require.Equal(t, `{"errors":[{"message":"Rate limit exceeded"}],"data":null,"extensions":{"rateLimit":{"requestRate":1,"remaining":0,"retryAfterMs":1234,"resetAfterMs":1234}}}`, res.Body)
+		})
+	})
+	t.Run("enabled - unreachable redis", func(t *testing.T) {
+		t.Parallel()
+
+		rateLimitConfig := func(failureMode string) *config.RateLimitConfiguration {
+			return &config.RateLimitConfiguration{
+				Enabled:     true,
+				Strategy:    "simple",
+				FailureMode: failureMode,
+				SimpleStrategy: config.RateLimitSimpleStrategy{
+					Rate:                    1,
+					Burst:                   1,
+					Period:                  time.Minute,
+					RejectExceedingRequests: true,
+				},
+				Storage: config.RedisConfiguration{
+					Url:       "redis://localhost:1",
+					KeyPrefix: "unreachable",
+				},
+			}
+		}
+
+		t.Run("fail open", func(t *testing.T) {
+			t.Parallel()
+
+			testenv.Run(t, &testenv.Config{
+				RouterOptions: []core.Option{
+					core.WithRateLimitConfig(rateLimitConfig("open")),
+				},
+			}, func(t *testing.T, xEnv *testenv.Environment) {
+				res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+					Query:     `query ($n:Int!) { employee(id:$n) { id details { forename surname } } }`,
+					Variables: json.RawMessage(`{"n":1}`),
+				})
+				require.Equal(t, `{"data":{"employee":{"id":1,"details":{"forename":"Jens","surname":"Neuse"}}},"extensions":{"rateLimit":{"requestRate":0,"remaining":0,"retryAfterMs":0,"resetAfterMs":0}}}`, res.Body)
+			})
+		})
+		t.Run("fail closed", func(t *testing.T) {
+			t.Parallel()
+
+			testenv.Run(t, &testenv.Config{
+				RouterOptions: []core.Option{
+					core.WithRateLimitConfig(rateLimitConfig("closed")),
+				},
+			}, func(t *testing.T, xEnv *testenv.Environment) {
+				res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+					Query:     `query ($n:Int!) { employee(id:$n) { id details { forename surname } } }`,
+					Variables: json.RawMessage(`{"n":1}`),
+				})
+				require.Equal(t, `{"errors":[{"message":"Rate limit exceeded"}],"data":null,"extensions":{"rateLimit":{"requestRate":0,"remaining":0,"retryAfterMs":0,"resetAfterMs":0}}}`, res.Body)
+			})
+		})
+	})
 }
 
 const (
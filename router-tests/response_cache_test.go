@@ -0,0 +1,216 @@
+package integration_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/wundergraph/cosmo/router-tests/testenv"
+	"github.com/wundergraph/cosmo/router/core"
+	"github.com/wundergraph/cosmo/router/pkg/config"
+)
+
+func TestResponseCache(t *testing.T) {
+	t.Parallel()
+
+	responseCache := func(defaultTTL time.Duration, varyHeaders ...string) core.Option {
+		return core.WithResponseCache(&config.ResponseCacheConfiguration{
+			Enabled:     true,
+			DefaultTTL:  defaultTTL,
+			VaryHeaders: varyHeaders,
+			Storage: config.CacheStorage{
+				Provider: core.CacheStorageProviderMemory,
+				MaxSize:  10 * 1024 * 1024,
+			},
+		})
+	}
+
+	mostRestrictiveCacheControl := core.WithHeaderRules(config.HeaderRules{
+		All: &config.GlobalHeaderRule{
+			Response: []*config.ResponseHeaderRule{
+				{
+					Operation: config.HeaderRuleOperationPropagate,
+					Algorithm: config.ResponseHeaderRuleAlgorithmMostRestrictiveCacheControl,
+				},
+			},
+		},
+	})
+
+	cacheControl := func(value string) func(http.Handler) http.Handler {
+		return func(handler http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Cache-Control", value)
+				handler.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	t.Run("response is served from the cache", func(t *testing.T) {
+		t.Parallel()
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				responseCache(time.Minute),
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			for i := 0; i < 3; i++ {
+				res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+					Query: `{ employees { id } }`,
+				})
+				require.JSONEq(t, employeesIDData, res.Body)
+				require.NotEmpty(t, res.Response.Header.Get("ETag"))
+			}
+			require.Equal(t, int64(1), xEnv.SubgraphRequestCount.Employees.Load())
+		})
+	})
+
+	t.Run("most restrictive cache control of the subgraphs is honored", func(t *testing.T) {
+		t.Parallel()
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				responseCache(time.Minute),
+				mostRestrictiveCacheControl,
+			},
+			Subgraphs: testenv.SubgraphsConfig{
+				Employees: testenv.SubgraphConfig{
+					Middleware: cacheControl("no-store"),
+				},
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			for i := 0; i < 2; i++ {
+				res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+					Query: `{ employees { id } }`,
+				})
+				require.JSONEq(t, employeesIDData, res.Body)
+				require.Equal(t, "no-store", res.Response.Header.Get("Cache-Control"))
+			}
+			require.Equal(t, int64(2), xEnv.SubgraphRequestCount.Employees.Load())
+		})
+	})
+
+	t.Run("cache varies by the configured headers", func(t *testing.T) {
+		t.Parallel()
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				responseCache(time.Minute, "X-Tenant-ID"),
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			for _, tenant := range []string{"a", "b", "a"} {
+				res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+					Query:  `{ employees { id } }`,
+					Header: http.Header{"X-Tenant-ID": []string{tenant}},
+				})
+				require.JSONEq(t, employeesIDData, res.Body)
+				require.Equal(t, "X-Tenant-ID", res.Response.Header.Get("Vary"))
+			}
+			require.Equal(t, int64(2), xEnv.SubgraphRequestCount.Employees.Load())
+		})
+	})
+
+	t.Run("GET request with a matching If-None-Match is answered with 304", func(t *testing.T) {
+		t.Parallel()
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				responseCache(time.Minute),
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			res, err := xEnv.MakeGraphQLRequestOverGET(testenv.GraphQLRequest{
+				Query: `{ employees { id } }`,
+			})
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, res.Response.StatusCode)
+			require.JSONEq(t, employeesIDData, res.Body)
+
+			etag := res.Response.Header.Get("ETag")
+			require.NotEmpty(t, etag)
+
+			res, err = xEnv.MakeGraphQLRequestOverGET(testenv.GraphQLRequest{
+				Query:  `{ employees { id } }`,
+				Header: http.Header{"If-None-Match": []string{etag}},
+			})
+			require.NoError(t, err)
+			require.Equal(t, http.StatusNotModified, res.Response.StatusCode)
+			require.Empty(t, res.Body)
+			require.Equal(t, etag, res.Response.Header.Get("ETag"))
+			require.Equal(t, int64(1), xEnv.SubgraphRequestCount.Employees.Load())
+		})
+	})
+
+	t.Run("responses with the query plan are not cached", func(t *testing.T) {
+		t.Parallel()
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				responseCache(time.Minute),
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+				Query:  `{ employees { id } }`,
+				Header: http.Header{"X-WG-Include-Query-Plan": []string{"true"}},
+			})
+			require.Contains(t, res.Body, `"queryPlan"`)
+			require.Empty(t, res.Response.Header.Get("ETag"))
+
+			res = xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+				Query: `{ employees { id } }`,
+			})
+			require.JSONEq(t, employeesIDData, res.Body)
+			require.Equal(t, int64(2), xEnv.SubgraphRequestCount.Employees.Load())
+		})
+	})
+
+	t.Run("rate limit extension is not cached", func(t *testing.T) {
+		t.Parallel()
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				responseCache(time.Minute),
+				core.WithRateLimitConfig(&config.RateLimitConfiguration{
+					Enabled:  true,
+					Strategy: "simple",
+					SimpleStrategy: config.RateLimitSimpleStrategy{
+						Rate:   10,
+						Burst:  10,
+						Period: time.Second * 10,
+					},
+					Backend: "memory",
+					Debug:   true,
+				}),
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+				Query: `{ employees { id } }`,
+			})
+			require.Contains(t, res.Body, `"rateLimit"`)
+
+			res = xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+				Query: `{ employees { id } }`,
+			})
+			require.JSONEq(t, employeesIDData, res.Body)
+			require.Equal(t, int64(1), xEnv.SubgraphRequestCount.Employees.Load())
+		})
+	})
+
+	t.Run("mutations are not cached", func(t *testing.T) {
+		t.Parallel()
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				responseCache(time.Minute),
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			for i := 0; i < 2; i++ {
+				res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+					Query: `mutation { updateEmployeeTag(id: 1, tag: "test") { id } }`,
+				})
+				require.Empty(t, res.Response.Header.Get("ETag"))
+			}
+			require.Equal(t, int64(2), xEnv.SubgraphRequestCount.Employees.Load())
+		})
+	})
+}
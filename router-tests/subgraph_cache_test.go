@@ -0,0 +1,186 @@
+package integration_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/wundergraph/cosmo/router-tests/testenv"
+	"github.com/wundergraph/cosmo/router/core"
+	"github.com/wundergraph/cosmo/router/pkg/config"
+)
+
+func TestSubgraphResponseCache(t *testing.T) {
+	t.Parallel()
+
+	const queryEmployeeWithHobby = `{ employee(id: 1) { id hobbies { ... on Gaming { name } } } }`
+
+	subgraphCache := func(scopeHeaders ...string) core.Option {
+		return core.WithSubgraphCache(&config.SubgraphCacheConfiguration{
+			Enabled:      true,
+			ScopeHeaders: scopeHeaders,
+			Storage: config.CacheStorage{
+				Provider: core.CacheStorageProviderMemory,
+				MaxSize:  10 * 1024 * 1024,
+			},
+		})
+	}
+
+	cacheControl := func(value string) func(http.Handler) http.Handler {
+		return func(handler http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Cache-Control", value)
+				handler.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	t.Run("root fetch is served from the cache", func(t *testing.T) {
+		t.Parallel()
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				subgraphCache(),
+			},
+			Subgraphs: testenv.SubgraphsConfig{
+				Employees: testenv.SubgraphConfig{
+					Middleware: cacheControl("max-age=60"),
+				},
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			for i := 0; i < 3; i++ {
+				res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+					Query: `{ employees { id } }`,
+				})
+				require.JSONEq(t, employeesIDData, res.Body)
+			}
+			require.Equal(t, int64(1), xEnv.SubgraphRequestCount.Employees.Load())
+		})
+	})
+
+	t.Run("responses without expiration are not cached", func(t *testing.T) {
+		t.Parallel()
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				subgraphCache(),
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			for i := 0; i < 2; i++ {
+				res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+					Query: `{ employees { id } }`,
+				})
+				require.JSONEq(t, employeesIDData, res.Body)
+			}
+			require.Equal(t, int64(2), xEnv.SubgraphRequestCount.Employees.Load())
+		})
+	})
+
+	t.Run("entity fetch is served from the cache", func(t *testing.T) {
+		t.Parallel()
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				subgraphCache(),
+			},
+			Subgraphs: testenv.SubgraphsConfig{
+				Employees: testenv.SubgraphConfig{
+					Middleware: cacheControl("no-store"),
+				},
+				Hobbies: testenv.SubgraphConfig{
+					Middleware: cacheControl("max-age=60"),
+				},
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			for i := 0; i < 2; i++ {
+				res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+					Query: queryEmployeeWithHobby,
+				})
+				require.Equal(t, `{"data":{"employee":{"id":1,"hobbies":[{},{"name":"Counter Strike"},{},{},{}]}}}`, res.Body)
+			}
+			require.Equal(t, int64(2), xEnv.SubgraphRequestCount.Employees.Load())
+			require.Equal(t, int64(1), xEnv.SubgraphRequestCount.Hobbies.Load())
+		})
+	})
+
+	t.Run("cache is scoped by the configured headers", func(t *testing.T) {
+		t.Parallel()
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				subgraphCache("X-Tenant-ID"),
+			},
+			Subgraphs: testenv.SubgraphsConfig{
+				Employees: testenv.SubgraphConfig{
+					Middleware: cacheControl("max-age=60"),
+				},
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			for _, tenant := range []string{"a", "b", "a"} {
+				res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+					Query:  `{ employees { id } }`,
+					Header: http.Header{"X-Tenant-ID": []string{tenant}},
+				})
+				require.JSONEq(t, employeesIDData, res.Body)
+			}
+			require.Equal(t, int64(2), xEnv.SubgraphRequestCount.Employees.Load())
+		})
+	})
+
+	t.Run("cache is scoped by the forwarded headers", func(t *testing.T) {
+		t.Parallel()
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				subgraphCache(),
+				core.WithHeaderRules(config.HeaderRules{
+					All: &config.GlobalHeaderRule{
+						Request: []*config.RequestHeaderRule{
+							{
+								Operation: config.HeaderRuleOperationPropagate,
+								Named:     "X-Tenant-ID",
+							},
+						},
+					},
+				}),
+			},
+			Subgraphs: testenv.SubgraphsConfig{
+				Employees: testenv.SubgraphConfig{
+					Middleware: cacheControl("max-age=60"),
+				},
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			for _, tenant := range []string{"a", "b", "a"} {
+				res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+					Query:  `{ employees { id } }`,
+					Header: http.Header{"X-Tenant-ID": []string{tenant}},
+				})
+				require.JSONEq(t, employeesIDData, res.Body)
+			}
+			require.Equal(t, int64(2), xEnv.SubgraphRequestCount.Employees.Load())
+		})
+	})
+
+	t.Run("mutations are not cached", func(t *testing.T) {
+		t.Parallel()
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				subgraphCache(),
+			},
+			Subgraphs: testenv.SubgraphsConfig{
+				Employees: testenv.SubgraphConfig{
+					Middleware: cacheControl("max-age=60"),
+				},
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			for i := 0; i < 2; i++ {
+				xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+					Query: `mutation { updateEmployeeTag(id: 1, tag: "test") { id } }`,
+				})
+			}
+			require.Equal(t, int64(2), xEnv.SubgraphRequestCount.Employees.Load())
+		})
+	})
+}
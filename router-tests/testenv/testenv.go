@@ -115,6 +115,7 @@ type Config struct {
 	ModifySubgraphErrorPropagation     func(subgraphErrorPropagation *config.SubgraphErrorPropagationConfiguration)
 	ModifyWebsocketConfiguration       func(websocketConfiguration *config.WebSocketConfiguration)
 	ModifyCDNConfig                    func(cdnConfig *config.CDNConfiguration)
+	ModifyEventsConfiguration          func(eventsConfiguration *config.EventsConfiguration)
 	KafkaSeeds                         []string
 	DisableWebSockets                  bool
 	DisableParentBasedSampler          bool
@@ -643,6 +644,17 @@ func configureRouter(listenerAddr string, testConfig *Config, routerConfig *node
 		})
 	}
 
+	eventsConfiguration := config.EventsConfiguration{
+		Providers: config.EventProviders{
+			Nats:  natsEventSources,
+			Kafka: kafkaEventSources,
+		},
+	}
+
+	if testConfig.ModifyEventsConfiguration != nil {
+		testConfig.ModifyEventsConfiguration(&eventsConfiguration)
+	}
+
 	routerOpts := []core.Option{
 		core.WithLogger(zapLogger),
 		core.WithGraphApiToken(graphApiToken),
@@ -658,12 +670,7 @@ func configureRouter(listenerAddr string, testConfig *Config, routerConfig *node
 		core.WithGracePeriod(15 * time.Second),
 		core.WithIntrospection(true),
 		core.WithQueryPlans(true),
-		core.WithEvents(config.EventsConfiguration{
-			Providers: config.EventProviders{
-				Nats:  natsEventSources,
-				Kafka: kafkaEventSources,
-			},
-		}),
+		core.WithEvents(eventsConfiguration),
 	}
 	routerOpts = append(routerOpts, testConfig.RouterOptions...)
 
@@ -1503,7 +1510,7 @@ func subgraphOptions(ctx context.Context, t testing.TB, natsServer *natsserver.S
 		js, err := jetstream.New(natsConnection)
 		require.NoError(t, err)
 
-		natsPubSubByProviderID[sourceName] = pubsubNats.NewConnector(zap.NewNop(), natsConnection, js).New(ctx)
+		natsPubSubByProviderID[sourceName] = pubsubNats.NewConnector(zap.NewNop(), natsConnection, js, pubsubNats.Options{}).New(ctx)
 	}
 
 	return &subgraphs.SubgraphOptions{
@@ -0,0 +1,272 @@
+package integration_test
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/wundergraph/cosmo/router-tests/testenv"
+	"github.com/wundergraph/cosmo/router/core"
+)
+
+func TestSubgraphTrafficShaping(t *testing.T) {
+	t.Parallel()
+
+	subgraphTimeout := func(timeout time.Duration) *core.SubgraphTrafficOptions {
+		transport := core.DefaultSubgraphTransportOptions()
+		transport.RequestTimeout = timeout
+		return &core.SubgraphTrafficOptions{
+			Transport: transport,
+		}
+	}
+
+	t.Run("subgraph timeout overrides the timeout of all subgraphs", func(t *testing.T) {
+		t.Parallel()
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				core.WithSubgraphTrafficOptions(map[string]*core.SubgraphTrafficOptions{
+					"employees": subgraphTimeout(100 * time.Millisecond),
+				}),
+			},
+			Subgraphs: testenv.SubgraphsConfig{
+				Employees: testenv.SubgraphConfig{
+					Delay: 500 * time.Millisecond,
+				},
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+				Query: `{ employees { id } }`,
+			})
+			require.Equal(t, `{"errors":[{"message":"Failed to fetch from Subgraph 'employees'."}],"data":{"employees":null}}`, res.Body)
+		})
+	})
+
+	t.Run("other subgraphs keep the timeout of all subgraphs", func(t *testing.T) {
+		t.Parallel()
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				core.WithSubgraphTrafficOptions(map[string]*core.SubgraphTrafficOptions{
+					"family": subgraphTimeout(100 * time.Millisecond),
+				}),
+			},
+			Subgraphs: testenv.SubgraphsConfig{
+				Employees: testenv.SubgraphConfig{
+					Delay: 500 * time.Millisecond,
+				},
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+				Query: `{ employees { id } }`,
+			})
+			require.JSONEq(t, employeesIDData, res.Body)
+		})
+	})
+}
+
+func TestSubgraphCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	circuitBreaker := func(cooldown time.Duration) core.Option {
+		return core.WithSubgraphCircuitBreakerOptions(core.SubgraphCircuitBreakerOptions{
+			Enabled:                  true,
+			ErrorThresholdPercentage: 50,
+			MinimumRequests:          2,
+			Window:                   time.Minute,
+			Cooldown:                 cooldown,
+			HalfOpenRequests:         1,
+		})
+	}
+
+	failingSubgraph := func(requests *atomic.Int64, healthy *atomic.Bool) func(http.Handler) http.Handler {
+		return func(handler http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				if healthy.Load() {
+					handler.ServeHTTP(w, r)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte(`{"errors":[{"message":"Internal Server Error"}]}`))
+			})
+		}
+	}
+
+	t.Run("open circuit rejects requests without calling the subgraph", func(t *testing.T) {
+		t.Parallel()
+
+		var requests atomic.Int64
+		var healthy atomic.Bool
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				circuitBreaker(time.Minute),
+			},
+			Subgraphs: testenv.SubgraphsConfig{
+				Employees: testenv.SubgraphConfig{
+					Middleware: failingSubgraph(&requests, &healthy),
+				},
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			for i := 0; i < 2; i++ {
+				res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+					Query: `{ employees { id } }`,
+				})
+				require.Contains(t, res.Body, `Internal Server Error`)
+			}
+			require.Equal(t, int64(2), requests.Load())
+
+			res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+				Query: `{ employees { id } }`,
+			})
+			require.Contains(t, res.Body, `"message":"Subgraph 'employees' is unavailable."`)
+			require.Contains(t, res.Body, `"code":"SUBGRAPH_UNAVAILABLE"`)
+			require.Equal(t, int64(2), requests.Load())
+		})
+	})
+
+	t.Run("circuit closes after a successful probe", func(t *testing.T) {
+		t.Parallel()
+
+		var requests atomic.Int64
+		var healthy atomic.Bool
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				circuitBreaker(200 * time.Millisecond),
+			},
+			Subgraphs: testenv.SubgraphsConfig{
+				Employees: testenv.SubgraphConfig{
+					Middleware: failingSubgraph(&requests, &healthy),
+				},
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			for i := 0; i < 3; i++ {
+				xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+					Query: `{ employees { id } }`,
+				})
+			}
+			require.Equal(t, int64(2), requests.Load())
+
+			healthy.Store(true)
+			time.Sleep(200 * time.Millisecond)
+
+			for i := 0; i < 2; i++ {
+				res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+					Query: `{ employees { id } }`,
+				})
+				require.JSONEq(t, employeesIDData, res.Body)
+			}
+			require.Equal(t, int64(4), requests.Load())
+		})
+	})
+
+	t.Run("circuit of other subgraphs stays closed", func(t *testing.T) {
+		t.Parallel()
+
+		var requests atomic.Int64
+		var healthy atomic.Bool
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				core.WithSubgraphTrafficOptions(map[string]*core.SubgraphTrafficOptions{
+					"family": {
+						Transport: core.DefaultSubgraphTransportOptions(),
+						CircuitBreaker: core.SubgraphCircuitBreakerOptions{
+							Enabled:         true,
+							MinimumRequests: 1,
+							Cooldown:        time.Minute,
+						},
+					},
+				}),
+			},
+			Subgraphs: testenv.SubgraphsConfig{
+				Employees: testenv.SubgraphConfig{
+					Middleware: failingSubgraph(&requests, &healthy),
+				},
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			for i := 0; i < 3; i++ {
+				res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+					Query: `{ employees { id } }`,
+				})
+				require.NotContains(t, res.Body, `SUBGRAPH_UNAVAILABLE`)
+			}
+			require.Equal(t, int64(3), requests.Load())
+		})
+	})
+}
+
+func TestSubgraphRetry(t *testing.T) {
+	t.Parallel()
+
+	failingOnce := func(requests *atomic.Int64, statusCode int, header http.Header) func(http.Handler) http.Handler {
+		return func(handler http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if requests.Add(1) > 1 {
+					handler.ServeHTTP(w, r)
+					return
+				}
+				for key, values := range header {
+					w.Header()[key] = values
+				}
+				w.WriteHeader(statusCode)
+			})
+		}
+	}
+
+	t.Run("retry after the duration of the Retry-After header", func(t *testing.T) {
+		t.Parallel()
+
+		var requests atomic.Int64
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				core.WithSubgraphRetryOptions(true, 3, 10*time.Second, 10*time.Second),
+			},
+			Subgraphs: testenv.SubgraphsConfig{
+				Employees: testenv.SubgraphConfig{
+					Middleware: failingOnce(&requests, http.StatusServiceUnavailable, http.Header{"Retry-After": []string{"0"}}),
+				},
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			start := time.Now()
+			res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+				Query: `{ employees { id } }`,
+			})
+			require.JSONEq(t, employeesIDData, res.Body)
+			require.Equal(t, int64(2), requests.Load())
+			// The backoff of up to 10s is replaced by the Retry-After header
+			require.Less(t, time.Since(start), 5*time.Second)
+		})
+	})
+
+	t.Run("retry configured status codes", func(t *testing.T) {
+		t.Parallel()
+
+		var requests atomic.Int64
+
+		testenv.Run(t, &testenv.Config{
+			RouterOptions: []core.Option{
+				core.WithSubgraphRetryOptions(true, 3, 10*time.Millisecond, time.Millisecond),
+				core.WithSubgraphRetryConditions([]int{http.StatusConflict}, nil),
+			},
+			Subgraphs: testenv.SubgraphsConfig{
+				Employees: testenv.SubgraphConfig{
+					Middleware: failingOnce(&requests, http.StatusConflict, nil),
+				},
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+			res := xEnv.MakeGraphQLRequestOK(testenv.GraphQLRequest{
+				Query: `{ employees { id } }`,
+			})
+			require.JSONEq(t, employeesIDData, res.Body)
+			require.Equal(t, int64(2), requests.Load())
+		})
+	})
+}
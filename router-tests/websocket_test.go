@@ -1192,6 +1192,31 @@ func TestWebSockets(t *testing.T) {
 			require.Equal(t, `[{"message":"operation type 'subscription' is blocked"}]`, string(msg.Payload))
 		})
 	})
+	t.Run("operation limits apply to websocket operations", func(t *testing.T) {
+		t.Parallel()
+		testenv.Run(t, &testenv.Config{
+			ModifySecurityConfiguration: func(securityConfiguration *config.SecurityConfiguration) {
+				securityConfiguration.ComplexityLimits.TokenCount.Enabled = true
+				securityConfiguration.ComplexityLimits.TokenCount.Limit = 4
+			},
+		}, func(t *testing.T, xEnv *testenv.Environment) {
+
+			conn := xEnv.InitGraphQLWebSocketConnection(nil, nil, nil)
+			err := conn.WriteJSON(&testenv.WebSocketMessage{
+				ID:      "1",
+				Type:    "subscribe",
+				Payload: []byte(`{"query":"{ employees { id } }"}`),
+			})
+			require.NoError(t, err)
+
+			var msg testenv.WebSocketMessage
+			err = conn.ReadJSON(&msg)
+			require.NoError(t, err)
+			require.Equal(t, "1", msg.ID)
+			require.Equal(t, "error", msg.Type)
+			require.Equal(t, `[{"message":"The token count 5 exceeds the max token count allowed (4)"}]`, string(msg.Payload))
+		})
+	})
 	t.Run("multiple subscriptions one connection", func(t *testing.T) {
 		testenv.Run(t, &testenv.Config{
 			ModifyEngineExecutionConfiguration: func(engineExecutionConfiguration *config.EngineExecutionConfiguration) {
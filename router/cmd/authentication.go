@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"github.com/wundergraph/cosmo/router/pkg/authentication"
+	"github.com/wundergraph/cosmo/router/pkg/config"
+)
+
+const defaultAPIKeyHeaderName = "X-API-Key"
+
+// tokenSource describes where the token of an authentication provider is read from and how it is decoded
+type tokenSource struct {
+	kind                string
+	url                 string
+	headerNames         []string
+	headerValuePrefixes []string
+	tokenDecoder        authentication.TokenDecoder
+}
+
+// newTokenSource creates the token decoder of the configured provider. It returns nil if no provider is configured.
+func newTokenSource(auth config.AuthenticationProvider) (*tokenSource, error) {
+	switch {
+	case auth.JWKS != nil:
+		tokenDecoder, err := authentication.NewValidatingJwksTokenDecoder(auth.JWKS.URL, auth.JWKS.RefreshInterval, authentication.TokenValidationOptions{
+			Issuer:            auth.JWKS.Issuer,
+			Audiences:         auth.JWKS.Audiences,
+			AllowedAlgorithms: auth.JWKS.AllowedAlgorithms,
+			RequiredClaims:    auth.JWKS.RequiredClaims,
+			Leeway:            auth.JWKS.Leeway,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &tokenSource{
+			kind:                "jwks",
+			url:                 auth.JWKS.URL,
+			headerNames:         auth.JWKS.HeaderNames,
+			headerValuePrefixes: auth.JWKS.HeaderValuePrefixes,
+			tokenDecoder:        tokenDecoder,
+		}, nil
+	case auth.APIKeys != nil:
+		keys := make([]authentication.APIKey, 0, len(auth.APIKeys.Keys))
+		for _, key := range auth.APIKeys.Keys {
+			keys = append(keys, authentication.APIKey{
+				Name:   key.Name,
+				Hash:   key.Hash,
+				Scopes: key.Scopes,
+				Claims: key.Claims,
+			})
+		}
+		if auth.APIKeys.File != "" {
+			fileKeys, err := authentication.LoadAPIKeys(auth.APIKeys.File)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, fileKeys...)
+		}
+		tokenDecoder, err := authentication.NewAPIKeyTokenDecoder(keys)
+		if err != nil {
+			return nil, err
+		}
+		headerNames := auth.APIKeys.HeaderNames
+		if len(headerNames) == 0 {
+			headerNames = []string{defaultAPIKeyHeaderName}
+		}
+		headerValuePrefixes := auth.APIKeys.HeaderValuePrefixes
+		if len(headerValuePrefixes) == 0 {
+			// The whole header value is the key
+			headerValuePrefixes = []string{""}
+		}
+		return &tokenSource{
+			kind:                "api-keys",
+			headerNames:         headerNames,
+			headerValuePrefixes: headerValuePrefixes,
+			tokenDecoder:        tokenDecoder,
+		}, nil
+	case auth.SharedSecret != nil:
+		tokenDecoder, err := authentication.NewSharedSecretTokenDecoder([]byte(auth.SharedSecret.Secret), authentication.TokenValidationOptions{
+			Issuer:            auth.SharedSecret.Issuer,
+			Audiences:         auth.SharedSecret.Audiences,
+			AllowedAlgorithms: auth.SharedSecret.AllowedAlgorithms,
+			RequiredClaims:    auth.SharedSecret.RequiredClaims,
+			Leeway:            auth.SharedSecret.Leeway,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &tokenSource{
+			kind:                "shared-secret",
+			headerNames:         auth.SharedSecret.HeaderNames,
+			headerValuePrefixes: auth.SharedSecret.HeaderValuePrefixes,
+			tokenDecoder:        tokenDecoder,
+		}, nil
+	case auth.Introspection != nil:
+		tokenDecoder, err := authentication.NewIntrospectionTokenDecoder(authentication.IntrospectionTokenDecoderOptions{
+			URL:          auth.Introspection.URL,
+			ClientID:     auth.Introspection.ClientID,
+			ClientSecret: auth.Introspection.ClientSecret,
+			Timeout:      auth.Introspection.Timeout,
+			CacheTTL:     auth.Introspection.CacheTTL,
+			CacheSize:    auth.Introspection.CacheSize,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &tokenSource{
+			kind:                "introspection",
+			url:                 auth.Introspection.URL,
+			headerNames:         auth.Introspection.HeaderNames,
+			headerValuePrefixes: auth.Introspection.HeaderValuePrefixes,
+			tokenDecoder:        tokenDecoder,
+		}, nil
+	}
+	return nil, nil
+}
@@ -60,37 +60,41 @@ func NewRouter(params Params, additionalOptions ...core.Option) (*core.Router, e
 
 	var authenticators []authentication.Authenticator
 	for i, auth := range cfg.Authentication.Providers {
-		if auth.JWKS != nil {
-			name := auth.Name
-			if name == "" {
-				name = fmt.Sprintf("jwks-#%d", i)
-			}
-			tokenDecoder, _ := authentication.NewJwksTokenDecoder(auth.JWKS.URL, auth.JWKS.RefreshInterval)
-			opts := authentication.HttpHeaderAuthenticatorOptions{
-				Name:                name,
-				URL:                 auth.JWKS.URL,
-				HeaderNames:         auth.JWKS.HeaderNames,
-				HeaderValuePrefixes: auth.JWKS.HeaderValuePrefixes,
-				TokenDecoder:        tokenDecoder,
+		source, err := newTokenSource(auth)
+		if err != nil {
+			logger.Fatal("Could not create token decoder", zap.Error(err), zap.String("name", auth.Name))
+		}
+		if source == nil {
+			continue
+		}
+		name := auth.Name
+		if name == "" {
+			name = fmt.Sprintf("%s-#%d", source.kind, i)
+		}
+		opts := authentication.HttpHeaderAuthenticatorOptions{
+			Name:                name,
+			URL:                 source.url,
+			HeaderNames:         source.headerNames,
+			HeaderValuePrefixes: source.headerValuePrefixes,
+			TokenDecoder:        source.tokenDecoder,
+		}
+		authenticator, err := authentication.NewHttpHeaderAuthenticator(opts)
+		if err != nil {
+			logger.Fatal("Could not create HttpHeader authenticator", zap.Error(err), zap.String("name", name))
+		}
+		authenticators = append(authenticators, authenticator)
+
+		if cfg.WebSocket.Authentication.FromInitialPayload.Enabled {
+			opts := authentication.WebsocketInitialPayloadAuthenticatorOptions{
+				TokenDecoder:        source.tokenDecoder,
+				Key:                 cfg.WebSocket.Authentication.FromInitialPayload.Key,
+				HeaderValuePrefixes: source.headerValuePrefixes,
 			}
-			authenticator, err := authentication.NewHttpHeaderAuthenticator(opts)
+			authenticator, err = authentication.NewWebsocketInitialPayloadAuthenticator(opts)
 			if err != nil {
-				logger.Fatal("Could not create HttpHeader authenticator", zap.Error(err), zap.String("name", name))
+				logger.Fatal("Could not create WebsocketInitialPayload authenticator", zap.Error(err))
 			}
 			authenticators = append(authenticators, authenticator)
-
-			if cfg.WebSocket.Authentication.FromInitialPayload.Enabled {
-				opts := authentication.WebsocketInitialPayloadAuthenticatorOptions{
-					TokenDecoder:        tokenDecoder,
-					Key:                 cfg.WebSocket.Authentication.FromInitialPayload.Key,
-					HeaderValuePrefixes: auth.JWKS.HeaderValuePrefixes,
-				}
-				authenticator, err = authentication.NewWebsocketInitialPayloadAuthenticator(opts)
-				if err != nil {
-					logger.Fatal("Could not create WebsocketInitialPayload authenticator", zap.Error(err))
-				}
-				authenticators = append(authenticators, authenticator)
-			}
 		}
 	}
 
@@ -126,21 +130,25 @@ func NewRouter(params Params, additionalOptions ...core.Option) (*core.Router, e
 		core.WithHeaderRules(cfg.Headers),
 		core.WithRouterTrafficConfig(&cfg.TrafficShaping.Router),
 		core.WithFileUploadConfig(&cfg.FileUpload),
-		core.WithSubgraphTransportOptions(&core.SubgraphTransportOptions{
-			RequestTimeout:         cfg.TrafficShaping.All.RequestTimeout,
-			ResponseHeaderTimeout:  cfg.TrafficShaping.All.ResponseHeaderTimeout,
-			ExpectContinueTimeout:  cfg.TrafficShaping.All.ExpectContinueTimeout,
-			KeepAliveIdleTimeout:   cfg.TrafficShaping.All.KeepAliveIdleTimeout,
-			DialTimeout:            cfg.TrafficShaping.All.DialTimeout,
-			TLSHandshakeTimeout:    cfg.TrafficShaping.All.TLSHandshakeTimeout,
-			KeepAliveProbeInterval: cfg.TrafficShaping.All.KeepAliveProbeInterval,
-		}),
+		core.WithSubgraphTransportOptions(subgraphTransportOptions(cfg.TrafficShaping.All)),
 		core.WithSubgraphRetryOptions(
 			cfg.TrafficShaping.All.BackoffJitterRetry.Enabled,
 			cfg.TrafficShaping.All.BackoffJitterRetry.MaxAttempts,
 			cfg.TrafficShaping.All.BackoffJitterRetry.MaxDuration,
 			cfg.TrafficShaping.All.BackoffJitterRetry.Interval,
 		),
+		core.WithSubgraphRetryConditions(
+			cfg.TrafficShaping.All.BackoffJitterRetry.StatusCodes,
+			cfg.TrafficShaping.All.BackoffJitterRetry.Errors,
+		),
+		core.WithSubgraphRetryBudget(
+			cfg.TrafficShaping.RetryBudget.Enabled,
+			cfg.TrafficShaping.RetryBudget.Percentage,
+			cfg.TrafficShaping.RetryBudget.MinRetries,
+			cfg.TrafficShaping.RetryBudget.Window,
+		),
+		core.WithSubgraphCircuitBreakerOptions(subgraphCircuitBreakerOptions(cfg.TrafficShaping.All.CircuitBreaker)),
+		core.WithSubgraphTrafficOptions(subgraphTrafficOptions(&cfg.TrafficShaping)),
 		core.WithCors(&cors.Config{
 			Enabled:          cfg.CORS.Enabled,
 			AllowOrigins:     cfg.CORS.AllowOrigins,
@@ -170,6 +178,8 @@ func NewRouter(params Params, additionalOptions ...core.Option) (*core.Router, e
 		core.WithCDN(cfg.CDN),
 		core.WithEvents(cfg.Events),
 		core.WithRateLimitConfig(&cfg.RateLimit),
+		core.WithSubgraphCache(&cfg.SubgraphCache),
+		core.WithResponseCache(&cfg.ResponseCache),
 	}
 
 	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY
@@ -214,6 +224,51 @@ func NewRouter(params Params, additionalOptions ...core.Option) (*core.Router, e
 	return core.NewRouter(options...)
 }
 
+func subgraphTransportOptions(rule config.GlobalSubgraphRequestRule) *core.SubgraphTransportOptions {
+	return &core.SubgraphTransportOptions{
+		RequestTimeout:         rule.RequestTimeout,
+		ResponseHeaderTimeout:  rule.ResponseHeaderTimeout,
+		ExpectContinueTimeout:  rule.ExpectContinueTimeout,
+		KeepAliveIdleTimeout:   rule.KeepAliveIdleTimeout,
+		DialTimeout:            rule.DialTimeout,
+		TLSHandshakeTimeout:    rule.TLSHandshakeTimeout,
+		KeepAliveProbeInterval: rule.KeepAliveProbeInterval,
+	}
+}
+
+// subgraphTrafficOptions returns the options of the subgraphs that override the rules of all subgraphs
+func subgraphTrafficOptions(rules *config.TrafficShapingRules) map[string]*core.SubgraphTrafficOptions {
+	options := make(map[string]*core.SubgraphTrafficOptions, len(rules.Subgraphs))
+	for name := range rules.Subgraphs {
+		rule := rules.SubgraphRule(name)
+		options[name] = &core.SubgraphTrafficOptions{
+			Transport: subgraphTransportOptions(rule),
+			Retry: core.SubgraphRetryOptions{
+				Enabled:       rule.BackoffJitterRetry.Enabled,
+				MaxRetryCount: rule.BackoffJitterRetry.MaxAttempts,
+				MaxDuration:   rule.BackoffJitterRetry.MaxDuration,
+				Interval:      rule.BackoffJitterRetry.Interval,
+				StatusCodes:   rule.BackoffJitterRetry.StatusCodes,
+				Errors:        rule.BackoffJitterRetry.Errors,
+			},
+			CircuitBreaker: subgraphCircuitBreakerOptions(rule.CircuitBreaker),
+		}
+	}
+	return options
+}
+
+func subgraphCircuitBreakerOptions(cfg config.CircuitBreaker) core.SubgraphCircuitBreakerOptions {
+	return core.SubgraphCircuitBreakerOptions{
+		Enabled:                  cfg.Enabled,
+		ErrorThresholdPercentage: cfg.ErrorThresholdPercentage,
+		LatencyThreshold:         cfg.LatencyThreshold,
+		MinimumRequests:          cfg.MinimumRequests,
+		Window:                   cfg.Window,
+		Cooldown:                 cfg.Cooldown,
+		HalfOpenRequests:         cfg.HalfOpenRequests,
+	}
+}
+
 func hasProxyConfigured() bool {
 	_, httpProxy := os.LookupEnv("HTTP_PROXY")
 	_, httpsProxy := os.LookupEnv("HTTPS_PROXY")
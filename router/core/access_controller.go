@@ -2,6 +2,7 @@ package core
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/wundergraph/cosmo/router/pkg/authentication"
@@ -14,6 +15,20 @@ var (
 	ErrUnauthorized = errors.New("unauthorized")
 )
 
+// UnauthorizedError is returned when the token of the request is rejected by the claim validation.
+// It matches ErrUnauthorized and includes the reason in the error message.
+type UnauthorizedError struct {
+	Reason string
+}
+
+func (e *UnauthorizedError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrUnauthorized, e.Reason)
+}
+
+func (e *UnauthorizedError) Is(target error) bool {
+	return target == ErrUnauthorized
+}
+
 // AccessController handles both authentication and authorization for the Router
 type AccessController struct {
 	authenticationRequired bool
@@ -33,6 +48,10 @@ func NewAccessController(authenticators []authentication.Authenticator, authenti
 func (a *AccessController) Access(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
 	auth, err := authentication.AuthenticateHTTPRequest(r.Context(), a.authenticators, r)
 	if err != nil {
+		var validationErr *authentication.TokenValidationError
+		if errors.As(err, &validationErr) {
+			return nil, &UnauthorizedError{Reason: validationErr.Reason}
+		}
 		return nil, ErrUnauthorized
 	}
 	if auth != nil {
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	persistedQueryNotFoundErrorCode = "PERSISTED_QUERY_NOT_FOUND"
+	// persistedQueryNotFoundMessage is the message Apollo clients expect before they resend the query
+	persistedQueryNotFoundMessage = "PersistedQueryNotFound"
+)
+
+// PersistedQueryNotFoundError is returned if the hash of an Automatic Persisted Query is unknown
+type PersistedQueryNotFoundError struct {
+	Sha256Hash string
+}
+
+func (e *PersistedQueryNotFoundError) Error() string {
+	return persistedQueryNotFoundMessage
+}
+
+func (e *PersistedQueryNotFoundError) Message() string {
+	return e.Error()
+}
+
+func (e *PersistedQueryNotFoundError) StatusCode() int {
+	return http.StatusOK
+}
+
+func (e *PersistedQueryNotFoundError) graphqlError() graphqlError {
+	return graphqlError{
+		Message: e.Error(),
+		Extensions: &Extensions{
+			Code: persistedQueryNotFoundErrorCode,
+		},
+	}
+}
+
+var (
+	_ HttpError = (*PersistedQueryNotFoundError)(nil)
+)
+
+// automaticPersistedQueries registers the operations of clients that use Automatic Persisted Queries (APQ).
+// The storage is shared by all graph servers, because the registered operations don't depend on the schema.
+type automaticPersistedQueries struct {
+	storage ResponseCacheStorage
+	ttl     time.Duration
+	logger  *zap.Logger
+}
+
+func newAutomaticPersistedQueries(storage ResponseCacheStorage, ttl time.Duration, logger *zap.Logger) *automaticPersistedQueries {
+	return &automaticPersistedQueries{
+		storage: storage,
+		ttl:     ttl,
+		logger:  logger,
+	}
+}
+
+// register stores the query under its hash. The hash must be the hex encoded sha256 of the query.
+func (a *automaticPersistedQueries) register(ctx context.Context, sha256Hash, query string) error {
+	sum := sha256.Sum256([]byte(query))
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), sha256Hash) {
+		return &httpGraphqlError{
+			message:    "provided sha does not match query",
+			statusCode: http.StatusBadRequest,
+		}
+	}
+
+	if err := a.storage.Set(ctx, []string{strings.ToLower(sha256Hash)}, [][]byte{[]byte(query)}, a.ttl); err != nil {
+		// The client resends the query if the registration is lost
+		a.logger.Warn("Failed to register automatic persisted query", zap.String("sha256_hash", sha256Hash), zap.Error(err))
+	}
+	return nil
+}
+
+// query returns nil if the hash isn't registered
+func (a *automaticPersistedQueries) query(ctx context.Context, sha256Hash string) []byte {
+	values, err := a.storage.Get(ctx, []string{strings.ToLower(sha256Hash)})
+	if err != nil {
+		a.logger.Warn("Failed to load automatic persisted query", zap.String("sha256_hash", sha256Hash), zap.Error(err))
+		return nil
+	}
+	return values[0]
+}
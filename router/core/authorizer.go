@@ -3,35 +3,54 @@ package core
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"slices"
+	"strings"
 	"sync"
 
 	nodev1 "github.com/wundergraph/cosmo/router/gen/proto/wg/cosmo/node/v1"
 	"github.com/wundergraph/cosmo/router/pkg/authentication"
+	"github.com/wundergraph/cosmo/router/pkg/config"
 	"github.com/wundergraph/graphql-go-tools/v2/pkg/engine/resolve"
 )
 
 type CosmoAuthorizerOptions struct {
 	FieldConfigurations           []*nodev1.FieldConfiguration
 	RejectOperationIfUnauthorized bool
+	// ScopeClaimPaths are the claims the scopes are read from. The scopes of the Authentication are used if empty.
+	ScopeClaimPaths     []string
+	ScopePrefixMappings []config.ScopePrefixMapping
+	// FieldPolicies are evaluated after the scopes of a field are validated
+	FieldPolicies []config.FieldPolicy
 }
 
 func NewCosmoAuthorizer(opts *CosmoAuthorizerOptions) *CosmoAuthorizer {
+	fieldPolicies := make(map[string][]config.FieldPolicy, len(opts.FieldPolicies))
+	for _, policy := range opts.FieldPolicies {
+		fieldPolicies[policy.Coordinate] = append(fieldPolicies[policy.Coordinate], policy)
+	}
 	return &CosmoAuthorizer{
 		fieldConfigurations: opts.FieldConfigurations,
 		rejectUnauthorized:  opts.RejectOperationIfUnauthorized,
+		scopeClaimPaths:     opts.ScopeClaimPaths,
+		scopePrefixMappings: opts.ScopePrefixMappings,
+		fieldPolicies:       fieldPolicies,
 	}
 }
 
 type CosmoAuthorizer struct {
 	fieldConfigurations []*nodev1.FieldConfiguration
 	rejectUnauthorized  bool
+	scopeClaimPaths     []string
+	scopePrefixMappings []config.ScopePrefixMapping
+	// fieldPolicies are keyed by the coordinate of the field
+	fieldPolicies map[string][]config.FieldPolicy
 }
 
 func (a *CosmoAuthorizer) HasResponseExtensionData(ctx *resolve.Context) bool {
 	extension := a.getAuthorizationExtension(ctx)
-	return extension != nil && len(extension.MissingScopes) > 0
+	return extension != nil && (len(extension.MissingScopes) > 0 || len(extension.DeniedPolicies) > 0)
 }
 
 func (a *CosmoAuthorizer) RenderResponseExtension(ctx *resolve.Context, out io.Writer) error {
@@ -52,7 +71,44 @@ func (a *CosmoAuthorizer) getAuth(ctx context.Context) (isAuthenticated bool, sc
 	if auth == nil {
 		return false, nil
 	}
-	return true, auth.Scopes()
+	return true, a.scopes(auth)
+}
+
+// scopes reads the scopes from the configured claims. Claims can be space separated strings, like the
+// "scope" claim of OAuth2, or arrays, like the "permissions" of Auth0 or the "realm_access.roles" of Keycloak.
+func (a *CosmoAuthorizer) scopes(auth authentication.Authentication) []string {
+	var scopes []string
+	if len(a.scopeClaimPaths) == 0 {
+		scopes = auth.Scopes()
+	} else {
+		claims := auth.Claims()
+		for _, path := range a.scopeClaimPaths {
+			switch value := lookupClaim(claims, path).(type) {
+			case string:
+				scopes = append(scopes, strings.Fields(value)...)
+			case []any:
+				for _, element := range value {
+					if scope, ok := element.(string); ok {
+						scopes = append(scopes, scope)
+					}
+				}
+			}
+		}
+	}
+	if len(a.scopePrefixMappings) == 0 {
+		return scopes
+	}
+	mapped := make([]string, len(scopes))
+	for i, scope := range scopes {
+		mapped[i] = scope
+		for _, mapping := range a.scopePrefixMappings {
+			if strings.HasPrefix(scope, mapping.From) {
+				mapped[i] = mapping.To + strings.TrimPrefix(scope, mapping.From)
+				break
+			}
+		}
+	}
+	return mapped
 }
 
 func (a *CosmoAuthorizer) handleRejectUnauthorized(result *resolve.AuthorizationDeny) (*resolve.AuthorizationDeny, error) {
@@ -68,13 +124,21 @@ func (a *CosmoAuthorizer) handleRejectUnauthorized(result *resolve.Authorization
 func (a *CosmoAuthorizer) AuthorizePreFetch(ctx *resolve.Context, dataSourceID string, input json.RawMessage, coordinate resolve.GraphCoordinate) (result *resolve.AuthorizationDeny, err error) {
 	isAuthenticated, actual := a.getAuth(ctx.Context())
 	required := a.requiredScopesForField(coordinate)
-	return a.handleRejectUnauthorized(a.validateScopes(ctx, coordinate, required, isAuthenticated, actual))
+	result = a.validateScopes(ctx, coordinate, required, isAuthenticated, actual)
+	if result == nil {
+		result = a.validateFieldPolicies(ctx, coordinate, actual)
+	}
+	return a.handleRejectUnauthorized(result)
 }
 
 func (a *CosmoAuthorizer) AuthorizeObjectField(ctx *resolve.Context, dataSourceID string, object json.RawMessage, coordinate resolve.GraphCoordinate) (result *resolve.AuthorizationDeny, err error) {
 	isAuthenticated, actual := a.getAuth(ctx.Context())
 	required := a.requiredScopesForField(coordinate)
-	return a.handleRejectUnauthorized(a.validateScopes(ctx, coordinate, required, isAuthenticated, actual))
+	result = a.validateScopes(ctx, coordinate, required, isAuthenticated, actual)
+	if result == nil {
+		result = a.validateFieldPolicies(ctx, coordinate, actual)
+	}
+	return a.handleRejectUnauthorized(result)
 }
 
 func (a *CosmoAuthorizer) validateScopes(ctx *resolve.Context, coordinate resolve.GraphCoordinate, requiredOrScopes []*nodev1.Scopes, isAuthenticated bool, actual []string) (result *resolve.AuthorizationDeny) {
@@ -108,13 +172,7 @@ func (a *CosmoAuthorizer) addMissingScopes(ctx *resolve.Context, coordinate reso
 	}
 	extension := extensionCtx.(*authorizationExtensionCtx)
 	extension.mux.Lock()
-	if extension.extension.ActualScopes == nil {
-		if len(actual) == 0 {
-			extension.extension.ActualScopes = make([]string, 0)
-		} else {
-			extension.extension.ActualScopes = actual
-		}
-	}
+	extension.setActualScopes(actual)
 	newMissingScopesError := a.missingScopesError(coordinate, requiredOrScopes)
 	if !slices.ContainsFunc(extension.extension.MissingScopes, func(existingMissingScopesError MissingScopesError) bool {
 		return existingMissingScopesError.Coordinate.TypeName == newMissingScopesError.Coordinate.TypeName &&
@@ -125,6 +183,55 @@ func (a *CosmoAuthorizer) addMissingScopes(ctx *resolve.Context, coordinate reso
 	extension.mux.Unlock()
 }
 
+// validateFieldPolicies evaluates the policies of the field against the claims of the request and the arguments
+// of the field. The authorizer is called once per coordinate, so every selection of the field must be allowed.
+// It is only called for authenticated requests.
+func (a *CosmoAuthorizer) validateFieldPolicies(ctx *resolve.Context, coordinate resolve.GraphCoordinate, actual []string) *resolve.AuthorizationDeny {
+	policies := a.fieldPolicies[coordinate.TypeName+"."+coordinate.FieldName]
+	if len(policies) == 0 {
+		return nil
+	}
+	claims := authentication.FromContext(ctx.Context()).Claims()
+	// Without collected arguments, only conditions without arguments can match
+	selections := []map[string]string{nil}
+	if requestContext := getRequestContext(ctx.Context()); requestContext != nil && requestContext.operation != nil {
+		if arguments := requestContext.operation.fieldArguments[coordinate.TypeName+"."+coordinate.FieldName]; len(arguments) > 0 {
+			selections = arguments
+		}
+	}
+	for _, policy := range policies {
+		for _, arguments := range selections {
+			if !fieldPolicyAllows(policy, claims, arguments) {
+				a.addDeniedPolicy(ctx, coordinate, policy.Name, actual)
+				return &resolve.AuthorizationDeny{
+					Reason: fmt.Sprintf("denied by policy '%s'", policy.Name),
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (a *CosmoAuthorizer) addDeniedPolicy(ctx *resolve.Context, coordinate resolve.GraphCoordinate, policy string, actual []string) {
+	extensionCtx := ctx.Context().Value(authorizationExtensionKey{})
+	if extensionCtx == nil {
+		return
+	}
+	extension := extensionCtx.(*authorizationExtensionCtx)
+	extension.mux.Lock()
+	extension.setActualScopes(actual)
+	if !slices.ContainsFunc(extension.extension.DeniedPolicies, func(existing DeniedPolicyError) bool {
+		return existing.Coordinate.TypeName == coordinate.TypeName &&
+			existing.Coordinate.FieldName == coordinate.FieldName
+	}) {
+		extension.extension.DeniedPolicies = append(extension.extension.DeniedPolicies, DeniedPolicyError{
+			Coordinate: coordinate,
+			Policy:     policy,
+		})
+	}
+	extension.mux.Unlock()
+}
+
 func (a *CosmoAuthorizer) getAuthorizationExtension(ctx *resolve.Context) *AuthorizationExtension {
 	extensionCtx := ctx.Context().Value(authorizationExtensionKey{})
 	if extensionCtx == nil {
@@ -139,6 +246,18 @@ type authorizationExtensionCtx struct {
 	mux       sync.Mutex
 }
 
+// setActualScopes has to be called with the lock held
+func (e *authorizationExtensionCtx) setActualScopes(actual []string) {
+	if e.extension.ActualScopes != nil {
+		return
+	}
+	if len(actual) == 0 {
+		e.extension.ActualScopes = make([]string, 0)
+	} else {
+		e.extension.ActualScopes = actual
+	}
+}
+
 type authorizationExtensionKey struct{}
 
 func WithAuthorizationExtension(ctx *resolve.Context) *resolve.Context {
@@ -147,8 +266,9 @@ func WithAuthorizationExtension(ctx *resolve.Context) *resolve.Context {
 }
 
 type AuthorizationExtension struct {
-	MissingScopes []MissingScopesError `json:"missingScopes,omitempty"`
-	ActualScopes  []string             `json:"actualScopes"`
+	MissingScopes  []MissingScopesError `json:"missingScopes,omitempty"`
+	DeniedPolicies []DeniedPolicyError  `json:"deniedPolicies,omitempty"`
+	ActualScopes   []string             `json:"actualScopes"`
 }
 
 type MissingScopesError struct {
@@ -156,6 +276,11 @@ type MissingScopesError struct {
 	RequiredOrScopes [][]string              `json:"required"`
 }
 
+type DeniedPolicyError struct {
+	Coordinate resolve.GraphCoordinate `json:"coordinate"`
+	Policy     string                  `json:"policy"`
+}
+
 type RequiredAndScopes struct {
 	RequiredAndScopes []string `json:"and"`
 }
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wundergraph/graphql-go-tools/v2/pkg/engine/resolve"
+
+	"github.com/wundergraph/cosmo/router/pkg/authentication"
+	"github.com/wundergraph/cosmo/router/pkg/config"
+)
+
+func TestCosmoAuthorizerScopes(t *testing.T) {
+	auth := &testAuthentication{scopes: []string{"read:employee", "openid"}, claims: authentication.Claims{
+		"scope":       "read:employee openid",
+		"scp":         []any{"read:private"},
+		"permissions": []any{"api:write:fact", "api:read:all", 1},
+		"realm_access": map[string]any{
+			"roles": []any{"employees-admin"},
+		},
+	}}
+
+	authorizer := NewCosmoAuthorizer(&CosmoAuthorizerOptions{
+		ScopeClaimPaths: []string{"scope", "scp", "permissions", "realm_access.roles", "missing"},
+		ScopePrefixMappings: []config.ScopePrefixMapping{
+			{From: "api:", To: ""},
+			{From: "employees-", To: "employees:"},
+		},
+	})
+	require.Equal(t, []string{"read:employee", "openid", "read:private", "write:fact", "read:all", "employees:admin"}, authorizer.scopes(auth))
+
+	// Without claim paths the scopes of the authentication are used
+	authorizer = NewCosmoAuthorizer(&CosmoAuthorizerOptions{})
+	require.Equal(t, []string{"read:employee", "openid"}, authorizer.scopes(auth))
+}
+
+func TestCosmoAuthorizerFieldPolicies(t *testing.T) {
+	authorizer := NewCosmoAuthorizer(&CosmoAuthorizerOptions{
+		FieldPolicies: []config.FieldPolicy{
+			{
+				Name:       "own-employee-or-hr",
+				Coordinate: "Query.employee",
+				Allow: []config.FieldPolicyCondition{
+					{Claims: []config.FieldPolicyClaim{{Path: "employee_id", Argument: "id"}}},
+					{Claims: []config.FieldPolicyClaim{{Path: "roles", Values: []string{"hr"}}}},
+				},
+			},
+		},
+	})
+	coordinate := resolve.GraphCoordinate{TypeName: "Query", FieldName: "employee"}
+
+	authorize := func(claims authentication.Claims, selections ...map[string]string) (*resolve.AuthorizationDeny, *AuthorizationExtension) {
+		ctx := authentication.NewContext(context.Background(), &testAuthentication{claims: claims})
+		ctx = withRequestContext(ctx, &requestContext{operation: &operationContext{
+			fieldArguments: map[string][]map[string]string{"Query.employee": selections},
+		}})
+		resolveCtx := WithAuthorizationExtension(resolve.NewContext(ctx))
+		result, err := authorizer.AuthorizePreFetch(resolveCtx, "0", nil, coordinate)
+		require.NoError(t, err)
+		return result, authorizer.getAuthorizationExtension(resolveCtx)
+	}
+
+	result, _ := authorize(authentication.Claims{"employee_id": float64(1)}, map[string]string{"id": "1"})
+	require.Nil(t, result)
+
+	result, _ = authorize(authentication.Claims{"roles": []any{"hr"}}, map[string]string{"id": "1"})
+	require.Nil(t, result)
+
+	// Every selection of the field must be allowed
+	result, extension := authorize(authentication.Claims{"employee_id": float64(1)}, map[string]string{"id": "1"}, map[string]string{"id": "2"})
+	require.Equal(t, &resolve.AuthorizationDeny{Reason: "denied by policy 'own-employee-or-hr'"}, result)
+	require.Equal(t, []DeniedPolicyError{{Coordinate: coordinate, Policy: "own-employee-or-hr"}}, extension.DeniedPolicies)
+	require.Equal(t, []string{}, extension.ActualScopes)
+
+	// Arguments that weren't collected never match
+	result, _ = authorize(authentication.Claims{"employee_id": "1"})
+	require.NotNil(t, result)
+
+	// Fields without a policy are only checked for scopes
+	result, err := authorizer.AuthorizePreFetch(resolve.NewContext(authentication.NewContext(context.Background(), &testAuthentication{})), "0", nil, resolve.GraphCoordinate{TypeName: "Query", FieldName: "employees"})
+	require.NoError(t, err)
+	require.Nil(t, result)
+}
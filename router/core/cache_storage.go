@@ -0,0 +1,180 @@
+package core
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgraph-io/ristretto"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/wundergraph/cosmo/router/pkg/config"
+)
+
+const (
+	CacheStorageProviderMemory = "memory"
+	CacheStorageProviderRedis  = "redis"
+
+	// cacheAverageEntrySize is used to estimate the number of entries of the in-memory storage
+	cacheAverageEntrySize = 1024
+)
+
+// ResponseCacheStorage stores the entries of the subgraph and the response cache. Implementations must be safe
+// for concurrent use. The storage is shared by all graph servers of the router, so that cached responses survive
+// a config reload.
+type ResponseCacheStorage interface {
+	// Get returns the values of the keys in the same order. The value of a key that isn't cached is nil.
+	Get(ctx context.Context, keys []string) ([][]byte, error)
+	// Set stores the values of the keys until the ttl expires
+	Set(ctx context.Context, keys []string, values [][]byte, ttl time.Duration) error
+	Close() error
+}
+
+// newResponseCacheStorage creates the configured storage. The key prefix of Redis defaults to defaultKeyPrefix.
+func newResponseCacheStorage(cfg *config.CacheStorage, defaultKeyPrefix string) (ResponseCacheStorage, error) {
+	switch cfg.Provider {
+	case CacheStorageProviderMemory, "":
+		return NewMemoryResponseCacheStorage(int64(cfg.MaxSize.Uint64()))
+	case CacheStorageProviderRedis:
+		client, err := newRedisClient(&config.RedisConfiguration{
+			Url:                cfg.Redis.Url,
+			ClusterAddrs:       cfg.Redis.ClusterAddrs,
+			SentinelMasterName: cfg.Redis.SentinelMasterName,
+			SentinelPassword:   cfg.Redis.SentinelPassword,
+			Username:           cfg.Redis.Username,
+			Password:           cfg.Redis.Password,
+			TLS:                cfg.Redis.TLS,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the cache redis client: %w", err)
+		}
+		keyPrefix := cfg.Redis.KeyPrefix
+		if keyPrefix == "" {
+			keyPrefix = defaultKeyPrefix
+		}
+		return NewRedisResponseCacheStorage(client, keyPrefix), nil
+	default:
+		return nil, fmt.Errorf("unknown cache storage provider: %s", cfg.Provider)
+	}
+}
+
+// encodeCacheEntry prefixes the value with its expiration, so that a cached response
+// can announce its remaining lifetime in the Cache-Control header
+func encodeCacheEntry(value []byte, ttl time.Duration) []byte {
+	entry := make([]byte, 8+len(value))
+	binary.BigEndian.PutUint64(entry, uint64(time.Now().Add(ttl).UnixNano()))
+	copy(entry[8:], value)
+	return entry
+}
+
+func decodeCacheEntry(entry []byte) ([]byte, time.Duration, bool) {
+	if len(entry) < 8 {
+		return nil, 0, false
+	}
+	ttl := time.Until(time.Unix(0, int64(binary.BigEndian.Uint64(entry))))
+	if ttl <= 0 {
+		return nil, 0, false
+	}
+	return entry[8:], ttl, true
+}
+
+// MemoryResponseCacheStorage keeps the entries in the router. They aren't shared between router instances.
+type MemoryResponseCacheStorage struct {
+	cache *ristretto.Cache[string, []byte]
+}
+
+// NewMemoryResponseCacheStorage creates an in-memory storage that evicts entries once maxSize bytes are used
+func NewMemoryResponseCacheStorage(maxSize int64) (*MemoryResponseCacheStorage, error) {
+	cache, err := ristretto.NewCache[string, []byte](&ristretto.Config[string, []byte]{
+		NumCounters: (maxSize * 10) / cacheAverageEntrySize,
+		MaxCost:     maxSize,
+		BufferItems: 64,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the in-memory cache storage: %w", err)
+	}
+	return &MemoryResponseCacheStorage{cache: cache}, nil
+}
+
+func (s *MemoryResponseCacheStorage) Get(_ context.Context, keys []string) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		values[i], _ = s.cache.Get(key)
+	}
+	return values, nil
+}
+
+func (s *MemoryResponseCacheStorage) Set(_ context.Context, keys []string, values [][]byte, ttl time.Duration) error {
+	for i, key := range keys {
+		s.cache.SetWithTTL(key, values[i], int64(len(values[i])), ttl)
+	}
+	// Make the entries visible to the next request
+	s.cache.Wait()
+	return nil
+}
+
+func (s *MemoryResponseCacheStorage) Close() error {
+	s.cache.Close()
+	return nil
+}
+
+// RedisResponseCacheStorage shares the entries between all router instances
+type RedisResponseCacheStorage struct {
+	client    redis.UniversalClient
+	keyPrefix string
+}
+
+// NewRedisResponseCacheStorage creates a storage on top of the client. The client is closed with the storage.
+func NewRedisResponseCacheStorage(client redis.UniversalClient, keyPrefix string) *RedisResponseCacheStorage {
+	return &RedisResponseCacheStorage{
+		client:    client,
+		keyPrefix: keyPrefix,
+	}
+}
+
+func (s *RedisResponseCacheStorage) key(key string) string {
+	return s.keyPrefix + ":" + key
+}
+
+func (s *RedisResponseCacheStorage) Get(ctx context.Context, keys []string) ([][]byte, error) {
+	// A pipeline instead of MGET, because the keys of a cluster are spread over multiple slots
+	cmds := make([]*redis.StringCmd, len(keys))
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.Get(ctx, s.key(key))
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	values := make([][]byte, len(keys))
+	for i, cmd := range cmds {
+		value, err := cmd.Bytes()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func (s *RedisResponseCacheStorage) Set(ctx context.Context, keys []string, values [][]byte, ttl time.Duration) error {
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			pipe.Set(ctx, s.key(key), values[i], ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *RedisResponseCacheStorage) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,213 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/wundergraph/cosmo/router/internal/persistedoperation"
+	"github.com/wundergraph/cosmo/router/pkg/config"
+)
+
+// cacheWarmupOperation is an operation that is planned before a graph mux serves traffic
+type cacheWarmupOperation struct {
+	Query         string
+	OperationName string
+	// Sha256Hash is set for persisted operations. Their normalization is cached by the hash.
+	Sha256Hash string
+}
+
+type cacheWarmupResult struct {
+	Planned int
+	// Skipped counts the operations that failed or weren't planned before the timeout
+	Skipped  int
+	Duration time.Duration
+}
+
+// warmupPlanCache plans the operations into the execution plan cache of the planner. It stops when the timeout
+// expires or the context is canceled, the remaining operations are planned on their first request.
+// Operations that can't be planned, e.g. because they aren't valid for the schema, are skipped.
+func warmupPlanCache(ctx context.Context, cfg config.CacheWarmupConfiguration, processor *OperationProcessor, planner *OperationPlanner, operations []cacheWarmupOperation, logger *zap.Logger) cacheWarmupResult {
+	start := time.Now()
+
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	planned := make([]bool, len(operations))
+
+	g := errgroup.Group{}
+	g.SetLimit(workers)
+	for i, operation := range operations {
+		if ctx.Err() != nil {
+			break
+		}
+		i, operation := i, operation
+		g.Go(func() error {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err := planWarmupOperation(processor, planner, operation); err != nil {
+				logger.Debug("Failed to warm up operation",
+					zap.String("operation_name", operation.OperationName),
+					zap.String("sha256_hash", operation.Sha256Hash),
+					zap.Error(err),
+				)
+				return nil
+			}
+			planned[i] = true
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	// The plans are added to the cache asynchronously, wait until they are visible to the first requests
+	if cache, ok := planner.planCache.(interface{ Wait() }); ok {
+		cache.Wait()
+	}
+
+	result := cacheWarmupResult{
+		Duration: time.Since(start),
+	}
+	for _, ok := range planned {
+		if ok {
+			result.Planned++
+		}
+	}
+	result.Skipped = len(operations) - result.Planned
+
+	return result
+}
+
+// planWarmupOperation runs the same steps as the pre handler, but without variables. The plan cache key is the hash
+// of the normalized operation, so the plan is reused by requests with variables unless they toggle @skip or @include.
+func planWarmupOperation(processor *OperationProcessor, planner *OperationPlanner, operation cacheWarmupOperation) error {
+	request := GraphQLRequest{
+		Query:         operation.Query,
+		OperationName: operation.OperationName,
+	}
+	if operation.Sha256Hash != "" {
+		extensions, err := json.Marshal(GraphQLRequestExtensions{
+			PersistedQuery: &GraphQLRequestExtensionsPersistedQuery{
+				Version:    1,
+				Sha256Hash: operation.Sha256Hash,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		request.Extensions = extensions
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	kit, err := processor.NewKit()
+	if err != nil {
+		return err
+	}
+	defer kit.Free()
+
+	if err := kit.UnmarshalOperationFromBody(body); err != nil {
+		return err
+	}
+	if err := kit.Parse(); err != nil {
+		return err
+	}
+	if _, err := kit.NormalizeOperation(); err != nil {
+		return err
+	}
+	if err := kit.NormalizeVariables(); err != nil {
+		return err
+	}
+	// The variables are skipped, the warmup operation has none
+	if _, err := kit.Validate(true); err != nil {
+		return err
+	}
+
+	_, err = planner.plan(kit.parsedOperation, PlanOptions{
+		ClientInfo: &ClientInfo{},
+		warmup:     true,
+	})
+	return err
+}
+
+// warmupPersistedOperations plans the operations of a persisted operations provider that knows all of its
+// operations up front, e.g. the manifest of the CDN or the file system provider.
+func (s *graphServer) warmupPersistedOperations(ctx context.Context, processor *OperationProcessor, planner *OperationPlanner, featureFlagName string) {
+	lister, ok := s.persistedOperationClient.(persistedoperation.OperationLister)
+	if !ok {
+		return
+	}
+	persistedOperations := lister.Operations()
+	if len(persistedOperations) == 0 {
+		return
+	}
+
+	operations := make([]cacheWarmupOperation, 0, len(persistedOperations))
+	// The same operation can be persisted for multiple clients
+	seen := make(map[string]struct{}, len(persistedOperations))
+	for _, operation := range persistedOperations {
+		if _, ok := seen[operation.Sha256Hash]; ok {
+			continue
+		}
+		seen[operation.Sha256Hash] = struct{}{}
+		operations = append(operations, cacheWarmupOperation{
+			Query:      string(operation.Body),
+			Sha256Hash: operation.Sha256Hash,
+		})
+	}
+
+	result := warmupPlanCache(ctx, s.persistedOperationsConfig.Warmup, processor, planner, operations, s.logger)
+
+	s.logger.Info("Persisted operations warmup completed",
+		zap.String("feature_flag", featureFlagName),
+		zap.Int("planned", result.Planned),
+		zap.Int("skipped", result.Skipped),
+		zap.Duration("duration", result.Duration),
+	)
+}
+
+// warmupExecutionPlanCache plans the most used operations of the previous graph server against the new engine config
+func (s *graphServer) warmupExecutionPlanCache(ctx context.Context, processor *OperationProcessor, planner *OperationPlanner, featureFlagName string, usage []operationUsageCount) {
+	operations := make([]cacheWarmupOperation, 0, len(usage))
+	for _, u := range usage {
+		operations = append(operations, u.Operation)
+	}
+
+	cfg := s.engineExecutionConfiguration.ExecutionPlanCacheWarmup
+	result := warmupPlanCache(ctx, config.CacheWarmupConfiguration{
+		Enabled: cfg.Enabled,
+		Workers: cfg.Workers,
+		Timeout: cfg.Timeout,
+	}, processor, planner, operations, s.logger)
+
+	s.logger.Info("Execution plan cache warmup completed",
+		zap.String("feature_flag", featureFlagName),
+		zap.Int("planned", result.Planned),
+		zap.Int("skipped", result.Skipped),
+		zap.Duration("duration", result.Duration),
+	)
+}
+
+// operationUsage returns the n most used operations of every graph mux by feature flag
+func (s *graphServer) operationUsage(n int) map[string][]operationUsageCount {
+	usage := make(map[string][]operationUsageCount, len(s.graphMuxes))
+	for _, gm := range s.graphMuxes {
+		if gm.operationUsage != nil {
+			usage[gm.featureFlagName] = gm.operationUsage.top(n)
+		}
+	}
+	return usage
+}
@@ -0,0 +1,216 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	otrace "go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/wundergraph/cosmo/router/internal/circuitbreaker"
+	"github.com/wundergraph/cosmo/router/pkg/metric"
+	"github.com/wundergraph/cosmo/router/pkg/otel"
+)
+
+const subgraphUnavailableErrorCode = "SUBGRAPH_UNAVAILABLE"
+
+// subgraphCircuitBreakers holds the circuit of every subgraph. A circuit is created with the first
+// request to the subgraph and is shared by all transports of the graph server.
+type subgraphCircuitBreakers struct {
+	options     SubgraphCircuitBreakerOptions
+	overrides   map[string]*SubgraphTrafficOptions
+	metricStore metric.Provider
+	logger      *zap.Logger
+
+	mu sync.RWMutex
+	// breakers contains nil for subgraphs without a circuit breaker
+	breakers map[string]*circuitbreaker.Breaker
+}
+
+// newSubgraphCircuitBreakers returns nil if the circuit breaker isn't enabled for any subgraph
+func newSubgraphCircuitBreakers(options SubgraphCircuitBreakerOptions, overrides map[string]*SubgraphTrafficOptions, metricStore metric.Provider, logger *zap.Logger) *subgraphCircuitBreakers {
+	enabled := options.Enabled
+	for _, override := range overrides {
+		enabled = enabled || override.CircuitBreaker.Enabled
+	}
+	if !enabled {
+		return nil
+	}
+	return &subgraphCircuitBreakers{
+		options:     options,
+		overrides:   overrides,
+		metricStore: metricStore,
+		logger:      logger,
+		breakers:    make(map[string]*circuitbreaker.Breaker),
+	}
+}
+
+func (c *subgraphCircuitBreakers) get(subgraphName string) *circuitbreaker.Breaker {
+	c.mu.RLock()
+	breaker, ok := c.breakers[subgraphName]
+	c.mu.RUnlock()
+	if ok {
+		return breaker
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if breaker, ok := c.breakers[subgraphName]; ok {
+		return breaker
+	}
+
+	options := c.options
+	if override, ok := c.overrides[subgraphName]; ok {
+		options = override.CircuitBreaker
+	}
+	if options.Enabled {
+		breaker = circuitbreaker.New(circuitbreaker.Options{
+			ErrorThresholdPercentage: options.ErrorThresholdPercentage,
+			LatencyThreshold:         options.LatencyThreshold,
+			MinimumRequests:          options.MinimumRequests,
+			Window:                   options.Window,
+			Cooldown:                 options.Cooldown,
+			HalfOpenRequests:         options.HalfOpenRequests,
+			OnStateChange: func(from, to circuitbreaker.State) {
+				c.onStateChange(subgraphName, from, to)
+			},
+		})
+		c.measureState(subgraphName, circuitbreaker.StateClosed, 1)
+	}
+	c.breakers[subgraphName] = breaker
+
+	return breaker
+}
+
+func (c *subgraphCircuitBreakers) onStateChange(subgraphName string, from, to circuitbreaker.State) {
+	c.measureState(subgraphName, from, -1)
+	c.measureState(subgraphName, to, 1)
+
+	fields := []zap.Field{
+		zap.String("subgraph_name", subgraphName),
+		zap.String("from", from.String()),
+		zap.String("to", to.String()),
+	}
+	if to == circuitbreaker.StateOpen {
+		c.logger.Warn("Circuit breaker opened, requests to the subgraph are rejected", fields...)
+	} else {
+		c.logger.Info("Circuit breaker state changed", fields...)
+	}
+}
+
+func (c *subgraphCircuitBreakers) measureState(subgraphName string, state circuitbreaker.State, delta int64) {
+	c.metricStore.MeasureCircuitBreakerState(context.Background(), delta,
+		otel.WgSubgraphName.String(subgraphName),
+		otel.WgSubgraphCircuitBreakerState.String(state.String()),
+	)
+}
+
+// state returns the state attribute of the subgraph circuit, if the subgraph has one
+func (c *subgraphCircuitBreakers) state(subgraphName string) (attribute.KeyValue, bool) {
+	breaker := c.get(subgraphName)
+	if breaker == nil {
+		return attribute.KeyValue{}, false
+	}
+	return otel.WgSubgraphCircuitBreakerState.String(breaker.State().String()), true
+}
+
+// shutdown removes the circuits of the graph server from the state metric
+func (c *subgraphCircuitBreakers) shutdown() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for subgraphName, breaker := range c.breakers {
+		if breaker != nil {
+			c.measureState(subgraphName, breaker.State(), -1)
+		}
+	}
+}
+
+// circuitBreakerTransport records the outcome of every subgraph request on the circuit of the subgraph.
+// It sits below the retry transport, so retries stop as soon as the circuit opens.
+type circuitBreakerTransport struct {
+	roundTripper http.RoundTripper
+	breakers     *subgraphCircuitBreakers
+	metricStore  metric.Provider
+}
+
+func (t *circuitBreakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	subgraph := getRequestContext(req.Context()).ActiveSubgraph(req)
+	if subgraph == nil {
+		return t.roundTripper.RoundTrip(req)
+	}
+	breaker := t.breakers.get(subgraph.Name)
+	if breaker == nil {
+		return t.roundTripper.RoundTrip(req)
+	}
+
+	done, err := breaker.Allow()
+	if err != nil {
+		attributes := []attribute.KeyValue{
+			otel.WgSubgraphName.String(subgraph.Name),
+			otel.WgSubgraphID.String(subgraph.Id),
+		}
+		if baseAttributes := baseAttributesFromContext(req.Context()); baseAttributes != nil {
+			attributes = append(attributes, baseAttributes...)
+		}
+		t.metricStore.MeasureCircuitBreakerShortCircuit(req.Context(), attributes...)
+		// The request isn't sent, so there is no span of the subgraph request
+		otrace.SpanFromContext(req.Context()).SetAttributes(otel.WgSubgraphCircuitBreakerState.String(circuitbreaker.StateOpen.String()))
+		return nil, err
+	}
+
+	resp, err := t.roundTripper.RoundTrip(req)
+	done(circuitBreakerOutcome(resp, err))
+	return resp, err
+}
+
+// circuitBreakerOutcome returns a failure if the request failed because of the subgraph.
+// Requests that were canceled by the client are ignored, the subgraph never answered them.
+func circuitBreakerOutcome(resp *http.Response, err error) circuitbreaker.Outcome {
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return circuitbreaker.OutcomeIgnored
+		}
+		return circuitbreaker.OutcomeFailure
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return circuitbreaker.OutcomeFailure
+	}
+	return circuitbreaker.OutcomeSuccess
+}
+
+// subgraphUnavailableResponse is returned instead of the subgraph response while the circuit of the subgraph is open
+func subgraphUnavailableResponse(req *http.Request, subgraph *Subgraph) *http.Response {
+	message := "Subgraph is unavailable."
+	if subgraph != nil {
+		message = fmt.Sprintf("Subgraph '%s' is unavailable.", subgraph.Name)
+	}
+	// Marshaling a static struct can't fail
+	body, _ := json.Marshal(struct {
+		Errors []graphqlError `json:"errors"`
+	}{
+		Errors: []graphqlError{{
+			Message:    message,
+			Extensions: &Extensions{Code: subgraphUnavailableErrorCode},
+		}},
+	})
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable)),
+		StatusCode:    http.StatusServiceUnavailable,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json; charset=utf-8"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,41 @@
+package core
+
+import (
+	"strconv"
+	"strings"
+)
+
+// claimValue looks up a claim by its dot separated path and formats scalar values as string
+func claimValue(claims map[string]any, path string) string {
+	return claimString(lookupClaim(claims, path))
+}
+
+// lookupClaim returns the claim at the dot separated path or nil if it doesn't exist
+func lookupClaim(claims map[string]any, path string) any {
+	var value any = claims
+	for _, segment := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value, ok = object[segment]
+		if !ok {
+			return nil
+		}
+	}
+	return value
+}
+
+// claimString formats scalar claims as string. Objects and arrays are formatted as empty string.
+func claimString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return ""
+	}
+}
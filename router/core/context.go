@@ -372,6 +372,13 @@ type operationContext struct {
 	persistedOperationCacheHit bool
 	normalizationCacheHit      bool
 
+	// cost is the estimated static cost of the operation, 0 if the cost analysis is disabled
+	cost int
+	// rootFields are the coordinates of the root fields, only collected if an operation rule matches on them
+	rootFields []string
+	// fieldArguments are the arguments of the fields with a field policy, keyed by coordinate
+	fieldArguments map[string][]map[string]string
+
 	typeFieldUsageInfo []*graphqlmetrics.TypeFieldUsageInfo
 	argumentUsageInfo  []*graphqlmetrics.ArgumentUsageInfo
 	inputUsageInfo     []*graphqlmetrics.InputUsageInfo
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"github.com/wundergraph/cosmo/router/internal/persistedoperation"
+	"io"
 	"net"
 	"net/http"
 
@@ -44,6 +45,9 @@ type (
 		Authorization json.RawMessage `json:"authorization,omitempty"`
 		Trace         json.RawMessage `json:"trace,omitempty"`
 		StatusCode    int             `json:"statusCode,omitempty"`
+		Code          string          `json:"code,omitempty"`
+		// Rule is the name of the operation rule that blocked the operation
+		Rule string `json:"rule,omitempty"`
 	}
 )
 
@@ -122,33 +126,53 @@ func propagateSubgraphErrors(ctx *resolve.Context, logger *zap.Logger) {
 // It accepts a graphqlerrors.RequestErrors object and writes it to the response based on the GraphQL spec.
 func writeRequestErrors(r *http.Request, w http.ResponseWriter, statusCode int, requestErrors graphqlerrors.RequestErrors, requestLogger *zap.Logger) {
 	if requestErrors != nil {
+		writeErrorResponse(r, w, statusCode, requestLogger, func(w io.Writer) error {
+			_, err := requestErrors.WriteResponse(w)
+			return err
+		})
+	}
+}
 
-		if r.URL.Query().Has("wg_sse") {
+// writeGraphQLErrors writes errors with extensions, which can't be expressed with graphqlerrors.RequestErrors
+func writeGraphQLErrors(r *http.Request, w http.ResponseWriter, statusCode int, errs []graphqlError, requestLogger *zap.Logger) {
+	writeErrorResponse(r, w, statusCode, requestLogger, func(w io.Writer) error {
+		data, err := json.Marshal(GraphQLErrorResponse{
+			Errors: errs,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	})
+}
 
-			setSubscriptionHeaders(w)
+func writeErrorResponse(r *http.Request, w http.ResponseWriter, statusCode int, requestLogger *zap.Logger, write func(w io.Writer) error) {
+	if r.URL.Query().Has("wg_sse") {
 
-			if statusCode != 0 {
-				w.WriteHeader(statusCode)
-			}
-			_, err := w.Write([]byte("event: next\ndata: "))
-			if err != nil {
-				if requestLogger != nil {
-					requestLogger.Error("error writing response", zap.Error(err))
-				}
-				return
-			}
-		}
+		setSubscriptionHeaders(w)
 
-		// Set header before writing status code
-		w.Header().Set("Content-Type", "application/json")
 		if statusCode != 0 {
 			w.WriteHeader(statusCode)
 		}
-
-		if _, err := requestErrors.WriteResponse(w); err != nil {
+		_, err := w.Write([]byte("event: next\ndata: "))
+		if err != nil {
 			if requestLogger != nil {
 				requestLogger.Error("error writing response", zap.Error(err))
 			}
+			return
+		}
+	}
+
+	// Set header before writing status code
+	w.Header().Set("Content-Type", "application/json")
+	if statusCode != 0 {
+		w.WriteHeader(statusCode)
+	}
+
+	if err := write(w); err != nil {
+		if requestLogger != nil {
+			requestLogger.Error("error writing response", zap.Error(err))
 		}
 	}
 }
@@ -159,7 +183,16 @@ func writeOperationError(r *http.Request, w http.ResponseWriter, requestLogger *
 	var reportErr ReportError
 	var httpErr HttpError
 	var poNotFoundErr *persistedoperation.PersistentOperationNotFoundError
+	var blockedErr *OperationBlockedError
+	var pqNotFoundErr *PersistedQueryNotFoundError
 	switch {
+	case errors.As(err, &blockedErr):
+		requestLogger.Debug(blockedErr.Error())
+		writeGraphQLErrors(r, w, blockedErr.StatusCode(), []graphqlError{blockedErr.graphqlError()}, requestLogger)
+	case errors.As(err, &pqNotFoundErr):
+		requestLogger.Debug("automatic persisted query not found",
+			zap.String("sha256_hash", pqNotFoundErr.Sha256Hash))
+		writeGraphQLErrors(r, w, pqNotFoundErr.StatusCode(), []graphqlError{pqNotFoundErr.graphqlError()}, requestLogger)
 	case errors.As(err, &httpErr):
 		requestLogger.Debug(httpErr.Error())
 		writeRequestErrors(r, w, httpErr.StatusCode(), graphqlerrors.RequestErrorsFromError(err), requestLogger)
@@ -2,7 +2,6 @@ package core
 
 import (
 	"context"
-	"crypto/tls"
 	"errors"
 	"fmt"
 	"net/http"
@@ -10,7 +9,6 @@ import (
 
 	"github.com/nats-io/nats.go"
 	"github.com/twmb/franz-go/pkg/kgo"
-	"github.com/twmb/franz-go/pkg/sasl/plain"
 	"go.uber.org/zap"
 
 	"github.com/wundergraph/graphql-go-tools/v2/pkg/ast"
@@ -33,6 +31,8 @@ type ExecutorConfigurationBuilder struct {
 	logger         *zap.Logger
 
 	transportOptions *TransportOptions
+	// subgraphTransports override the transport and its options for single subgraphs, the key is the subgraph name
+	subgraphTransports map[string]*SubgraphTransport
 }
 
 type Executor struct {
@@ -182,13 +182,21 @@ func buildNatsOptions(eventSource config.NatsEventSource, logger *zap.Logger) ([
 
 			if errors.Is(err, nats.ErrSlowConsumer) {
 				logger.Warn(
-					"Nats slow consumer detected. Events are being dropped. Please consider increasing the buffer size or reducing the number of messages being sent.",
+					"Nats slow consumer detected. Events are being dropped. Please consider increasing the pending_buffer_size of the provider or reducing the number of messages being sent.",
 					zap.Error(err),
 				)
 			} else {
 				logger.Error("nats error", zap.Error(err))
 			}
 		}),
+		nats.DisconnectErrHandler(func(conn *nats.Conn, err error) {
+			if err != nil {
+				logger.Warn("Nats connection lost, reconnecting", zap.String("provider_id", eventSource.ID), zap.Error(err))
+			}
+		}),
+		nats.ReconnectHandler(func(conn *nats.Conn) {
+			logger.Info("Nats connection reestablished", zap.String("provider_id", eventSource.ID), zap.String("url", conn.ConnectedUrlRedacted()))
+		}),
 	}
 
 	if eventSource.Authentication != nil {
@@ -196,7 +204,37 @@ func buildNatsOptions(eventSource config.NatsEventSource, logger *zap.Logger) ([
 			opts = append(opts, nats.Token(*eventSource.Authentication.Token))
 		} else if eventSource.Authentication.UserInfo.Username != nil && eventSource.Authentication.UserInfo.Password != nil {
 			opts = append(opts, nats.UserInfo(*eventSource.Authentication.UserInfo.Username, *eventSource.Authentication.UserInfo.Password))
+		} else if eventSource.Authentication.CredentialsFile != "" {
+			// The file contains the user JWT and the NKey seed that signs the server nonce
+			opts = append(opts, nats.UserCredentials(eventSource.Authentication.CredentialsFile))
+		} else if eventSource.Authentication.NKeySeedFile != "" {
+			nkeyOpt, err := nats.NkeyOptionFromSeed(eventSource.Authentication.NKeySeedFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load the nats NKey seed file: %w", err)
+			}
+			opts = append(opts, nkeyOpt)
+		}
+	}
+
+	if eventSource.TLS != nil && eventSource.TLS.Enabled {
+		tlsConfig, err := newClientTLSConfig("nats", eventSource.TLS.CaFile, eventSource.TLS.CertFile, eventSource.TLS.KeyFile, eventSource.TLS.InsecureSkipVerify)
+		if err != nil {
+			return nil, err
 		}
+		opts = append(opts, nats.Secure(tlsConfig))
+	}
+
+	if eventSource.MaxReconnects != nil {
+		opts = append(opts, nats.MaxReconnects(*eventSource.MaxReconnects))
+	}
+	if eventSource.ReconnectWait > 0 {
+		opts = append(opts, nats.ReconnectWait(eventSource.ReconnectWait))
+	}
+	if eventSource.PingInterval > 0 {
+		opts = append(opts, nats.PingInterval(eventSource.PingInterval))
+	}
+	if eventSource.MaxPingsOutstanding > 0 {
+		opts = append(opts, nats.MaxPingsOutstanding(eventSource.MaxPingsOutstanding))
 	}
 
 	return opts, nil
@@ -214,18 +252,20 @@ func buildKafkaOptions(eventSource config.KafkaEventSource) ([]kgo.Opt, error) {
 		kgo.ConnIdleTimeout(60 * time.Second),
 	}
 
-	if eventSource.TLS != nil && eventSource.TLS.Enabled {
-		opts = append(opts,
-			// Configure TLS. Uses SystemCertPool for RootCAs by default.
-			kgo.DialTLSConfig(new(tls.Config)),
-		)
+	tlsConfig, err := newKafkaTLSConfig(eventSource.TLS)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		opts = append(opts, kgo.DialTLSConfig(tlsConfig))
 	}
 
-	if eventSource.Authentication != nil && eventSource.Authentication.SASLPlain.Username != nil && eventSource.Authentication.SASLPlain.Password != nil {
-		opts = append(opts, kgo.SASL(plain.Auth{
-			User: *eventSource.Authentication.SASLPlain.Username,
-			Pass: *eventSource.Authentication.SASLPlain.Password,
-		}.AsMechanism()))
+	mechanism, err := newKafkaSASLMechanism(eventSource.Authentication)
+	if err != nil {
+		return nil, err
+	}
+	if mechanism != nil {
+		opts = append(opts, kgo.SASL(mechanism))
 	}
 
 	return opts, nil
@@ -240,6 +280,7 @@ func (b *ExecutorConfigurationBuilder) buildPlannerConfiguration(ctx context.Con
 		ctx,
 		NewTransport(b.transportOptions),
 		b.transport,
+		b.subgraphTransports,
 		b.logger,
 		routerEngineCfg.Execution.EnableSingleFlight,
 		pubSubProviders.nats,
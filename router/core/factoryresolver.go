@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/wundergraph/graphql-go-tools/v2/pkg/engine/argument_templates"
@@ -34,7 +35,7 @@ type Loader struct {
 }
 
 type FactoryResolver interface {
-	ResolveGraphqlFactory() (plan.PlannerFactory[graphql_datasource.Configuration], error)
+	ResolveGraphqlFactory(subgraphName string) (plan.PlannerFactory[graphql_datasource.Configuration], error)
 	ResolveStaticFactory() (plan.PlannerFactory[staticdatasource.Configuration], error)
 	ResolvePubsubFactory() (plan.PlannerFactory[pubsub_datasource.Configuration], error)
 }
@@ -45,6 +46,12 @@ type ApiTransportFactory interface {
 	DefaultHTTPProxyURL() *url.URL
 }
 
+// SubgraphTransport is the transport of a subgraph that doesn't share the transport options of all subgraphs
+type SubgraphTransport struct {
+	Transport http.RoundTripper
+	Options   *TransportOptions
+}
+
 type DefaultFactoryResolver struct {
 	baseTransport    http.RoundTripper
 	transportFactory ApiTransportFactory
@@ -56,13 +63,21 @@ type DefaultFactoryResolver struct {
 	engineCtx       context.Context
 	httpClient      *http.Client
 	streamingClient *http.Client
+	// subgraphClients are the clients of the subgraphs with their own transport, keyed by subgraph name
+	subgraphClients map[string]*subgraphClients
 	factoryLogger   abstractlogger.Logger
 }
 
+type subgraphClients struct {
+	httpClient      *http.Client
+	streamingClient *http.Client
+}
+
 func NewDefaultFactoryResolver(
 	ctx context.Context,
 	transportFactory ApiTransportFactory,
 	baseTransport http.RoundTripper,
+	subgraphTransports map[string]*SubgraphTransport,
 	log *zap.Logger,
 	enableSingleFlight bool,
 	natsPubSubBySourceID map[string]pubsub_datasource.NatsPubSub,
@@ -77,6 +92,20 @@ func NewDefaultFactoryResolver(
 		Transport: transportFactory.RoundTripper(enableSingleFlight, baseTransport),
 	}
 
+	clients := make(map[string]*subgraphClients, len(subgraphTransports))
+	for name, subgraphTransport := range subgraphTransports {
+		subgraphTransportFactory := NewTransport(subgraphTransport.Options)
+		clients[name] = &subgraphClients{
+			httpClient: &http.Client{
+				Timeout:   subgraphTransportFactory.DefaultTransportTimeout(),
+				Transport: subgraphTransportFactory.RoundTripper(enableSingleFlight, subgraphTransport.Transport),
+			},
+			streamingClient: &http.Client{
+				Transport: subgraphTransportFactory.RoundTripper(enableSingleFlight, subgraphTransport.Transport),
+			},
+		}
+	}
+
 	var factoryLogger abstractlogger.Logger
 	if log != nil {
 		factoryLogger = abstractlogger.NewZapLogger(log, abstractlogger.DebugLevel)
@@ -92,18 +121,24 @@ func NewDefaultFactoryResolver(
 		engineCtx:        ctx,
 		httpClient:       defaultHttpClient,
 		streamingClient:  streamingClient,
+		subgraphClients:  clients,
 	}
 }
 
-func (d *DefaultFactoryResolver) ResolveGraphqlFactory() (plan.PlannerFactory[graphql_datasource.Configuration], error) {
+func (d *DefaultFactoryResolver) ResolveGraphqlFactory(subgraphName string) (plan.PlannerFactory[graphql_datasource.Configuration], error) {
+	httpClient, streamingClient := d.httpClient, d.streamingClient
+	if clients, ok := d.subgraphClients[subgraphName]; ok {
+		httpClient, streamingClient = clients.httpClient, clients.streamingClient
+	}
+
 	subscriptionClient := graphql_datasource.NewGraphQLSubscriptionClient(
-		d.httpClient,
-		d.streamingClient,
+		httpClient,
+		streamingClient,
 		d.engineCtx,
 		graphql_datasource.WithLogger(d.factoryLogger),
 	)
 
-	factory, err := graphql_datasource.NewFactory(d.engineCtx, d.httpClient, subscriptionClient)
+	factory, err := graphql_datasource.NewFactory(d.engineCtx, httpClient, subscriptionClient)
 	return factory, err
 }
 
@@ -136,6 +171,8 @@ type RouterEngineConfiguration struct {
 	Headers                  *config.HeaderRules
 	Events                   config.EventsConfiguration
 	SubgraphErrorPropagation config.SubgraphErrorPropagationConfiguration
+	// FieldPolicyCoordinates are the fields that are authorized by a field policy of the router, e.g. Query.employee
+	FieldPolicyCoordinates []string
 }
 
 func mapProtoFilterToPlanFilter(input *nodev1.SubscriptionFilterCondition, output *plan.SubscriptionFilterCondition) *plan.SubscriptionFilterCondition {
@@ -215,12 +252,28 @@ func (l *Loader) Load(engineConfig *nodev1.EngineConfiguration, subgraphs []*nod
 			TypeName:                    configuration.TypeName,
 			FieldName:                   configuration.FieldName,
 			Arguments:                   args,
-			HasAuthorizationRule:        l.fieldHasAuthorizationRule(configuration),
+			HasAuthorizationRule:        l.fieldHasAuthorizationRule(configuration) || slices.Contains(routerEngineConfig.FieldPolicyCoordinates, configuration.TypeName+"."+configuration.FieldName),
 			SubscriptionFilterCondition: mapProtoFilterToPlanFilter(configuration.SubscriptionFilterCondition, &plan.SubscriptionFilterCondition{}),
 		}
 		outConfig.Fields = append(outConfig.Fields, fieldConfig)
 	}
 
+	// The engine only authorizes fields with a field configuration. Fields without arguments and
+	// authorization directives have none, so one is added for every field policy.
+	for _, coordinate := range routerEngineConfig.FieldPolicyCoordinates {
+		typeName, fieldName, _ := strings.Cut(coordinate, ".")
+		if slices.ContainsFunc(outConfig.Fields, func(field plan.FieldConfiguration) bool {
+			return field.TypeName == typeName && field.FieldName == fieldName
+		}) {
+			continue
+		}
+		outConfig.Fields = append(outConfig.Fields, plan.FieldConfiguration{
+			TypeName:             typeName,
+			FieldName:            fieldName,
+			HasAuthorizationRule: true,
+		})
+	}
+
 	for _, configuration := range engineConfig.TypeConfigurations {
 		outConfig.Types = append(outConfig.Types, plan.TypeConfiguration{
 			TypeName: configuration.TypeName,
@@ -320,7 +373,7 @@ func (l *Loader) Load(engineConfig *nodev1.EngineConfiguration, subgraphs []*nod
 				return nil, fmt.Errorf("error parsing header rules for data source %s: %w", in.Id, err)
 			}
 
-			factory, err := l.resolver.ResolveGraphqlFactory()
+			factory, err := l.resolver.ResolveGraphqlFactory(l.subgraphName(subgraphs, in.Id))
 			if err != nil {
 				return nil, err
 			}
@@ -0,0 +1,134 @@
+package core
+
+import (
+	"slices"
+
+	"github.com/buger/jsonparser"
+	"github.com/wundergraph/graphql-go-tools/v2/pkg/ast"
+
+	"github.com/wundergraph/cosmo/router/pkg/config"
+)
+
+// fieldPolicyCoordinates returns the distinct coordinates of the field policies
+func fieldPolicyCoordinates(policies []config.FieldPolicy) []string {
+	var coordinates []string
+	for _, policy := range policies {
+		if !slices.Contains(coordinates, policy.Coordinate) {
+			coordinates = append(coordinates, policy.Coordinate)
+		}
+	}
+	return coordinates
+}
+
+// fieldPolicyAllows returns true if any condition of the policy matches. A condition matches if all of its claims match.
+func fieldPolicyAllows(policy config.FieldPolicy, claims map[string]any, arguments map[string]string) bool {
+	return slices.ContainsFunc(policy.Allow, func(condition config.FieldPolicyCondition) bool {
+		for _, claim := range condition.Claims {
+			if !fieldPolicyClaimMatches(claims, claim, arguments) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func fieldPolicyClaimMatches(claims map[string]any, claim config.FieldPolicyClaim, arguments map[string]string) bool {
+	values := claim.Values
+	if claim.Argument != "" {
+		argument, ok := arguments[claim.Argument]
+		if !ok {
+			return false
+		}
+		values = []string{argument}
+	}
+	return claimMatches(claims, config.OperationRuleClaim{Path: claim.Path, Values: values})
+}
+
+// CollectFieldArguments stores the arguments of every selection of the given fields on the ParsedOperation.
+// The arguments are needed to evaluate the field policies, they are not available to the authorizer of the engine.
+func (o *OperationKit) CollectFieldArguments(definition *ast.Document, coordinates []string) {
+	operationRef := o.normalizedOperationDefinitionRef()
+	if operationRef == -1 {
+		return
+	}
+	arguments := make(map[string][]map[string]string)
+	o.collectFieldArguments(definition, coordinates, arguments, o.rootTypeName(definition, operationRef), o.kit.doc.OperationDefinitions[operationRef].SelectionSet)
+	o.parsedOperation.FieldArguments = arguments
+}
+
+func (o *OperationKit) collectFieldArguments(definition *ast.Document, coordinates []string, arguments map[string][]map[string]string, typeName string, selectionSet int) {
+	if selectionSet == -1 {
+		return
+	}
+	for _, selectionRef := range o.kit.doc.SelectionSets[selectionSet].SelectionRefs {
+		selection := o.kit.doc.Selections[selectionRef]
+		switch selection.Kind {
+		case ast.SelectionKindField:
+			fieldName := o.kit.doc.FieldNameString(selection.Ref)
+			coordinate := typeName + "." + fieldName
+			if slices.Contains(coordinates, coordinate) {
+				arguments[coordinate] = append(arguments[coordinate], o.fieldArguments(selection.Ref))
+			}
+			if !o.kit.doc.Fields[selection.Ref].HasSelections {
+				continue
+			}
+			if fieldTypeName, ok := fieldTypeName(definition, typeName, fieldName); ok {
+				o.collectFieldArguments(definition, coordinates, arguments, fieldTypeName, o.kit.doc.Fields[selection.Ref].SelectionSet)
+			}
+		case ast.SelectionKindInlineFragment:
+			fragmentTypeName := typeName
+			if o.kit.doc.InlineFragmentHasTypeCondition(selection.Ref) {
+				fragmentTypeName = o.kit.doc.InlineFragmentTypeConditionNameString(selection.Ref)
+			}
+			o.collectFieldArguments(definition, coordinates, arguments, fragmentTypeName, o.kit.doc.InlineFragments[selection.Ref].SelectionSet)
+		}
+	}
+}
+
+// fieldArguments returns the scalar arguments of the field formatted as string. The normalization extracts
+// inline arguments into variables, so the values are usually read from the variables.
+func (o *OperationKit) fieldArguments(fieldRef int) map[string]string {
+	argumentRefs := o.kit.doc.FieldArguments(fieldRef)
+	arguments := make(map[string]string, len(argumentRefs))
+	for _, argumentRef := range argumentRefs {
+		var (
+			data     []byte
+			dataType jsonparser.ValueType
+			err      error
+		)
+		value := o.kit.doc.ArgumentValue(argumentRef)
+		if value.Kind == ast.ValueKindVariable {
+			data, dataType, _, err = jsonparser.Get(o.parsedOperation.Request.Variables, o.kit.doc.VariableValueNameString(value.Ref))
+		} else {
+			data, err = o.kit.doc.ValueToJSON(value)
+			if err == nil {
+				data, dataType, _, err = jsonparser.Get(data)
+			}
+		}
+		if err != nil {
+			continue
+		}
+		switch dataType {
+		case jsonparser.String:
+			if formatted, err := jsonparser.ParseString(data); err == nil {
+				arguments[o.kit.doc.ArgumentNameString(argumentRef)] = formatted
+			}
+		case jsonparser.Number, jsonparser.Boolean:
+			arguments[o.kit.doc.ArgumentNameString(argumentRef)] = string(data)
+		}
+	}
+	return arguments
+}
+
+// fieldTypeName returns the name of the unwrapped type of the field in the schema
+func fieldTypeName(definition *ast.Document, typeName, fieldName string) (string, bool) {
+	node, ok := definition.Index.FirstNodeByNameStr(typeName)
+	if !ok {
+		return "", false
+	}
+	fieldDefinitionRef, ok := definition.NodeFieldDefinitionByName(node, []byte(fieldName))
+	if !ok {
+		return "", false
+	}
+	return definition.ResolveTypeNameString(definition.FieldDefinitionType(fieldDefinitionRef)), true
+}
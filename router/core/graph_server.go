@@ -14,6 +14,7 @@ import (
 	"github.com/dgraph-io/ristretto"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-redis/redis_rate/v10"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/klauspost/compress/gzhttp"
 	"github.com/klauspost/compress/gzip"
@@ -76,6 +77,10 @@ type (
 		playgroundHandler       func(http.Handler) http.Handler
 		publicKey               *ecdsa.PublicKey
 		executionTransport      *http.Transport
+		subgraphTransports      map[string]*http.Transport
+		circuitBreakers         *subgraphCircuitBreakers
+		subgraphResponseCache   *subgraphResponseCache
+		apq                     *automaticPersistedQueries
 		baseOtelAttributes      []attribute.KeyValue
 		runtimeMetrics          *rmetric.RuntimeMetrics
 		metricStore             rmetric.Store
@@ -85,11 +90,15 @@ type (
 		// does not include websocket (hijacked) connections
 		inFlightRequests *atomic.Uint64
 		graphMuxes       []*graphMux
+		// previousOperationUsage holds the most used operations of the previous graph server by feature flag
+		previousOperationUsage map[string][]operationUsageCount
+		// executionConfigFallback is set if the graph server serves the execution config of the fallback storage
+		executionConfigFallback bool
 	}
 )
 
 // newGraphServer creates a new server instance.
-func newGraphServer(ctx context.Context, r *Router, routerConfig *nodev1.RouterConfig, proxy ProxyFunc) (*graphServer, error) {
+func newGraphServer(ctx context.Context, r *Router, routerConfig *nodev1.RouterConfig, proxy ProxyFunc, previousOperationUsage map[string][]operationUsageCount) (*graphServer, error) {
 	ctx, cancel := context.WithCancel(ctx)
 	s := &graphServer{
 		context:                 ctx,
@@ -98,16 +107,23 @@ func newGraphServer(ctx context.Context, r *Router, routerConfig *nodev1.RouterC
 		websocketStats:          r.WebsocketStats,
 		metricStore:             rmetric.NewNoopMetrics(),
 		executionTransport:      newHTTPTransport(r.subgraphTransportOptions, proxy),
+		subgraphTransports:      make(map[string]*http.Transport, len(r.subgraphTrafficOptions)),
 		playgroundHandler:       r.playgroundHandler,
 		baseRouterConfigVersion: routerConfig.GetVersion(),
 		inFlightRequests:        &atomic.Uint64{},
 		graphMuxes:              make([]*graphMux, 0, 1),
+		previousOperationUsage:  previousOperationUsage,
+		executionConfigFallback: r.executionConfigFallback,
 		pubSubProviders: &EnginePubSubProviders{
 			nats:  map[string]pubsub_datasource.NatsPubSub{},
 			kafka: map[string]pubsub_datasource.KafkaPubSub{},
 		},
 	}
 
+	for name, opts := range r.subgraphTrafficOptions {
+		s.subgraphTransports[name] = newHTTPTransport(opts.Transport, proxy)
+	}
+
 	baseOtelAttributes := []attribute.KeyValue{
 		otel.WgRouterVersion.String(Version),
 		otel.WgRouterClusterName.String(r.clusterName),
@@ -160,6 +176,20 @@ func newGraphServer(ctx context.Context, r *Router, routerConfig *nodev1.RouterC
 		s.metricStore = m
 	}
 
+	if s.executionConfigFallback {
+		s.metricStore.MeasureExecutionConfigFallback(ctx, 1, otel.WgRouterConfigVersion.String(s.baseRouterConfigVersion))
+	}
+
+	s.circuitBreakers = newSubgraphCircuitBreakers(r.circuitBreakerOptions, r.subgraphTrafficOptions, s.metricStore, s.logger)
+
+	if r.subgraphCacheConfig != nil && r.subgraphCacheConfig.Enabled && r.subgraphCacheStorage != nil {
+		s.subgraphResponseCache = newSubgraphResponseCache(r.subgraphCacheStorage, r.subgraphCacheConfig, s.logger)
+	}
+
+	if r.persistedOperationsConfig.AutomaticPersistedQueries.Enabled && r.apqStorage != nil {
+		s.apq = newAutomaticPersistedQueries(r.apqStorage, r.persistedOperationsConfig.AutomaticPersistedQueries.TTL, s.logger)
+	}
+
 	if s.registrationInfo != nil {
 		publicKey, err := jwt.ParseECPublicKeyFromPEM([]byte(s.registrationInfo.GetGraphPublicKey()))
 		if err != nil {
@@ -295,10 +325,19 @@ func (s *graphServer) buildMultiGraphHandler(ctx context.Context, baseMux *chi.M
 
 type graphMux struct {
 	mux                *chi.Mux
+	featureFlagName    string
+	operationUsage     *operationUsage
 	planCache          ExecutionPlanCache[uint64, *planWithMetaData]
 	normalizationCache *ristretto.Cache[uint64, NormalizationCacheEntry]
 	validationCache    *ristretto.Cache[uint64, bool]
 	queryDepthCache    *ristretto.Cache[uint64, int]
+	queryCostCache     *ristretto.Cache[uint64, int]
+
+	fieldCountCache     *ristretto.Cache[uint64, int]
+	aliasCountCache     *ristretto.Cache[uint64, int]
+	rootFieldCountCache *ristretto.Cache[uint64, int]
+	directiveCountCache *ristretto.Cache[uint64, int]
+	tokenCountCache     *ristretto.Cache[uint64, int]
 }
 
 func (s *graphMux) Shutdown(_ context.Context) {
@@ -312,6 +351,24 @@ func (s *graphMux) Shutdown(_ context.Context) {
 	if s.queryDepthCache != nil {
 		s.queryDepthCache.Close()
 	}
+	if s.queryCostCache != nil {
+		s.queryCostCache.Close()
+	}
+	if s.fieldCountCache != nil {
+		s.fieldCountCache.Close()
+	}
+	if s.aliasCountCache != nil {
+		s.aliasCountCache.Close()
+	}
+	if s.rootFieldCountCache != nil {
+		s.rootFieldCountCache.Close()
+	}
+	if s.directiveCountCache != nil {
+		s.directiveCountCache.Close()
+	}
+	if s.tokenCountCache != nil {
+		s.tokenCountCache.Close()
+	}
 }
 
 // buildGraphMux creates a new graph mux with the given feature flags and engine configuration.
@@ -323,7 +380,9 @@ func (s *graphServer) buildGraphMux(ctx context.Context,
 	engineConfig *nodev1.EngineConfiguration,
 	configSubgraphs []*nodev1.Subgraph) (*graphMux, error) {
 
-	gm := &graphMux{}
+	gm := &graphMux{
+		featureFlagName: featureFlagName,
+	}
 
 	httpRouter := chi.NewRouter()
 
@@ -406,6 +465,35 @@ func (s *graphServer) buildGraphMux(ctx context.Context,
 		}
 	}
 
+	if s.securityConfiguration.CostAnalysis.Enabled && s.securityConfiguration.CostAnalysis.CacheSize > 0 {
+		queryCostCacheConfig := &ristretto.Config[uint64, int]{
+			MaxCost:     s.securityConfiguration.CostAnalysis.CacheSize,
+			NumCounters: s.securityConfiguration.CostAnalysis.CacheSize * 10,
+			BufferItems: 64,
+		}
+		gm.queryCostCache, err = ristretto.NewCache[uint64, int](queryCostCacheConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create query cost cache: %w", err)
+		}
+	}
+
+	limits := s.securityConfiguration.ComplexityLimits
+	if gm.fieldCountCache, err = newComplexityLimitCache(limits.FieldCount); err != nil {
+		return nil, fmt.Errorf("failed to create field count cache: %w", err)
+	}
+	if gm.aliasCountCache, err = newComplexityLimitCache(limits.AliasCount); err != nil {
+		return nil, fmt.Errorf("failed to create alias count cache: %w", err)
+	}
+	if gm.rootFieldCountCache, err = newComplexityLimitCache(limits.RootFieldCount); err != nil {
+		return nil, fmt.Errorf("failed to create root field count cache: %w", err)
+	}
+	if gm.directiveCountCache, err = newComplexityLimitCache(limits.DirectiveCount); err != nil {
+		return nil, fmt.Errorf("failed to create directive count cache: %w", err)
+	}
+	if gm.tokenCountCache, err = newComplexityLimitCache(limits.TokenCount); err != nil {
+		return nil, fmt.Errorf("failed to create token count cache: %w", err)
+	}
+
 	metrics := NewRouterMetrics(&routerMetricsConfig{
 		metrics:             s.metricStore,
 		gqlMetricsExporter:  s.gqlMetricsExporter,
@@ -535,6 +623,9 @@ func (s *graphServer) buildGraphMux(ctx context.Context,
 		Events:                   s.eventsConfig,
 		SubgraphErrorPropagation: s.subgraphErrorPropagation,
 	}
+	if s.authorization != nil {
+		routerEngineConfig.FieldPolicyCoordinates = fieldPolicyCoordinates(s.authorization.FieldPolicies)
+	}
 
 	err = s.buildPubSubConfiguration(ctx, engineConfig, routerEngineConfig)
 	if err != nil {
@@ -552,21 +643,33 @@ func (s *graphServer) buildGraphMux(ctx context.Context,
 			PreHandlers:    s.preOriginHandlers,
 			PostHandlers:   s.postOriginHandlers,
 			MetricStore:    s.metricStore,
-			RetryOptions: retrytransport.RetryOptions{
+			RetryOptions: subgraphRetryOptions(SubgraphRetryOptions{
 				Enabled:       s.retryOptions.Enabled,
 				MaxRetryCount: s.retryOptions.MaxRetryCount,
 				MaxDuration:   s.retryOptions.MaxDuration,
 				Interval:      s.retryOptions.Interval,
-				ShouldRetry: func(err error, req *http.Request, resp *http.Response) bool {
-					return retrytransport.IsRetryableError(err, resp) && !isMutationRequest(req.Context())
-				},
-			},
+				StatusCodes:   s.retryOptions.StatusCodes,
+				Errors:        s.retryOptions.Errors,
+			}, s.retryBudget),
 			TracerProvider:                s.tracerProvider,
 			LocalhostFallbackInsideDocker: s.localhostFallbackInsideDocker,
 			Logger:                        s.logger,
+			circuitBreakers:               s.circuitBreakers,
+			subgraphCache:                 s.subgraphResponseCache,
 		},
 	}
 
+	ecb.subgraphTransports = make(map[string]*SubgraphTransport, len(s.subgraphTrafficOptions))
+	for name, opts := range s.subgraphTrafficOptions {
+		transportOptions := *ecb.transportOptions
+		transportOptions.RequestTimeout = opts.Transport.RequestTimeout
+		transportOptions.RetryOptions = subgraphRetryOptions(opts.Retry, s.retryBudget)
+		ecb.subgraphTransports[name] = &SubgraphTransport{
+			Transport: s.subgraphTransports[name],
+			Options:   &transportOptions,
+		}
+	}
+
 	executor, err := ecb.Build(
 		ctx,
 		&ExecutorBuildOptions{
@@ -586,14 +689,35 @@ func (s *graphServer) buildGraphMux(ctx context.Context,
 		Executor:                       executor,
 		MaxOperationSizeInBytes:        int64(s.routerTrafficConfig.MaxRequestBodyBytes),
 		PersistedOperationClient:       s.persistedOperationClient,
+		AutomaticPersistedQueries:      s.apq,
 		EnablePersistedOperationsCache: s.engineExecutionConfiguration.EnablePersistedOperationsCache,
 		NormalizationCache:             gm.normalizationCache,
 		ValidationCache:                gm.validationCache,
 		QueryDepthCache:                gm.queryDepthCache,
+		QueryCostCache:                 gm.queryCostCache,
+		FieldCountCache:                gm.fieldCountCache,
+		AliasCountCache:                gm.aliasCountCache,
+		RootFieldCountCache:            gm.rootFieldCountCache,
+		DirectiveCountCache:            gm.directiveCountCache,
+		TokenCountCache:                gm.tokenCountCache,
 		ParseKitPoolSize:               s.engineExecutionConfiguration.ParseKitPoolSize,
 	})
 	operationPlanner := NewOperationPlanner(executor, gm.planCache)
 
+	if s.persistedOperationsConfig.Warmup.Enabled {
+		s.warmupPersistedOperations(ctx, operationProcessor, operationPlanner, featureFlagName)
+	}
+
+	// The usage can only be tracked for the operations that fit into the execution plan cache
+	if s.engineExecutionConfiguration.ExecutionPlanCacheWarmup.Enabled && s.engineExecutionConfiguration.ExecutionPlanCacheSize > 0 {
+		previousOperationUsage := s.previousOperationUsage[featureFlagName]
+		if len(previousOperationUsage) > 0 {
+			s.warmupExecutionPlanCache(ctx, operationProcessor, operationPlanner, featureFlagName, previousOperationUsage)
+		}
+		gm.operationUsage = newOperationUsage(s.engineExecutionConfiguration.ExecutionPlanCacheSize, previousOperationUsage)
+		operationPlanner.operationUsage = gm.operationUsage
+	}
+
 	authorizerOptions := &CosmoAuthorizerOptions{
 		FieldConfigurations:           engineConfig.FieldConfigurations,
 		RejectOperationIfUnauthorized: false,
@@ -601,6 +725,9 @@ func (s *graphServer) buildGraphMux(ctx context.Context,
 
 	if s.Config.authorization != nil {
 		authorizerOptions.RejectOperationIfUnauthorized = s.authorization.RejectOperationIfUnauthorized
+		authorizerOptions.ScopeClaimPaths = s.authorization.Scopes.ClaimPaths
+		authorizerOptions.ScopePrefixMappings = s.authorization.Scopes.PrefixMappings
+		authorizerOptions.FieldPolicies = s.authorization.FieldPolicies
 	}
 
 	handlerOpts := HandlerOptions{
@@ -617,22 +744,44 @@ func (s *graphServer) buildGraphMux(ctx context.Context,
 		EngineLoaderHooks:                           NewEngineRequestHooks(s.metricStore),
 	}
 
-	if s.redisClient != nil {
-		handlerOpts.RateLimitConfig = s.rateLimit
-		handlerOpts.RateLimiter = NewCosmoRateLimiter(&CosmoRateLimiterOptions{
-			RedisClient: s.redisClient,
-			Debug:       s.rateLimit.Debug,
+	if s.redisClient != nil || s.rateLimitMemoryStorage != nil {
+		rateLimiter, err := NewCosmoRateLimiter(&CosmoRateLimiterOptions{
+			RedisClient:   s.redisClient,
+			MemoryStorage: s.rateLimitMemoryStorage,
+			FailureMode:   s.rateLimit.FailureMode,
+			Logger:        s.logger,
+			Debug:         s.rateLimit.Debug,
+			Strategy:      s.rateLimit.Strategy,
+			DefaultLimit: redis_rate.Limit{
+				Rate:   s.rateLimit.SimpleStrategy.Rate,
+				Burst:  s.rateLimit.SimpleStrategy.Burst,
+				Period: s.rateLimit.SimpleStrategy.Period,
+			},
+			Keys: s.rateLimit.Keys,
 		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create rate limiter: %w", err)
+		}
+		handlerOpts.RateLimitConfig = s.rateLimit
+		handlerOpts.RateLimiter = rateLimiter
+	}
+
+	if s.responseCacheStorage != nil && s.responseCacheConfig != nil && s.responseCacheConfig.Enabled {
+		handlerOpts.ResponseCache = newResponseCache(s.responseCacheStorage, s.responseCacheConfig, routerConfigVersion+":"+featureFlagName, s.logger)
 	}
 
 	graphqlHandler := NewGraphQLHandler(handlerOpts)
 	executor.Resolver.SetAsyncErrorWriter(graphqlHandler)
 
-	operationBlocker := NewOperationBlocker(&OperationBlockerOptions{
+	operationBlocker, err := NewOperationBlocker(&OperationBlockerOptions{
 		BlockMutations:     s.securityConfiguration.BlockMutations,
 		BlockSubscriptions: s.securityConfiguration.BlockSubscriptions,
 		BlockNonPersisted:  s.securityConfiguration.BlockNonPersistedOperations,
+		Rules:              s.securityConfiguration.OperationRules,
 	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create operation blocker: %w", err)
+	}
 
 	graphqlPreHandler := NewPreHandler(&PreHandlerOptions{
 		Logger:                      s.logger,
@@ -654,6 +803,12 @@ func (s *graphServer) buildGraphMux(ctx context.Context,
 		QueryDepthEnabled:           s.securityConfiguration.DepthLimit.Enabled,
 		QueryDepthLimit:             s.securityConfiguration.DepthLimit.Limit,
 		QueryIgnorePersistent:       s.securityConfiguration.DepthLimit.IgnorePersistedOperations,
+		QueryCostEnabled:            s.securityConfiguration.CostAnalysis.Enabled,
+		QueryCostLimit:              s.securityConfiguration.CostAnalysis.MaxCost,
+		QueryCostDefaultListSize:    s.securityConfiguration.CostAnalysis.DefaultListSize,
+		QueryCostIgnorePersistent:   s.securityConfiguration.CostAnalysis.IgnorePersistedOperations,
+		ComplexityLimits:            s.securityConfiguration.ComplexityLimits,
+		FieldPolicyCoordinates:      routerEngineConfig.FieldPolicyCoordinates,
 		AlwaysIncludeQueryPlan:      s.engineExecutionConfiguration.Debug.AlwaysIncludeQueryPlan,
 		AlwaysSkipLoader:            s.engineExecutionConfiguration.Debug.AlwaysSkipLoader,
 		QueryPlansEnabled:           s.Config.queryPlansEnabled,
@@ -757,7 +912,16 @@ func (s *graphServer) buildPubSubConfiguration(ctx context.Context, engineConfig
 						return err
 					}
 
-					s.pubSubProviders.nats[providerID] = pubsubNats.NewConnector(s.logger, natsConnection, js).New(ctx)
+					jetStreamOptions, err := buildNatsJetStreamOptions(eventSource.JetStream)
+					if err != nil {
+						return fmt.Errorf("failed to build JetStream options for Nats provider with ID \"%s\": %w", providerID, err)
+					}
+
+					s.pubSubProviders.nats[providerID] = pubsubNats.NewConnector(s.logger, natsConnection, js, pubsubNats.Options{
+						PendingBufferSize: eventSource.PendingBufferSize,
+						JetStream:         jetStreamOptions,
+						Metrics:           s.metricStore,
+					}).New(ctx)
 
 					break
 				}
@@ -780,8 +944,15 @@ func (s *graphServer) buildPubSubConfiguration(ctx context.Context, engineConfig
 					if err != nil {
 						return fmt.Errorf("failed to build options for Kafka provider with ID \"%s\": %w", providerID, err)
 					}
-					ps, err := kafka.NewConnector(s.logger, options)
+					consumerOptions, err := s.buildKafkaConsumerOptions(eventSource, routerEngineCfg.Events.Providers.Nats)
+					if err != nil {
+						return fmt.Errorf("failed to build consumer options for Kafka provider with ID \"%s\": %w", providerID, err)
+					}
+					ps, err := kafka.NewConnector(s.logger, options, consumerOptions, buildKafkaProducerOptions(eventSource))
 					if err != nil {
+						if consumerOptions.Group != nil {
+							consumerOptions.Group.FanOut.Close()
+						}
 						return fmt.Errorf("failed to create connection for Kafka provider with ID \"%s\": %w", providerID, err)
 					}
 
@@ -797,6 +968,116 @@ func (s *graphServer) buildPubSubConfiguration(ctx context.Context, engineConfig
 	return nil
 }
 
+func buildKafkaProducerOptions(eventSource config.KafkaEventSource) kafka.ProducerOptions {
+	producer := eventSource.Producer
+
+	keys := make(map[string]kafka.RecordKey, len(producer.Keys))
+	for _, key := range producer.Keys {
+		keys[key.Topic] = kafka.RecordKey{
+			Argument: key.Argument,
+			Path:     key.Path,
+		}
+	}
+
+	return kafka.ProducerOptions{
+		Acks:             kafka.Acks(producer.Acks),
+		Idempotent:       producer.Idempotent,
+		Compression:      producer.Compression,
+		Linger:           producer.Linger,
+		Keys:             keys,
+		PropagateHeaders: producer.PropagateHeaders,
+		RequestHeaders: func(ctx context.Context) http.Header {
+			reqCtx := getRequestContext(ctx)
+			if reqCtx == nil || reqCtx.Request() == nil {
+				return nil
+			}
+			return reqCtx.Request().Header
+		},
+	}
+}
+
+// buildKafkaConsumerOptions connects to the NATS provider that fans out the records of the consumer group
+func (s *graphServer) buildKafkaConsumerOptions(eventSource config.KafkaEventSource, natsEventSources []config.NatsEventSource) (kafka.ConsumerOptions, error) {
+	consumerOptions := kafka.ConsumerOptions{
+		StartOffset:    kafka.StartOffset(eventSource.Consumer.StartOffset),
+		StartTimestamp: eventSource.Consumer.StartTimestamp,
+	}
+
+	group := eventSource.Consumer.Group
+	if group == nil {
+		return consumerOptions, nil
+	}
+
+	for _, natsEventSource := range natsEventSources {
+		if natsEventSource.ID != group.FanOutProviderID {
+			continue
+		}
+		options, err := buildNatsOptions(natsEventSource, s.logger)
+		if err != nil {
+			return consumerOptions, err
+		}
+		// A dedicated connection, the connection of the NATS provider is closed with its pubsub
+		natsConnection, err := nats.Connect(natsEventSource.URL, options...)
+		if err != nil {
+			return consumerOptions, fmt.Errorf("failed to create fan out connection for Nats provider with ID \"%s\": %w", group.FanOutProviderID, err)
+		}
+		consumerOptions.Group = &kafka.ConsumerGroupOptions{
+			ID:            group.ID,
+			Commit:        kafka.CommitMode(group.Commit),
+			FanOut:        natsConnection,
+			SubjectPrefix: group.FanOutSubjectPrefix,
+			// The subscriptions receive the records like the subscriptions of the NATS provider
+			PendingBufferSize: natsEventSource.PendingBufferSize,
+		}
+		return consumerOptions, nil
+	}
+
+	return consumerOptions, fmt.Errorf("fan out Nats provider with ID \"%s\" of the consumer group not found", group.FanOutProviderID)
+}
+
+func buildNatsJetStreamOptions(cfg config.NatsJetStreamConfiguration) (pubsubNats.JetStreamOptions, error) {
+	options := pubsubNats.JetStreamOptions{
+		EphemeralConsumers: cfg.EphemeralConsumers,
+		InactiveThreshold:  cfg.InactiveThreshold,
+		AckWait:            cfg.AckWait,
+		MaxDeliver:         cfg.MaxDeliver,
+		StartSequence:      cfg.StartSequence,
+		StartTime:          cfg.StartTime,
+		FetchBatchSize:     cfg.FetchBatchSize,
+		FetchMaxWait:       cfg.FetchMaxWait,
+	}
+
+	switch cfg.AckPolicy {
+	case "", "explicit":
+		options.AckPolicy = jetstream.AckExplicitPolicy
+	case "all":
+		options.AckPolicy = jetstream.AckAllPolicy
+	case "none":
+		options.AckPolicy = jetstream.AckNonePolicy
+	default:
+		return options, fmt.Errorf("unknown ack policy \"%s\"", cfg.AckPolicy)
+	}
+
+	switch cfg.DeliverPolicy {
+	case "", "all":
+		options.DeliverPolicy = jetstream.DeliverAllPolicy
+	case "last":
+		options.DeliverPolicy = jetstream.DeliverLastPolicy
+	case "new":
+		options.DeliverPolicy = jetstream.DeliverNewPolicy
+	case "last_per_subject":
+		options.DeliverPolicy = jetstream.DeliverLastPerSubjectPolicy
+	case "by_start_sequence":
+		options.DeliverPolicy = jetstream.DeliverByStartSequencePolicy
+	case "by_start_time":
+		options.DeliverPolicy = jetstream.DeliverByStartTimePolicy
+	default:
+		return options, fmt.Errorf("unknown deliver policy \"%s\"", cfg.DeliverPolicy)
+	}
+
+	return options, nil
+}
+
 // wait waits for all in-flight requests to finish. Similar to http.Server.Shutdown we wait in intervals + jitter
 // to make the shutdown process more efficient.
 func (s *graphServer) wait(ctx context.Context) error {
@@ -854,7 +1135,14 @@ func (s *graphServer) Shutdown(ctx context.Context) error {
 		ctx = newCtx
 	}
 
+	if s.circuitBreakers != nil {
+		s.circuitBreakers.shutdown()
+	}
+
 	if s.metricStore != nil {
+		if s.executionConfigFallback {
+			s.metricStore.MeasureExecutionConfigFallback(ctx, -1, otel.WgRouterConfigVersion.String(s.baseRouterConfigVersion))
+		}
 		if err := s.metricStore.Shutdown(ctx); err != nil {
 			s.logger.Error("Failed to shutdown metric store", zap.Error(err))
 			finalErr = errors.Join(finalErr, err)
@@ -1004,3 +1292,21 @@ func configureSubgraphOverwrites(
 
 	return subgraphs, nil
 }
+
+// subgraphRetryOptions returns the retry options of the transport. Requests of mutations are never retried.
+// The budget is shared by all transports of the router, it is nil if the retries are not limited.
+func subgraphRetryOptions(opts SubgraphRetryOptions, budget *retrytransport.Budget) retrytransport.RetryOptions {
+	retryOptions := retrytransport.RetryOptions{
+		Enabled:       opts.Enabled,
+		MaxRetryCount: opts.MaxRetryCount,
+		MaxDuration:   opts.MaxDuration,
+		Interval:      opts.Interval,
+		StatusCodes:   opts.StatusCodes,
+		Errors:        opts.Errors,
+		Budget:        budget,
+	}
+	retryOptions.ShouldRetry = func(err error, req *http.Request, resp *http.Response) bool {
+		return retryOptions.IsRetryable(err, resp) && !isMutationRequest(req.Context())
+	}
+	return retryOptions
+}
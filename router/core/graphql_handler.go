@@ -87,6 +87,8 @@ type HandlerOptions struct {
 	RateLimitConfig                             *config.RateLimitConfiguration
 	SubgraphErrorPropagation                    config.SubgraphErrorPropagationConfiguration
 	EngineLoaderHooks                           resolve.LoaderHooks
+	// ResponseCache is nil if the response cache is disabled
+	ResponseCache *responseCache
 }
 
 func NewGraphQLHandler(opts HandlerOptions) *GraphQLHandler {
@@ -107,6 +109,7 @@ func NewGraphQLHandler(opts HandlerOptions) *GraphQLHandler {
 		rateLimitConfig:          opts.RateLimitConfig,
 		subgraphErrorPropagation: opts.SubgraphErrorPropagation,
 		engineLoaderHooks:        opts.EngineLoaderHooks,
+		responseCache:            opts.ResponseCache,
 	}
 	return graphQLHandler
 }
@@ -132,6 +135,7 @@ type GraphQLHandler struct {
 	rateLimitConfig          *config.RateLimitConfiguration
 	subgraphErrorPropagation config.SubgraphErrorPropagationConfiguration
 	engineLoaderHooks        resolve.LoaderHooks
+	responseCache            *responseCache
 
 	enableExecutionPlanCacheResponseHeader      bool
 	enablePersistedOperationCacheResponseHeader bool
@@ -176,7 +180,7 @@ func (h *GraphQLHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if h.engineLoaderHooks != nil {
 		ctx.SetEngineLoaderHooks(h.engineLoaderHooks)
 	}
-	ctx = h.configureRateLimiting(ctx)
+	ctx = h.configureRateLimiting(operationCtx, ctx)
 
 	defer propagateSubgraphErrors(ctx, requestLogger)
 
@@ -187,13 +191,34 @@ func (h *GraphQLHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		if h.enableResponseHeaderPropagation {
 			ctx = WithResponseHeaderPropagation(ctx)
 		}
-		resp, err := h.executor.Resolver.ResolveGraphQLResponse(ctx, p.Response, nil, HeaderPropagationWriter(w, ctx.Context()))
+
+		writer := HeaderPropagationWriter(w, ctx.Context())
+		var cacheKey string
+		var cachedBody *bytes.Buffer
+		if h.responseCache != nil {
+			var cacheable bool
+			if cacheKey, cacheable = h.responseCache.key(getRequestContext(r.Context()), operationCtx); cacheable {
+				if h.responseCache.serve(w, r, cacheKey) {
+					graphqlExecutionSpan.SetAttributes(rotel.WgResponseCacheHit.Bool(true))
+					return
+				}
+				graphqlExecutionSpan.SetAttributes(rotel.WgResponseCacheHit.Bool(false))
+				// The response is buffered, because the ETag has to be sent before the body
+				cachedBody = &bytes.Buffer{}
+				writer = cachedBody
+			}
+		}
+
+		resp, err := h.executor.Resolver.ResolveGraphQLResponse(ctx, p.Response, nil, writer)
 		if err != nil {
 			requestLogger.Error("unable to resolve response", zap.Error(err))
 			trackResponseError(ctx.Context(), err)
 			h.WriteError(ctx, err, p.Response, w)
 			return
 		}
+		if cachedBody != nil {
+			h.responseCache.store(ctx.Context(), w, r, cacheKey, cachedBody.Bytes())
+		}
 		graphqlExecutionSpan.SetAttributes(rotel.WgAcquireResolverWaitTimeMs.Int64(resp.ResolveAcquireWaitTime.Milliseconds()))
 	case *plan.SubscriptionResponsePlan:
 		var (
@@ -235,7 +260,7 @@ func (h *GraphQLHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (h *GraphQLHandler) configureRateLimiting(ctx *resolve.Context) *resolve.Context {
+func (h *GraphQLHandler) configureRateLimiting(operationCtx *operationContext, ctx *resolve.Context) *resolve.Context {
 	if h.rateLimiter == nil {
 		return ctx
 	}
@@ -245,9 +270,6 @@ func (h *GraphQLHandler) configureRateLimiting(ctx *resolve.Context) *resolve.Co
 	if !h.rateLimitConfig.Enabled {
 		return ctx
 	}
-	if h.rateLimitConfig.Strategy != "simple" {
-		return ctx
-	}
 	ctx.SetRateLimiter(h.rateLimiter)
 	ctx.RateLimitOptions = resolve.RateLimitOptions{
 		Enable:                          true,
@@ -258,7 +280,8 @@ func (h *GraphQLHandler) configureRateLimiting(ctx *resolve.Context) *resolve.Co
 		RateLimitKey:                    h.rateLimitConfig.Storage.KeyPrefix,
 		RejectExceedingRequests:         h.rateLimitConfig.SimpleStrategy.RejectExceedingRequests,
 	}
-	return WithRateLimiterStats(ctx)
+	keys := h.rateLimiter.resolveKeys(getRequestContext(ctx.Context()))
+	return withRateLimiterStats(ctx, keys, operationCtx.cost)
 }
 
 // WriteError writes the error to the response writer. This function must be concurrency-safe.
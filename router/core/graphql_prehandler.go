@@ -20,6 +20,8 @@ import (
 	"go.opentelemetry.io/otel/trace"
 
 	"github.com/wundergraph/cosmo/router/pkg/art"
+	"github.com/wundergraph/cosmo/router/pkg/authentication"
+	"github.com/wundergraph/cosmo/router/pkg/config"
 	"github.com/wundergraph/cosmo/router/pkg/logging"
 	"github.com/wundergraph/cosmo/router/pkg/otel"
 	rtrace "github.com/wundergraph/cosmo/router/pkg/trace"
@@ -47,6 +49,15 @@ type PreHandlerOptions struct {
 	QueryDepthLimit       int
 	QueryIgnorePersistent bool
 
+	QueryCostEnabled          bool
+	QueryCostLimit            int
+	QueryCostDefaultListSize  int
+	QueryCostIgnorePersistent bool
+
+	ComplexityLimits config.ComplexityLimits
+	// FieldPolicyCoordinates are the fields whose arguments are collected for the field policies of the authorizer
+	FieldPolicyCoordinates []string
+
 	FlushTelemetryAfterResponse bool
 	FileUploadEnabled           bool
 	TraceExportVariables        bool
@@ -82,6 +93,13 @@ type PreHandler struct {
 	queryDepthEnabled           bool
 	queryDepthLimit             int
 	queryIgnorePersistent       bool
+	queryCostEnabled            bool
+	queryCostLimit              int
+	queryCostDefaultListSize    int
+	queryCostIgnorePersistent   bool
+	complexityLimits            []complexityLimit
+	tokenCountLimit             config.ComplexityLimit
+	fieldPolicyCoordinates      []string
 	bodyReadBuffers             *sync.Pool
 	trackSchemaUsageInfo        bool
 }
@@ -118,17 +136,24 @@ func NewPreHandler(opts *PreHandlerOptions) *PreHandler {
 			"wundergraph/cosmo/router/pre_handler",
 			trace.WithInstrumentationVersion("0.0.1"),
 		),
-		fileUploadEnabled:      opts.FileUploadEnabled,
-		maxUploadFiles:         opts.MaxUploadFiles,
-		maxUploadFileSize:      opts.MaxUploadFileSize,
-		queryDepthEnabled:      opts.QueryDepthEnabled,
-		queryDepthLimit:        opts.QueryDepthLimit,
-		queryIgnorePersistent:  opts.QueryIgnorePersistent,
-		bodyReadBuffers:        &sync.Pool{},
-		alwaysIncludeQueryPlan: opts.AlwaysIncludeQueryPlan,
-		alwaysSkipLoader:       opts.AlwaysSkipLoader,
-		queryPlansEnabled:      opts.QueryPlansEnabled,
-		trackSchemaUsageInfo:   opts.TrackSchemaUsageInfo,
+		fileUploadEnabled:         opts.FileUploadEnabled,
+		maxUploadFiles:            opts.MaxUploadFiles,
+		maxUploadFileSize:         opts.MaxUploadFileSize,
+		queryDepthEnabled:         opts.QueryDepthEnabled,
+		queryDepthLimit:           opts.QueryDepthLimit,
+		queryIgnorePersistent:     opts.QueryIgnorePersistent,
+		queryCostEnabled:          opts.QueryCostEnabled,
+		queryCostLimit:            opts.QueryCostLimit,
+		queryCostDefaultListSize:  opts.QueryCostDefaultListSize,
+		queryCostIgnorePersistent: opts.QueryCostIgnorePersistent,
+		complexityLimits:          newComplexityLimits(opts.ComplexityLimits),
+		tokenCountLimit:           opts.ComplexityLimits.TokenCount,
+		fieldPolicyCoordinates:    opts.FieldPolicyCoordinates,
+		bodyReadBuffers:           &sync.Pool{},
+		alwaysIncludeQueryPlan:    opts.AlwaysIncludeQueryPlan,
+		alwaysSkipLoader:          opts.AlwaysSkipLoader,
+		queryPlansEnabled:         opts.QueryPlansEnabled,
+		trackSchemaUsageInfo:      opts.TrackSchemaUsageInfo,
 	}
 }
 
@@ -336,6 +361,14 @@ func (h *PreHandler) Handler(next http.Handler) http.Handler {
 			r = validatedReq
 		}
 
+		if err := h.operationBlocker.OperationIsDenied(opContext, authentication.FromContext(r.Context())); err != nil {
+			finalErr = err
+			rtrace.AttachErrToSpan(routerSpan, err)
+
+			writeOperationError(r, w, requestLogger, err)
+			return
+		}
+
 		art.SetRequestTracingStats(r.Context(), traceOptions, traceTimings)
 
 		requestContext := buildRequestContext(w, r, opContext, requestLogger)
@@ -422,6 +455,14 @@ func (h *PreHandler) handleOperation(req *http.Request, buf *bytes.Buffer, httpO
 
 		httpOperation.traceTimings.StartParse()
 
+		if err := h.validateTokenCount(operationKit, engineParseSpan); err != nil {
+			rtrace.AttachErrToSpan(engineParseSpan, err)
+
+			engineParseSpan.End()
+
+			return nil, err
+		}
+
 		err = operationKit.Parse()
 		if err != nil {
 			rtrace.AttachErrToSpan(engineParseSpan, err)
@@ -564,7 +605,7 @@ func (h *PreHandler) handleOperation(req *http.Request, buf *bytes.Buffer, httpO
 
 	// Validate that the planned query doesn't exceed the maximum query depth configured
 	// This check runs if they've configured a max query depth, and it can optionally be turned off for persisted operations
-	if h.queryDepthEnabled && h.queryDepthLimit > 0 && (!operationKit.parsedOperation.IsPersistedOperation || operationKit.parsedOperation.IsPersistedOperation && !h.queryIgnorePersistent) {
+	if h.queryDepthEnabled && h.queryDepthLimit > 0 && (!operationKit.isTrustedPersistedOperation() || !h.queryIgnorePersistent) {
 		cacheHit, depth, queryDepthErr := operationKit.ValidateQueryDepth(h.queryDepthLimit, operationKit.kit.doc, h.executor.RouterSchema)
 		engineValidateSpan.SetAttributes(otel.WgQueryDepth.Int(depth))
 		engineValidateSpan.SetAttributes(otel.WgQueryDepthCacheHit.Bool(cacheHit))
@@ -575,8 +616,23 @@ func (h *PreHandler) handleOperation(req *http.Request, buf *bytes.Buffer, httpO
 			return nil, queryDepthErr
 		}
 	}
+
+	if err := h.validateOperationLimits(operationKit, engineValidateSpan); err != nil {
+		rtrace.AttachErrToSpan(engineValidateSpan, err)
+		engineValidateSpan.End()
+
+		return nil, err
+	}
 	engineValidateSpan.End()
 
+	// The operation rules are evaluated after authentication, collect the root fields while the document is available
+	if h.operationBlocker.MatchesRootFields() {
+		operationKit.CollectRootFields(h.executor.RouterSchema)
+	}
+	if len(h.fieldPolicyCoordinates) > 0 {
+		operationKit.CollectFieldArguments(h.executor.RouterSchema, h.fieldPolicyCoordinates)
+	}
+
 	httpOperation.traceTimings.EndValidate()
 
 	/**
@@ -652,6 +708,50 @@ func (h *PreHandler) flushMetrics(ctx context.Context, requestLogger *zap.Logger
 	requestLogger.Debug("Metrics flushed", zap.Duration("duration", time.Since(now)))
 }
 
+// validateTokenCount validates the token count on the raw document, so that the parser doesn't run for
+// oversized documents. It is used for operations received over HTTP and WebSocket.
+func (h *PreHandler) validateTokenCount(operationKit *OperationKit, span trace.Span) error {
+	if !h.tokenCountLimit.Enabled || h.tokenCountLimit.Limit <= 0 ||
+		operationKit.isTrustedPersistedOperation() && h.tokenCountLimit.IgnorePersistedOperations {
+		return nil
+	}
+	cacheHit, tokenCount, err := operationKit.ValidateTokenCount(h.tokenCountLimit.Limit)
+	span.SetAttributes(otel.WgQueryTokenCount.Int(tokenCount))
+	span.SetAttributes(otel.WgQueryTokenCountCacheHit.Bool(cacheHit))
+	return err
+}
+
+// validateOperationLimits estimates the cost of the operation and validates its size. The cost is used as the rate
+// of the request when rate limiting is enabled. The limits can optionally be turned off for persisted operations.
+// It is used for operations received over HTTP and WebSocket.
+func (h *PreHandler) validateOperationLimits(operationKit *OperationKit, span trace.Span) error {
+	if h.queryCostEnabled {
+		maxQueryCost := h.queryCostLimit
+		if operationKit.isTrustedPersistedOperation() && h.queryCostIgnorePersistent {
+			maxQueryCost = 0
+		}
+		cacheHit, cost, err := operationKit.CalculateQueryCost(maxQueryCost, h.queryCostDefaultListSize, operationKit.kit.doc, h.executor.RouterSchema)
+		span.SetAttributes(otel.WgQueryCost.Int(cost))
+		span.SetAttributes(otel.WgQueryCostCacheHit.Bool(cacheHit))
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, limit := range h.complexityLimits {
+		if operationKit.isTrustedPersistedOperation() && limit.limit.IgnorePersistedOperations {
+			continue
+		}
+		cacheHit, value, err := operationKit.validateComplexityLimit(limit)
+		span.SetAttributes(limit.valueAttribute.Int(value))
+		span.SetAttributes(limit.cacheAttribute.Bool(cacheHit))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (h *PreHandler) parseRequestOptions(r *http.Request, clientInfo *ClientInfo, requestLogger *zap.Logger) (resolve.ExecutionOptions, resolve.TraceOptions, error) {
 	ex, tr, err := h.internalParseRequestOptions(r, clientInfo, requestLogger)
 	if err != nil {
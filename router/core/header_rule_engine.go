@@ -414,26 +414,8 @@ func (h *HeaderPropagation) applyResponseRuleMostRestrictiveCacheControl(res *ht
 		return
 	}
 
-	reqDir, _ := cachedirective.ParseRequestCacheControl(res.Request.Header.Get(cacheControlKey))
-	resDir, _ := cachedirective.ParseResponseCacheControl(res.Header.Get(cacheControlKey))
-	expiresHeader, _ := http.ParseTime(res.Header.Get("Expires"))
-	dateHeader, _ := http.ParseTime(res.Header.Get("Date"))
-	lastModifiedHeader, _ := http.ParseTime(res.Header.Get("Last-Modified"))
-
-	obj := &cachedirective.Object{
-		RespDirectives:         resDir,
-		RespHeaders:            res.Header,
-		RespStatusCode:         res.StatusCode,
-		RespExpiresHeader:      expiresHeader,
-		RespDateHeader:         dateHeader,
-		RespLastModifiedHeader: lastModifiedHeader,
-
-		ReqDirectives: reqDir,
-		ReqHeaders:    res.Request.Header,
-		ReqMethod:     res.Request.Method,
-
-		NowUTC: time.Now().UTC(),
-	}
+	obj := newCacheControlObject(res, res.Request.Method)
+	expiresHeader := obj.RespExpiresHeader
 	rv := cachedirective.ObjectResults{}
 
 	cachedirective.CachableObject(obj, &rv)
@@ -481,6 +463,54 @@ func (h *HeaderPropagation) applyResponseRuleMostRestrictiveCacheControl(res *ht
 	}
 }
 
+// newCacheControlObject parses the caching headers of the subgraph request and response. The method
+// overrides the one of the request, because subgraph requests are sent as POST, which is never cacheable.
+func newCacheControlObject(res *http.Response, method string) *cachedirective.Object {
+	reqDir, _ := cachedirective.ParseRequestCacheControl(res.Request.Header.Get("Cache-Control"))
+	resDir, _ := cachedirective.ParseResponseCacheControl(res.Header.Get("Cache-Control"))
+	expiresHeader, _ := http.ParseTime(res.Header.Get("Expires"))
+	dateHeader, _ := http.ParseTime(res.Header.Get("Date"))
+	lastModifiedHeader, _ := http.ParseTime(res.Header.Get("Last-Modified"))
+
+	return &cachedirective.Object{
+		RespDirectives:         resDir,
+		RespHeaders:            res.Header,
+		RespStatusCode:         res.StatusCode,
+		RespExpiresHeader:      expiresHeader,
+		RespDateHeader:         dateHeader,
+		RespLastModifiedHeader: lastModifiedHeader,
+
+		ReqDirectives: reqDir,
+		ReqHeaders:    res.Request.Header,
+		ReqMethod:     method,
+
+		NowUTC: time.Now().UTC(),
+	}
+}
+
+// cacheControlTTL returns how long a shared cache may store the response. The default ttl is used
+// if the caching headers don't define an expiration. It returns false if the response must not be stored.
+func cacheControlTTL(res *http.Response, defaultTTL time.Duration) (time.Duration, bool) {
+	obj := newCacheControlObject(res, http.MethodGet)
+	// no-cache would require a revalidation with the origin, which isn't supported
+	if obj.RespDirectives.NoCachePresent {
+		return 0, false
+	}
+
+	rv := cachedirective.ObjectResults{}
+	cachedirective.CachableObject(obj, &rv)
+	if len(rv.OutReasons) > 0 {
+		return 0, false
+	}
+	cachedirective.ExpirationObject(obj, &rv)
+
+	if rv.OutExpirationTime.IsZero() {
+		return defaultTTL, defaultTTL > 0
+	}
+	ttl := rv.OutExpirationTime.Sub(obj.NowUTC)
+	return ttl, ttl > 0
+}
+
 // isMoreRestrictive compares two cachedirective.Object instances and returns true if the first is more restrictive
 func isMoreRestrictive(prev *cachedirective.Object, curr *cachedirective.Object) bool {
 	// Example comparison logic: check if "no-store" or "no-cache" are present, which are more restrictive
@@ -0,0 +1,167 @@
+package core
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/oauth"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+
+	"github.com/wundergraph/cosmo/router/pkg/config"
+)
+
+// kafkaOAuthTokenExpiryDelta refreshes a cached token before it expires, so that a connection
+// isn't authenticated with a token that expires during the handshake
+const kafkaOAuthTokenExpiryDelta = 30 * time.Second
+
+func newKafkaTLSConfig(cfg *config.KafkaTLSConfiguration) (*tls.Config, error) {
+	if cfg == nil || !cfg.Enabled {
+		return nil, nil
+	}
+
+	return newClientTLSConfig("kafka", cfg.CaFile, cfg.CertFile, cfg.KeyFile, cfg.InsecureSkipVerify)
+}
+
+// newKafkaSASLMechanism returns the configured SASL mechanism or nil if the provider doesn't use SASL
+func newKafkaSASLMechanism(cfg *config.KafkaAuthentication) (sasl.Mechanism, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	if cfg.SASLSCRAM != nil {
+		if cfg.SASLSCRAM.Username == nil || cfg.SASLSCRAM.Password == nil {
+			return nil, errors.New("username and password are required for SCRAM authentication")
+		}
+		auth := scram.Auth{
+			User: *cfg.SASLSCRAM.Username,
+			Pass: *cfg.SASLSCRAM.Password,
+		}
+		switch cfg.SASLSCRAM.Mechanism {
+		case "SCRAM-SHA-256":
+			return auth.AsSha256Mechanism(), nil
+		case "SCRAM-SHA-512":
+			return auth.AsSha512Mechanism(), nil
+		default:
+			return nil, fmt.Errorf("unknown SCRAM mechanism \"%s\"", cfg.SASLSCRAM.Mechanism)
+		}
+	}
+
+	if cfg.SASLOAuthBearer != nil {
+		if cfg.SASLOAuthBearer.Token != nil {
+			token := *cfg.SASLOAuthBearer.Token
+			return oauth.Oauth(func(ctx context.Context) (oauth.Auth, error) {
+				return oauth.Auth{
+					Token:      token,
+					Extensions: cfg.SASLOAuthBearer.Extensions,
+				}, nil
+			}), nil
+		}
+		if cfg.SASLOAuthBearer.TokenURL == "" {
+			return nil, errors.New("either a token or a token URL is required for OAUTHBEARER authentication")
+		}
+		source := newKafkaOAuthTokenSource(cfg.SASLOAuthBearer, &http.Client{Timeout: 10 * time.Second})
+		return oauth.Oauth(func(ctx context.Context) (oauth.Auth, error) {
+			token, err := source.Token(ctx)
+			if err != nil {
+				return oauth.Auth{}, err
+			}
+			return oauth.Auth{
+				Token:      token,
+				Extensions: cfg.SASLOAuthBearer.Extensions,
+			}, nil
+		}), nil
+	}
+
+	if cfg.SASLPlain.Username != nil && cfg.SASLPlain.Password != nil {
+		return plain.Auth{
+			User: *cfg.SASLPlain.Username,
+			Pass: *cfg.SASLPlain.Password,
+		}.AsMechanism(), nil
+	}
+
+	return nil, nil
+}
+
+// kafkaOAuthTokenSource fetches tokens with the client credentials grant. Every new broker connection
+// authenticates, the token is cached until shortly before it expires.
+type kafkaOAuthTokenSource struct {
+	cfg    *config.KafkaSASLOAuthBearerAuthentication
+	client *http.Client
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+type kafkaOAuthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	// ExpiresIn is the lifetime of the token in seconds. If it's missing, the token is fetched again for every connection.
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+func newKafkaOAuthTokenSource(cfg *config.KafkaSASLOAuthBearerAuthentication, client *http.Client) *kafkaOAuthTokenSource {
+	return &kafkaOAuthTokenSource{
+		cfg:    cfg,
+		client: client,
+	}
+}
+
+func (s *kafkaOAuthTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Now().Before(s.expiresAt) {
+		return s.token, nil
+	}
+
+	form := url.Values{
+		"grant_type": {"client_credentials"},
+	}
+	if len(s.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.cfg.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	// The client credentials are form encoded before they are used for basic authentication (RFC 6749 2.3.1)
+	req.SetBasicAuth(url.QueryEscape(s.cfg.ClientID), url.QueryEscape(s.cfg.ClientSecret))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch kafka OAuth token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("failed to fetch kafka OAuth token: unexpected status code %d: %s", resp.StatusCode, body)
+	}
+
+	var tokenResponse kafkaOAuthTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
+		return "", fmt.Errorf("failed to decode kafka OAuth token response: %w", err)
+	}
+	if tokenResponse.AccessToken == "" {
+		return "", errors.New("kafka OAuth token response doesn't contain an access token")
+	}
+
+	s.token = tokenResponse.AccessToken
+	s.expiresAt = time.Now().Add(time.Duration(tokenResponse.ExpiresIn)*time.Second - kafkaOAuthTokenExpiryDelta)
+
+	return s.token, nil
+}
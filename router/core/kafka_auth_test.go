@@ -0,0 +1,129 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/wundergraph/cosmo/router/pkg/config"
+)
+
+func TestKafkaOAuthTokenSource(t *testing.T) {
+	t.Run("fetches a token with the client credentials grant and caches it", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+
+			require.NoError(t, r.ParseForm())
+			require.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+			require.Equal(t, "kafka events", r.PostForm.Get("scope"))
+
+			clientID, clientSecret, ok := r.BasicAuth()
+			require.True(t, ok)
+			require.Equal(t, "router", clientID)
+			require.Equal(t, "secret", clientSecret)
+
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"access_token":"token","token_type":"bearer","expires_in":3600}`))
+		}))
+		t.Cleanup(server.Close)
+
+		source := newKafkaOAuthTokenSource(&config.KafkaSASLOAuthBearerAuthentication{
+			TokenURL:     server.URL,
+			ClientID:     "router",
+			ClientSecret: "secret",
+			Scopes:       []string{"kafka", "events"},
+		}, server.Client())
+
+		for i := 0; i < 3; i++ {
+			token, err := source.Token(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, "token", token)
+		}
+		require.Equal(t, int32(1), requests.Load())
+	})
+
+	t.Run("fetches a new token if the token has no expiry", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			_, _ = w.Write([]byte(`{"access_token":"token"}`))
+		}))
+		t.Cleanup(server.Close)
+
+		source := newKafkaOAuthTokenSource(&config.KafkaSASLOAuthBearerAuthentication{
+			TokenURL: server.URL,
+		}, server.Client())
+
+		for i := 0; i < 2; i++ {
+			_, err := source.Token(context.Background())
+			require.NoError(t, err)
+		}
+		require.Equal(t, int32(2), requests.Load())
+	})
+
+	t.Run("returns an error for a failed token request", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_client"}`))
+		}))
+		t.Cleanup(server.Close)
+
+		source := newKafkaOAuthTokenSource(&config.KafkaSASLOAuthBearerAuthentication{
+			TokenURL: server.URL,
+		}, server.Client())
+
+		_, err := source.Token(context.Background())
+		require.ErrorContains(t, err, "unexpected status code 401")
+	})
+}
+
+func TestKafkaSASLMechanism(t *testing.T) {
+	username, password := "admin", "admin"
+
+	t.Run("SCRAM mechanisms", func(t *testing.T) {
+		for _, name := range []string{"SCRAM-SHA-256", "SCRAM-SHA-512"} {
+			mechanism, err := newKafkaSASLMechanism(&config.KafkaAuthentication{
+				SASLSCRAM: &config.KafkaSASLSCRAMAuthentication{
+					Mechanism: name,
+					Username:  &username,
+					Password:  &password,
+				},
+			})
+			require.NoError(t, err)
+			require.Equal(t, name, mechanism.Name())
+		}
+	})
+
+	t.Run("OAUTHBEARER with a static token", func(t *testing.T) {
+		token := "token"
+		mechanism, err := newKafkaSASLMechanism(&config.KafkaAuthentication{
+			SASLOAuthBearer: &config.KafkaSASLOAuthBearerAuthentication{
+				Token: &token,
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "OAUTHBEARER", mechanism.Name())
+	})
+
+	t.Run("plain", func(t *testing.T) {
+		mechanism, err := newKafkaSASLMechanism(&config.KafkaAuthentication{
+			SASLPlain: config.KafkaSASLPlainAuthentication{
+				Username: &username,
+				Password: &password,
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "PLAIN", mechanism.Name())
+	})
+
+	t.Run("no mechanism", func(t *testing.T) {
+		mechanism, err := newKafkaSASLMechanism(&config.KafkaAuthentication{})
+		require.NoError(t, err)
+		require.Nil(t, mechanism)
+	})
+}
@@ -2,6 +2,13 @@ package core
 
 import (
 	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/wundergraph/cosmo/router/pkg/authentication"
+	"github.com/wundergraph/cosmo/router/pkg/config"
 )
 
 var (
@@ -10,24 +17,88 @@ var (
 	ErrNonPersistedOperationBlocked = errors.New("non-persisted operation is blocked")
 )
 
+const (
+	OperationRuleActionAllow = "allow"
+	OperationRuleActionDeny  = "deny"
+
+	operationBlockedErrorCode = "OPERATION_BLOCKED"
+)
+
+// OperationBlockedError is returned if an operation is denied by an operation rule
+type OperationBlockedError struct {
+	// Rule is the name of the rule that matched the operation
+	Rule string
+}
+
+func (e *OperationBlockedError) Error() string {
+	return fmt.Sprintf("operation is blocked by rule '%s'", e.Rule)
+}
+
+func (e *OperationBlockedError) Message() string {
+	return e.Error()
+}
+
+func (e *OperationBlockedError) StatusCode() int {
+	return http.StatusOK
+}
+
+func (e *OperationBlockedError) graphqlError() graphqlError {
+	return graphqlError{
+		Message: e.Error(),
+		Extensions: &Extensions{
+			Code: operationBlockedErrorCode,
+			Rule: e.Rule,
+		},
+	}
+}
+
+var (
+	_ HttpError = (*OperationBlockedError)(nil)
+)
+
 type OperationBlocker struct {
 	blockMutations     bool
 	blockSubscriptions bool
 	blockNonPersisted  bool
+	rules              []config.OperationRule
+	matchRootFields    bool
 }
 
 type OperationBlockerOptions struct {
 	BlockMutations     bool
 	BlockSubscriptions bool
 	BlockNonPersisted  bool
+	// Rules are evaluated in order for every root field, the first matching rule decides if it is allowed or denied
+	Rules []config.OperationRule
 }
 
-func NewOperationBlocker(opts *OperationBlockerOptions) *OperationBlocker {
-	return &OperationBlocker{
+func NewOperationBlocker(opts *OperationBlockerOptions) (*OperationBlocker, error) {
+	blocker := &OperationBlocker{
 		blockMutations:     opts.BlockMutations,
 		blockSubscriptions: opts.BlockSubscriptions,
 		blockNonPersisted:  opts.BlockNonPersisted,
+		rules:              opts.Rules,
 	}
+	for i, rule := range opts.Rules {
+		if rule.Action != OperationRuleActionAllow && rule.Action != OperationRuleActionDeny {
+			return nil, fmt.Errorf("operation rule %d has an invalid action '%s'", i, rule.Action)
+		}
+		for _, coordinate := range rule.Match.RootFields {
+			if typeName, fieldName, ok := strings.Cut(coordinate, "."); !ok || typeName == "" || fieldName == "" {
+				return nil, fmt.Errorf("operation rule %d has an invalid root field coordinate '%s'", i, coordinate)
+			}
+		}
+		if len(rule.Match.RootFields) > 0 {
+			blocker.matchRootFields = true
+		}
+	}
+	return blocker, nil
+}
+
+// MatchesRootFields returns true if a rule matches on root fields. Only then the root fields of the operation
+// have to be collected.
+func (o *OperationBlocker) MatchesRootFields() bool {
+	return o.matchRootFields
 }
 
 func (o *OperationBlocker) OperationIsBlocked(operation *ParsedOperation) error {
@@ -48,3 +119,70 @@ func (o *OperationBlocker) OperationIsBlocked(operation *ParsedOperation) error
 	}
 	return nil
 }
+
+// OperationIsDenied evaluates the operation rules. The rules are evaluated after the request is authenticated,
+// so they can match on the claims of the request. Operations that match no rule are allowed.
+// The rules are evaluated for every root field, otherwise an allow rule for one root field would also allow
+// the other root fields of the operation.
+func (o *OperationBlocker) OperationIsDenied(operation *operationContext, auth authentication.Authentication) error {
+	if len(operation.rootFields) == 0 {
+		return o.evaluateRules(operation, "", auth)
+	}
+	for _, rootField := range operation.rootFields {
+		if err := o.evaluateRules(operation, rootField, auth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evaluateRules returns an error if the first rule that matches the root field denies the operation
+func (o *OperationBlocker) evaluateRules(operation *operationContext, rootField string, auth authentication.Authentication) error {
+	for _, rule := range o.rules {
+		if !operationRuleMatches(rule.Match, operation, rootField, auth) {
+			continue
+		}
+		if rule.Action == OperationRuleActionDeny {
+			return &OperationBlockedError{Rule: rule.Name}
+		}
+		return nil
+	}
+	return nil
+}
+
+// operationRuleMatches returns true if all configured conditions match. A condition matches if any of its values match.
+func operationRuleMatches(match config.OperationRuleMatch, operation *operationContext, rootField string, auth authentication.Authentication) bool {
+	if len(match.OperationTypes) > 0 && !slices.Contains(match.OperationTypes, operation.Type()) {
+		return false
+	}
+	if len(match.OperationNames) > 0 && !slices.Contains(match.OperationNames, operation.Name()) {
+		return false
+	}
+	if len(match.ClientNames) > 0 && !slices.Contains(match.ClientNames, operation.clientInfo.Name) {
+		return false
+	}
+	if len(match.ClientVersions) > 0 && !slices.Contains(match.ClientVersions, operation.clientInfo.Version) {
+		return false
+	}
+	if len(match.RootFields) > 0 && !slices.Contains(match.RootFields, rootField) {
+		return false
+	}
+	for _, claim := range match.Claims {
+		if auth == nil || !claimMatches(auth.Claims(), claim) {
+			return false
+		}
+	}
+	return true
+}
+
+// claimMatches compares the claim with the configured values. Array claims, e.g. roles, match if any element matches.
+func claimMatches(claims map[string]any, claim config.OperationRuleClaim) bool {
+	value := lookupClaim(claims, claim.Path)
+	if elements, ok := value.([]any); ok {
+		return slices.ContainsFunc(elements, func(element any) bool {
+			return slices.Contains(claim.Values, claimString(element))
+		})
+	}
+	formatted := claimString(value)
+	return formatted != "" && slices.Contains(claim.Values, formatted)
+}
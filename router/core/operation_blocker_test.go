@@ -0,0 +1,117 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/wundergraph/cosmo/router/pkg/authentication"
+	"github.com/wundergraph/cosmo/router/pkg/config"
+)
+
+type testAuthentication struct {
+	claims authentication.Claims
+	scopes []string
+}
+
+func (a *testAuthentication) Authenticator() string {
+	return "test"
+}
+
+func (a *testAuthentication) Claims() authentication.Claims {
+	return a.claims
+}
+
+func (a *testAuthentication) Scopes() []string {
+	return a.scopes
+}
+
+func TestOperationBlockerRules(t *testing.T) {
+	blocker, err := NewOperationBlocker(&OperationBlockerOptions{
+		Rules: []config.OperationRule{
+			{
+				Name:   "admins-may-delete",
+				Action: OperationRuleActionAllow,
+				Match: config.OperationRuleMatch{
+					RootFields: []string{"Mutation.deleteEmployee"},
+					Claims:     []config.OperationRuleClaim{{Path: "org.roles", Values: []string{"admin"}}},
+				},
+			},
+			{
+				Name:   "no-deletion",
+				Action: OperationRuleActionDeny,
+				Match: config.OperationRuleMatch{
+					OperationTypes: []string{"mutation"},
+					RootFields:     []string{"Mutation.deleteEmployee"},
+				},
+			},
+			{
+				Name:   "old-clients",
+				Action: OperationRuleActionDeny,
+				Match: config.OperationRuleMatch{
+					ClientNames:    []string{"ios"},
+					ClientVersions: []string{"1.0.0"},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.True(t, blocker.MatchesRootFields())
+
+	admin := &testAuthentication{claims: authentication.Claims{"org": map[string]any{"roles": []any{"viewer", "admin"}}}}
+	viewer := &testAuthentication{claims: authentication.Claims{"org": map[string]any{"roles": []any{"viewer"}}}}
+
+	deleteEmployee := &operationContext{opType: "mutation", rootFields: []string{"Mutation.deleteEmployee", "Mutation.updateEmployeeTag"}}
+
+	require.NoError(t, blocker.OperationIsDenied(deleteEmployee, admin))
+
+	var blockedErr *OperationBlockedError
+	require.ErrorAs(t, blocker.OperationIsDenied(deleteEmployee, viewer), &blockedErr)
+	require.Equal(t, "no-deletion", blockedErr.Rule)
+	require.ErrorAs(t, blocker.OperationIsDenied(deleteEmployee, nil), &blockedErr)
+	require.Equal(t, "no-deletion", blockedErr.Rule)
+
+	query := &operationContext{opType: "query", rootFields: []string{"Query.employees"}, clientInfo: ClientInfo{Name: "ios", Version: "1.0.0"}}
+	require.ErrorAs(t, blocker.OperationIsDenied(query, nil), &blockedErr)
+	require.Equal(t, "old-clients", blockedErr.Rule)
+
+	query.clientInfo.Version = "1.1.0"
+	require.NoError(t, blocker.OperationIsDenied(query, nil))
+
+	t.Run("allow rules only allow the listed root fields", func(t *testing.T) {
+		blocker, err := NewOperationBlocker(&OperationBlockerOptions{
+			Rules: []config.OperationRule{
+				{
+					Name:   "allow-delete",
+					Action: OperationRuleActionAllow,
+					Match:  config.OperationRuleMatch{RootFields: []string{"Mutation.deleteEmployee"}},
+				},
+				{
+					Name:   "no-mutations",
+					Action: OperationRuleActionDeny,
+					Match:  config.OperationRuleMatch{OperationTypes: []string{"mutation"}},
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, blocker.OperationIsDenied(&operationContext{opType: "mutation", rootFields: []string{"Mutation.deleteEmployee"}}, nil))
+
+		var blockedErr *OperationBlockedError
+		mixed := &operationContext{opType: "mutation", rootFields: []string{"Mutation.deleteEmployee", "Mutation.dropEverything"}}
+		require.ErrorAs(t, blocker.OperationIsDenied(mixed, nil), &blockedErr)
+		require.Equal(t, "no-mutations", blockedErr.Rule)
+	})
+
+	t.Run("invalid rules", func(t *testing.T) {
+		_, err := NewOperationBlocker(&OperationBlockerOptions{
+			Rules: []config.OperationRule{{Name: "invalid", Action: "block"}},
+		})
+		require.Error(t, err)
+
+		_, err = NewOperationBlocker(&OperationBlockerOptions{
+			Rules: []config.OperationRule{{Name: "invalid", Action: OperationRuleActionDeny, Match: config.OperationRuleMatch{RootFields: []string{"deleteEmployee"}}}},
+		})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,216 @@
+package core
+
+import (
+	"math"
+	"strconv"
+
+	fastjson "github.com/wundergraph/astjson"
+	"github.com/wundergraph/graphql-go-tools/v2/pkg/ast"
+	"github.com/wundergraph/graphql-go-tools/v2/pkg/astvisitor"
+	"github.com/wundergraph/graphql-go-tools/v2/pkg/operationreport"
+)
+
+var (
+	literalCost             = []byte("cost")
+	literalWeight           = []byte("weight")
+	literalListSize         = []byte("listSize")
+	literalAssumedSize      = []byte("assumedSize")
+	literalSlicingArguments = []byte("slicingArguments")
+)
+
+// maxOperationCost caps the estimated cost so that deeply nested list selections can't overflow.
+const maxOperationCost = math.MaxInt32
+
+// calculateOperationCost estimates the static cost of a normalized operation.
+//
+// Every field returning a composite type costs 1, scalar and enum fields are free.
+// The weight can be overwritten with a @cost(weight: Int) directive on the field or on the returned type.
+// The cost of a list field, including its sub-selections, is multiplied by the expected list size.
+// The list size is taken from the slicing arguments of a @listSize directive, its assumedSize
+// or, if no hint is available, the defaultListSize.
+func calculateOperationCost(operation, definition *ast.Document, defaultListSize int) (int, error) {
+	walker := astvisitor.NewWalker(48)
+	visitor := &operationCostVisitor{
+		walker:          &walker,
+		operation:       operation,
+		definition:      definition,
+		defaultListSize: defaultListSize,
+	}
+	if len(operation.Input.Variables) > 0 {
+		variables, err := fastjson.ParseBytes(operation.Input.Variables)
+		if err == nil {
+			visitor.variables = variables
+		}
+	}
+	walker.RegisterEnterFieldVisitor(visitor)
+	walker.RegisterLeaveFieldVisitor(visitor)
+	report := &operationreport.Report{}
+	walker.Walk(operation, definition, report)
+	if report.HasErrors() {
+		return 0, report
+	}
+	return visitor.cost, nil
+}
+
+type operationCostVisitor struct {
+	walker                *astvisitor.Walker
+	operation, definition *ast.Document
+	variables             *fastjson.Value
+	defaultListSize       int
+	fields                []fieldCost
+	cost                  int
+}
+
+type fieldCost struct {
+	weight     int
+	multiplier int
+	children   int
+}
+
+func (v *operationCostVisitor) EnterField(ref int) {
+	field := fieldCost{multiplier: 1}
+	fieldDefinition, ok := v.definition.NodeFieldDefinitionByName(v.walker.EnclosingTypeDefinition, v.operation.FieldNameBytes(ref))
+	if ok {
+		field.weight = v.fieldWeight(fieldDefinition)
+		if v.definition.TypeIsList(v.definition.FieldDefinitions[fieldDefinition].Type) {
+			field.multiplier = v.listSize(ref, fieldDefinition)
+		}
+	}
+	v.fields = append(v.fields, field)
+}
+
+func (v *operationCostVisitor) LeaveField(_ int) {
+	field := v.fields[len(v.fields)-1]
+	v.fields = v.fields[:len(v.fields)-1]
+	cost := multiplyCost(addCost(field.weight, field.children), field.multiplier)
+	if len(v.fields) == 0 {
+		v.cost = addCost(v.cost, cost)
+		return
+	}
+	parent := &v.fields[len(v.fields)-1]
+	parent.children = addCost(parent.children, cost)
+}
+
+func (v *operationCostVisitor) fieldWeight(fieldDefinition int) int {
+	if weight, ok := v.costDirectiveWeight(v.definition.FieldDefinitions[fieldDefinition].Directives.Refs); ok {
+		return weight
+	}
+	typeName := v.definition.ResolveTypeNameString(v.definition.FieldDefinitions[fieldDefinition].Type)
+	node, ok := v.definition.NodeByNameStr(typeName)
+	if !ok {
+		return 0
+	}
+	if weight, ok := v.costDirectiveWeight(v.definition.NodeDirectives(node)); ok {
+		return weight
+	}
+	switch node.Kind {
+	case ast.NodeKindObjectTypeDefinition, ast.NodeKindInterfaceTypeDefinition, ast.NodeKindUnionTypeDefinition:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (v *operationCostVisitor) costDirectiveWeight(directives []int) (int, bool) {
+	directive, ok := directiveByName(v.definition, directives, literalCost)
+	if !ok {
+		return 0, false
+	}
+	value, ok := v.definition.DirectiveArgumentValueByName(directive, literalWeight)
+	if !ok {
+		return 0, false
+	}
+	switch value.Kind {
+	case ast.ValueKindInt:
+		return int(v.definition.IntValueAsInt(value.Ref)), true
+	case ast.ValueKindString:
+		// the cost specification defines the weight as a string to allow decimals
+		weight, err := strconv.ParseFloat(v.definition.StringValueContentString(value.Ref), 64)
+		if err != nil {
+			return 0, false
+		}
+		return int(math.Ceil(weight)), true
+	}
+	return 0, false
+}
+
+func (v *operationCostVisitor) listSize(field, fieldDefinition int) int {
+	directive, ok := directiveByName(v.definition, v.definition.FieldDefinitions[fieldDefinition].Directives.Refs, literalListSize)
+	if !ok {
+		return v.defaultListSize
+	}
+	if slicingArguments, ok := v.definition.DirectiveArgumentValueByName(directive, literalSlicingArguments); ok && slicingArguments.Kind == ast.ValueKindList {
+		size, found := 0, false
+		for _, ref := range v.definition.ListValues[slicingArguments.Ref].Refs {
+			argumentName := v.definition.Values[ref]
+			if argumentName.Kind != ast.ValueKindString {
+				continue
+			}
+			// Negative sizes are ignored, many subgraphs treat them as "no limit"
+			if n, ok := v.argumentIntValue(field, v.definition.StringValueContent(argumentName.Ref)); ok && n >= 0 {
+				size, found = max(size, n), true
+			}
+		}
+		if found {
+			return size
+		}
+	}
+	if assumedSize, ok := v.definition.DirectiveArgumentValueByName(directive, literalAssumedSize); ok && assumedSize.Kind == ast.ValueKindInt {
+		return int(v.definition.IntValueAsInt(assumedSize.Ref))
+	}
+	return v.defaultListSize
+}
+
+// argumentIntValue resolves the value of an integer argument of the operation field.
+// After normalization, most argument values are extracted into variables.
+func (v *operationCostVisitor) argumentIntValue(field int, name []byte) (int, bool) {
+	argument, ok := v.operation.FieldArgument(field, name)
+	if !ok {
+		return 0, false
+	}
+	value := v.operation.ArgumentValue(argument)
+	switch value.Kind {
+	case ast.ValueKindInt:
+		return int(v.operation.IntValueAsInt(value.Ref)), true
+	case ast.ValueKindVariable:
+		if v.variables == nil {
+			return 0, false
+		}
+		variable := v.variables.Get(v.operation.VariableValueNameString(value.Ref))
+		if variable == nil || variable.Type() != fastjson.TypeNumber {
+			return 0, false
+		}
+		n, err := variable.Int()
+		if err != nil {
+			return 0, false
+		}
+		return n, true
+	}
+	return 0, false
+}
+
+func directiveByName(document *ast.Document, directives []int, name []byte) (int, bool) {
+	for _, ref := range directives {
+		if string(document.DirectiveNameBytes(ref)) == string(name) {
+			return ref, true
+		}
+	}
+	return -1, false
+}
+
+func addCost(a, b int) int {
+	if a > maxOperationCost-b {
+		return maxOperationCost
+	}
+	return a + b
+}
+
+func multiplyCost(cost, multiplier int) int {
+	if multiplier <= 0 || cost <= 0 {
+		return 0
+	}
+	if cost > maxOperationCost/multiplier {
+		return maxOperationCost
+	}
+	return cost * multiplier
+}
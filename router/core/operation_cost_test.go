@@ -0,0 +1,171 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/ristretto"
+	"github.com/stretchr/testify/require"
+	"github.com/wundergraph/graphql-go-tools/v2/pkg/astparser"
+	"github.com/wundergraph/graphql-go-tools/v2/pkg/asttransform"
+)
+
+const operationCostTestSchema = `
+directive @cost(weight: String!) on FIELD_DEFINITION | OBJECT
+directive @listSize(assumedSize: Int, slicingArguments: [String!]) on FIELD_DEFINITION
+
+type Query {
+	me: User
+	users(first: Int, last: Int): [User!]! @listSize(assumedSize: 50, slicingArguments: ["first", "last"])
+	products: [Product!]!
+	search: [Product!]! @listSize(assumedSize: 5)
+	expensive: Int! @cost(weight: "25")
+}
+
+type User {
+	id: ID!
+	name: String!
+	friends: [User!]!
+}
+
+type Product @cost(weight: "3") {
+	upc: String!
+}
+`
+
+func TestCalculateOperationCost(t *testing.T) {
+	definition, report := astparser.ParseGraphqlDocumentString(operationCostTestSchema)
+	require.False(t, report.HasErrors(), report.Error())
+	require.NoError(t, asttransform.MergeDefinitionWithBaseSchema(&definition))
+
+	testCases := []struct {
+		name      string
+		operation string
+		variables string
+		expected  int
+	}{
+		{
+			name:      "scalar fields are free",
+			operation: `{ me { id name } }`,
+			expected:  1,
+		},
+		{
+			name:      "lists use the default list size",
+			operation: `{ me { friends { id } } }`,
+			expected:  1 + 10,
+		},
+		{
+			name:      "nested lists multiply",
+			operation: `{ me { friends { friends { id } } } }`,
+			expected:  1 + 10*(1+10),
+		},
+		{
+			name:      "slicing argument",
+			operation: `{ users(first: 3) { id } }`,
+			expected:  3,
+		},
+		{
+			name:      "largest slicing argument from variables",
+			operation: `query($first: Int, $last: Int) { users(first: $first, last: $last) { id } }`,
+			variables: `{"first":2,"last":7}`,
+			expected:  7,
+		},
+		{
+			name:      "negative slicing argument uses the assumed size",
+			operation: `{ users(first: -1) { id } }`,
+			expected:  50,
+		},
+		{
+			name:      "negative slicing argument from variables is ignored",
+			operation: `query($first: Int, $last: Int) { users(first: $first, last: $last) { id } }`,
+			variables: `{"first":-1,"last":7}`,
+			expected:  7,
+		},
+		{
+			name:      "assumed size without slicing argument",
+			operation: `{ users { id } }`,
+			expected:  50,
+		},
+		{
+			name:      "type weight",
+			operation: `{ search { upc } }`,
+			expected:  5 * 3,
+		},
+		{
+			name:      "field weight",
+			operation: `{ expensive products { upc } }`,
+			expected:  25 + 10*3,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			operation, report := astparser.ParseGraphqlDocumentString(tc.operation)
+			require.False(t, report.HasErrors(), report.Error())
+			if tc.variables != "" {
+				operation.Input.Variables = []byte(tc.variables)
+			}
+
+			cost, err := calculateOperationCost(&operation, &definition, 10)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, cost)
+		})
+	}
+}
+
+func TestCalculateQueryCostCacheKeyIncludesVariables(t *testing.T) {
+	definition, report := astparser.ParseGraphqlDocumentString(operationCostTestSchema)
+	require.False(t, report.HasErrors(), report.Error())
+	require.NoError(t, asttransform.MergeDefinitionWithBaseSchema(&definition))
+
+	cache, err := ristretto.NewCache[uint64, int](&ristretto.Config[uint64, int]{
+		MaxCost:     100,
+		NumCounters: 1000,
+		BufferItems: 64,
+	})
+	require.NoError(t, err)
+
+	processor := NewOperationProcessor(OperationProcessorOptions{
+		Executor: &Executor{
+			ClientSchema: &definition,
+			RouterSchema: &definition,
+		},
+		MaxOperationSizeInBytes: 10 << 20,
+		ParseKitPoolSize:        1,
+		QueryCostCache:          cache,
+	})
+
+	normalize := func(body string) *OperationKit {
+		kit, err := processor.NewKit()
+		require.NoError(t, err)
+		t.Cleanup(kit.Free)
+
+		require.NoError(t, kit.UnmarshalOperationFromBody([]byte(body)))
+		require.NoError(t, kit.Parse())
+		_, err = kit.NormalizeOperation()
+		require.NoError(t, err)
+		require.NoError(t, kit.NormalizeVariables())
+		return kit
+	}
+
+	cheap := normalize(`{"query":"query($first: Int) { users(first: $first) { id } }","variables":{"first":1}}`)
+	cacheHit, cost, err := cheap.CalculateQueryCost(1000, 10, cheap.kit.doc, &definition)
+	require.NoError(t, err)
+	require.False(t, cacheHit)
+	require.Equal(t, 1, cost)
+	cache.Wait()
+
+	cheap = normalize(`{"query":"query($first: Int) { users(first: $first) { id } }","variables":{"first":1}}`)
+	cacheHit, cost, err = cheap.CalculateQueryCost(1000, 10, cheap.kit.doc, &definition)
+	require.NoError(t, err)
+	require.True(t, cacheHit)
+	require.Equal(t, 1, cost)
+
+	// Only the variables differ, so both operations have the same ID
+	expensive := normalize(`{"query":"query($first: Int) { users(first: $first) { id } }","variables":{"first":100000}}`)
+	require.Equal(t, cheap.parsedOperation.ID, expensive.parsedOperation.ID)
+	cacheHit, cost, err = expensive.CalculateQueryCost(1000, 10, expensive.kit.doc, &definition)
+	require.EqualError(t, err, "The estimated query cost 100000 exceeds the max query cost allowed (1000)")
+	require.False(t, cacheHit)
+	require.Equal(t, 100000, cost)
+}
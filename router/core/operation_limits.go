@@ -0,0 +1,203 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/dgraph-io/ristretto"
+	"github.com/wundergraph/graphql-go-tools/v2/pkg/ast"
+	"github.com/wundergraph/graphql-go-tools/v2/pkg/lexer"
+	"github.com/wundergraph/graphql-go-tools/v2/pkg/lexer/keyword"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/wundergraph/cosmo/router/pkg/config"
+	"github.com/wundergraph/cosmo/router/pkg/otel"
+)
+
+// operationComplexity holds the size of the normalized operation. Fragments are inlined at this point,
+// so every field selected through a fragment spread is counted.
+type operationComplexity struct {
+	fieldCount     int
+	aliasCount     int
+	rootFieldCount int
+	directiveCount int
+}
+
+// complexityLimit is a single complexity limit check that is applied by the pre-handler after validation
+type complexityLimit struct {
+	name           string
+	limit          config.ComplexityLimit
+	valueAttribute attribute.Key
+	cacheAttribute attribute.Key
+	// cache returns the cache of the limit from the operation cache, it's nil if the cache is disabled
+	cache func(c *OperationCache) *ristretto.Cache[uint64, int]
+	count func(c *operationComplexity) int
+}
+
+// newComplexityLimits returns the enabled complexity limits in the order they are checked. The token count
+// isn't included, it's checked before the operation is parsed.
+func newComplexityLimits(limits config.ComplexityLimits) []complexityLimit {
+	all := []complexityLimit{
+		{
+			name:           "field count",
+			limit:          limits.FieldCount,
+			valueAttribute: otel.WgQueryFieldCount,
+			cacheAttribute: otel.WgQueryFieldCountCacheHit,
+			cache:          func(c *OperationCache) *ristretto.Cache[uint64, int] { return c.fieldCountCache },
+			count:          func(c *operationComplexity) int { return c.fieldCount },
+		},
+		{
+			name:           "alias count",
+			limit:          limits.AliasCount,
+			valueAttribute: otel.WgQueryAliasCount,
+			cacheAttribute: otel.WgQueryAliasCountCacheHit,
+			cache:          func(c *OperationCache) *ristretto.Cache[uint64, int] { return c.aliasCountCache },
+			count:          func(c *operationComplexity) int { return c.aliasCount },
+		},
+		{
+			name:           "root field count",
+			limit:          limits.RootFieldCount,
+			valueAttribute: otel.WgQueryRootFieldCount,
+			cacheAttribute: otel.WgQueryRootFieldCountCacheHit,
+			cache:          func(c *OperationCache) *ristretto.Cache[uint64, int] { return c.rootFieldCountCache },
+			count:          func(c *operationComplexity) int { return c.rootFieldCount },
+		},
+		{
+			name:           "directive count",
+			limit:          limits.DirectiveCount,
+			valueAttribute: otel.WgQueryDirectiveCount,
+			cacheAttribute: otel.WgQueryDirectiveCountCacheHit,
+			cache:          func(c *OperationCache) *ristretto.Cache[uint64, int] { return c.directiveCountCache },
+			count:          func(c *operationComplexity) int { return c.directiveCount },
+		},
+	}
+	enabled := make([]complexityLimit, 0, len(all))
+	for _, l := range all {
+		if l.limit.Enabled && l.limit.Limit > 0 {
+			enabled = append(enabled, l)
+		}
+	}
+	return enabled
+}
+
+// newComplexityLimitCache creates the cache of a complexity limit. It returns nil if the limit or the cache is disabled.
+func newComplexityLimitCache(limit config.ComplexityLimit) (*ristretto.Cache[uint64, int], error) {
+	if !limit.Enabled || limit.CacheSize <= 0 {
+		return nil, nil
+	}
+	return ristretto.NewCache[uint64, int](&ristretto.Config[uint64, int]{
+		MaxCost:     limit.CacheSize,
+		NumCounters: limit.CacheSize * 10,
+		BufferItems: 64,
+	})
+}
+
+// validateComplexityLimit validates that the counted value of the normalized operation isn't greater than the limit
+func (o *OperationKit) validateComplexityLimit(l complexityLimit) (bool, int, error) {
+	var cache *ristretto.Cache[uint64, int]
+	if o.cache != nil {
+		cache = l.cache(o.cache)
+	}
+	return o.validateOperationLimit(cache, o.parsedOperation.ID, l.name, l.limit.Limit, func() int {
+		return l.count(o.operationComplexity())
+	})
+}
+
+// ValidateTokenCount validates that the number of tokens of the operation document isn't greater than maxTokenCount.
+// It's called before the document is parsed, so that the parser never runs for oversized documents.
+func (o *OperationKit) ValidateTokenCount(maxTokenCount int) (bool, int, error) {
+	var cache *ristretto.Cache[uint64, int]
+	var cacheKey uint64
+	if o.cache != nil && o.cache.tokenCountCache != nil {
+		cache = o.cache.tokenCountCache
+		_, _ = o.kit.keyGen.WriteString(o.parsedOperation.Request.Query)
+		cacheKey = o.kit.keyGen.Sum64()
+		o.kit.keyGen.Reset()
+	}
+	return o.validateOperationLimit(cache, cacheKey, "token count", maxTokenCount, func() int {
+		return countTokens(o.parsedOperation.Request.Query)
+	})
+}
+
+// validateOperationLimit follows ValidateQueryDepth. The cache key has to identify everything the value is derived from.
+func (o *OperationKit) validateOperationLimit(cache *ristretto.Cache[uint64, int], cacheKey uint64, name string, limit int, count func() int) (bool, int, error) {
+	value, cacheHit := 0, false
+	if cache != nil {
+		value, cacheHit = cache.Get(cacheKey)
+	}
+
+	if !cacheHit {
+		value = count()
+		if cache != nil {
+			cache.Set(cacheKey, value, 1)
+		}
+	}
+
+	if value > limit {
+		return cacheHit, value, &httpGraphqlError{
+			message:    fmt.Sprintf("The %s %d exceeds the max %s allowed (%d)", name, value, name, limit),
+			statusCode: http.StatusBadRequest,
+		}
+	}
+	return cacheHit, value, nil
+}
+
+// operationComplexity walks the normalized operation once, all limits share the result
+func (o *OperationKit) operationComplexity() *operationComplexity {
+	if o.complexity != nil {
+		return o.complexity
+	}
+	o.complexity = &operationComplexity{}
+	operationRef := o.normalizedOperationDefinitionRef()
+	if operationRef == -1 {
+		return o.complexity
+	}
+	operation := o.kit.doc.OperationDefinitions[operationRef]
+	o.complexity.directiveCount += len(operation.Directives.Refs)
+	if operation.HasSelections {
+		o.complexity.countSelections(o.kit.doc, operation.SelectionSet, true)
+	}
+	return o.complexity
+}
+
+func (c *operationComplexity) countSelections(doc *ast.Document, selectionSet int, root bool) {
+	for _, selectionRef := range doc.SelectionSets[selectionSet].SelectionRefs {
+		selection := doc.Selections[selectionRef]
+		switch selection.Kind {
+		case ast.SelectionKindField:
+			field := doc.Fields[selection.Ref]
+			c.fieldCount++
+			if root {
+				c.rootFieldCount++
+			}
+			if field.Alias.IsDefined {
+				c.aliasCount++
+			}
+			c.directiveCount += len(field.Directives.Refs)
+			if field.HasSelections {
+				c.countSelections(doc, field.SelectionSet, false)
+			}
+		case ast.SelectionKindInlineFragment:
+			fragment := doc.InlineFragments[selection.Ref]
+			c.directiveCount += len(fragment.Directives.Refs)
+			if fragment.HasSelections {
+				// Fields of inline fragments on the root type are root fields as well
+				c.countSelections(doc, fragment.SelectionSet, root)
+			}
+		}
+	}
+}
+
+func countTokens(operation string) int {
+	input := &ast.Input{}
+	input.ResetInputString(operation)
+
+	l := &lexer.Lexer{}
+	l.SetInput(input)
+
+	count := 0
+	for l.Read().Keyword != keyword.EOF {
+		count++
+	}
+	return count
+}
@@ -0,0 +1,74 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wundergraph/graphql-go-tools/v2/pkg/astparser"
+)
+
+func TestOperationComplexity(t *testing.T) {
+	testCases := []struct {
+		name      string
+		operation string
+		expected  operationComplexity
+	}{
+		{
+			name:      "fields",
+			operation: `{ employee(id: 1) { id details { forename surname } } }`,
+			expected:  operationComplexity{fieldCount: 5, rootFieldCount: 1},
+		},
+		{
+			name:      "aliases and root fields",
+			operation: `{ a: employee(id: 1) { id } b: employee(id: 2) { id } employees { id } }`,
+			expected:  operationComplexity{fieldCount: 6, aliasCount: 2, rootFieldCount: 3},
+		},
+		{
+			name:      "directives",
+			operation: `query Q @a { employees @b { id @include(if: true) ... on Employee @skip(if: false) { tag } } }`,
+			expected:  operationComplexity{fieldCount: 3, rootFieldCount: 1, directiveCount: 4},
+		},
+		{
+			name:      "inline fragments on the root type",
+			operation: `{ ... on Query { employees { id } products { upc } } }`,
+			expected:  operationComplexity{fieldCount: 4, rootFieldCount: 2},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			doc, report := astparser.ParseGraphqlDocumentString(tc.operation)
+			require.False(t, report.HasErrors(), report.Error())
+
+			operation := doc.OperationDefinitions[0]
+			complexity := operationComplexity{directiveCount: len(operation.Directives.Refs)}
+			complexity.countSelections(&doc, operation.SelectionSet, true)
+			require.Equal(t, tc.expected, complexity)
+		})
+	}
+}
+
+func TestCountTokens(t *testing.T) {
+	require.Equal(t, 0, countTokens(""))
+	require.Equal(t, 3, countTokens(`{ me }`))
+	require.Equal(t, 18, countTokens(`query Q($id: Int!) { e(id: $id) }`))
+}
+
+func TestValidateTokenCountBeforeParse(t *testing.T) {
+	processor := NewOperationProcessor(OperationProcessorOptions{
+		Executor:                &Executor{},
+		MaxOperationSizeInBytes: 10 << 20,
+		ParseKitPoolSize:        1,
+	})
+	kit, err := processor.NewKit()
+	require.NoError(t, err)
+	defer kit.Free()
+
+	// The document is invalid, the limit is checked on the raw document without parsing it
+	require.NoError(t, kit.UnmarshalOperationFromBody([]byte(`{"query":"{ { { { { {"}`)))
+
+	cacheHit, tokenCount, err := kit.ValidateTokenCount(4)
+	require.False(t, cacheHit)
+	require.Equal(t, 6, tokenCount)
+	require.EqualError(t, err, "The token count 6 exceeds the max token count allowed (4)")
+}
@@ -27,6 +27,8 @@ type OperationPlanner struct {
 	planCache      ExecutionPlanCache[uint64, *planWithMetaData]
 	executor       *Executor
 	trackUsageInfo bool
+	// operationUsage is nil if the execution plan cache warmup is disabled
+	operationUsage *operationUsage
 }
 
 type ExecutionPlanCache[K any, V any] interface {
@@ -113,6 +115,9 @@ type PlanOptions struct {
 	TraceOptions         resolve.TraceOptions
 	ExecutionOptions     resolve.ExecutionOptions
 	TrackSchemaUsageInfo bool
+
+	// warmup is set for operations that are planned before the graph mux serves traffic. They aren't counted as usage.
+	warmup bool
 }
 
 func (p *OperationPlanner) plan(operation *ParsedOperation, options PlanOptions) (opContext *operationContext, err error) {
@@ -130,6 +135,9 @@ func (p *OperationPlanner) plan(operation *ParsedOperation, options PlanOptions)
 		persistedOperationCacheHit: operation.PersistedOperationCacheHit,
 		normalizationCacheHit:      operation.NormalizationCacheHit,
 		executionOptions:           options.ExecutionOptions,
+		cost:                       operation.Cost,
+		rootFields:                 operation.RootFields,
+		fieldArguments:             operation.FieldArguments,
 	}
 	if operation.IsPersistedOperation {
 		opContext.persistedID = operation.GraphQLRequestExtensions.PersistedQuery.Sha256Hash
@@ -185,6 +193,9 @@ func (p *OperationPlanner) plan(operation *ParsedOperation, options PlanOptions)
 			return nil, errors.New("unexpected prepared plan type")
 		}
 	}
+	if p.operationUsage != nil && !options.warmup {
+		p.operationUsage.track(opContext)
+	}
 	if options.TrackSchemaUsageInfo {
 		opContext.typeFieldUsageInfo = opContext.preparedPlan.typeFieldUsageInfo
 		opContext.argumentUsageInfo = opContext.preparedPlan.argumentUsageInfo
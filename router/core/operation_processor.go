@@ -3,6 +3,7 @@ package core
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
 	"fmt"
 	"github.com/wundergraph/graphql-go-tools/v2/pkg/middleware/operation_complexity"
 	"io"
@@ -56,8 +57,19 @@ type ParsedOperation struct {
 	GraphQLRequestExtensions   GraphQLRequestExtensions
 	IsPersistedOperation       bool
 	PersistedOperationCacheHit bool
+	// IsAutomaticPersistedQuery is set to true if the persisted operation was registered by a client with
+	// Automatic Persisted Queries. Such operations aren't trusted and don't skip the limits of persisted operations.
+	IsAutomaticPersistedQuery bool
 	// NormalizationCacheHit is set to true if the request is a non-persisted operation and the normalized operation was loaded from cache
 	NormalizationCacheHit bool
+	// Cost is the estimated static cost of the operation. Only available if the cost analysis is enabled.
+	Cost int
+	// RootFields are the coordinates of the root fields, e.g. Mutation.deleteEmployee.
+	// Only available if an operation rule matches on root fields.
+	RootFields []string
+	// FieldArguments are the arguments of the fields with a field policy, keyed by the coordinate of the field.
+	// Every selection of a field has its own entry. Variables are resolved, only scalar arguments are included.
+	FieldArguments map[string][]map[string]string
 }
 
 type invalidExtensionsTypeError jsonparser.ValueType
@@ -82,11 +94,19 @@ type OperationProcessorOptions struct {
 	Executor                 *Executor
 	MaxOperationSizeInBytes  int64
 	PersistedOperationClient persistedoperation.Client
+	// AutomaticPersistedQueries is nil if APQ is disabled
+	AutomaticPersistedQueries *automaticPersistedQueries
 
 	EnablePersistedOperationsCache bool
 	NormalizationCache             *ristretto.Cache[uint64, NormalizationCacheEntry]
 	ValidationCache                *ristretto.Cache[uint64, bool]
 	QueryDepthCache                *ristretto.Cache[uint64, int]
+	QueryCostCache                 *ristretto.Cache[uint64, int]
+	FieldCountCache                *ristretto.Cache[uint64, int]
+	AliasCountCache                *ristretto.Cache[uint64, int]
+	RootFieldCountCache            *ristretto.Cache[uint64, int]
+	DirectiveCountCache            *ristretto.Cache[uint64, int]
+	TokenCountCache                *ristretto.Cache[uint64, int]
 	ParseKitPoolSize               int
 }
 
@@ -96,6 +116,7 @@ type OperationProcessor struct {
 	executor                 *Executor
 	maxOperationSizeInBytes  int64
 	persistedOperationClient persistedoperation.Client
+	apq                      *automaticPersistedQueries
 	operationCache           *OperationCache
 	parseKits                map[int]*parseKit
 	parseKitSemaphore        chan int
@@ -125,6 +146,13 @@ type OperationCache struct {
 	normalizationCache *ristretto.Cache[uint64, NormalizationCacheEntry]
 	validationCache    *ristretto.Cache[uint64, bool]
 	queryDepthCache    *ristretto.Cache[uint64, int]
+	queryCostCache     *ristretto.Cache[uint64, int]
+
+	fieldCountCache     *ristretto.Cache[uint64, int]
+	aliasCountCache     *ristretto.Cache[uint64, int]
+	rootFieldCountCache *ristretto.Cache[uint64, int]
+	directiveCountCache *ristretto.Cache[uint64, int]
+	tokenCountCache     *ristretto.Cache[uint64, int]
 }
 
 // OperationKit provides methods to parse, normalize and validate operations.
@@ -137,6 +165,7 @@ type OperationKit struct {
 	operationProcessor       *OperationProcessor
 	kit                      *parseKit
 	parsedOperation          *ParsedOperation
+	complexity               *operationComplexity
 }
 
 type GraphQLRequest struct {
@@ -306,14 +335,22 @@ func (o *OperationKit) unmarshalOperation() error {
 }
 
 // FetchPersistedOperation fetches the persisted operation from the cache or the client. If the operation is fetched from the cache it returns true.
+// With Automatic Persisted Queries, an operation that is sent with its query is registered instead and operations
+// that aren't registered are looked up with the client.
 // UnmarshalOperationFromBody or UnmarshalOperationFromURL must be called before calling this method.
 func (o *OperationKit) FetchPersistedOperation(ctx context.Context, clientInfo *ClientInfo, commonTraceAttributes []attribute.KeyValue) (bool, error) {
-	if o.operationProcessor.persistedOperationClient == nil {
+	apq := o.operationProcessor.apq
+	if o.operationProcessor.persistedOperationClient == nil && apq == nil {
 		return false, &httpGraphqlError{
 			message:    "could not resolve persisted query, feature is not configured",
 			statusCode: http.StatusOK,
 		}
 	}
+	sha256Hash := o.parsedOperation.GraphQLRequestExtensions.PersistedQuery.Sha256Hash
+	if apq != nil && o.parsedOperation.Request.Query != "" {
+		o.parsedOperation.IsAutomaticPersistedQuery = true
+		return false, apq.register(ctx, sha256Hash, o.parsedOperation.Request.Query)
+	}
 	fromCache, err := o.loadPersistedOperationFromCache()
 	if err != nil {
 		return false, &httpGraphqlError{
@@ -324,8 +361,23 @@ func (o *OperationKit) FetchPersistedOperation(ctx context.Context, clientInfo *
 	if fromCache {
 		return true, nil
 	}
-	persistedOperationData, err := o.operationProcessor.persistedOperationClient.PersistedOperation(ctx, clientInfo.Name, o.parsedOperation.GraphQLRequestExtensions.PersistedQuery.Sha256Hash, commonTraceAttributes)
+	if apq != nil {
+		if query := apq.query(ctx, sha256Hash); query != nil {
+			o.parsedOperation.IsAutomaticPersistedQuery = true
+			o.parsedOperation.Request.Query = string(query)
+			return false, nil
+		}
+		if o.operationProcessor.persistedOperationClient == nil {
+			return false, &PersistedQueryNotFoundError{Sha256Hash: sha256Hash}
+		}
+	}
+	persistedOperationData, err := o.operationProcessor.persistedOperationClient.PersistedOperation(ctx, clientInfo.Name, sha256Hash, commonTraceAttributes)
 	if err != nil {
+		var poNotFoundErr *persistedoperation.PersistentOperationNotFoundError
+		if apq != nil && errors.As(err, &poNotFoundErr) {
+			// Ask the client to register the operation
+			return false, &PersistedQueryNotFoundError{Sha256Hash: sha256Hash}
+		}
 		return false, err
 	}
 	// it's important to make a copy of the persisted operation data, because it's used in the cache
@@ -597,9 +649,10 @@ func (o *OperationKit) NormalizeVariables() error {
 }
 
 type normalizedOperationCacheEntry struct {
-	operationID              uint64
-	normalizedRepresentation string
-	operationType            string
+	operationID               uint64
+	normalizedRepresentation  string
+	operationType             string
+	isAutomaticPersistedQuery bool
 }
 
 func (o *OperationKit) loadPersistedOperationFromCache() (ok bool, err error) {
@@ -623,6 +676,7 @@ func (o *OperationKit) loadPersistedOperationFromCache() (ok bool, err error) {
 	o.parsedOperation.ID = entry.operationID
 	o.parsedOperation.NormalizedRepresentation = entry.normalizedRepresentation
 	o.parsedOperation.Type = entry.operationType
+	o.parsedOperation.IsAutomaticPersistedQuery = entry.isAutomaticPersistedQuery
 	err = o.setAndParseOperationDoc()
 	if err != nil {
 		return false, err
@@ -630,6 +684,12 @@ func (o *OperationKit) loadPersistedOperationFromCache() (ok bool, err error) {
 	return true, nil
 }
 
+// isTrustedPersistedOperation returns true if the operation was loaded from the persisted operation storage.
+// Only these operations skip the limits that can be turned off for persisted operations.
+func (o *OperationKit) isTrustedPersistedOperation() bool {
+	return o.parsedOperation.IsPersistedOperation && !o.parsedOperation.IsAutomaticPersistedQuery
+}
+
 func (o *OperationKit) jsonIsNull(variables []byte) bool {
 	if variables == nil {
 		return true
@@ -647,9 +707,10 @@ func (o *OperationKit) jsonIsNull(variables []byte) bool {
 func (o *OperationKit) savePersistedOperationToCache(skipIncludeVariableNames []string) {
 	cacheKey := o.generatePersistedOperationCacheKey(skipIncludeVariableNames)
 	entry := normalizedOperationCacheEntry{
-		operationID:              o.parsedOperation.ID,
-		normalizedRepresentation: o.parsedOperation.NormalizedRepresentation,
-		operationType:            o.parsedOperation.Type,
+		operationID:               o.parsedOperation.ID,
+		normalizedRepresentation:  o.parsedOperation.NormalizedRepresentation,
+		operationType:             o.parsedOperation.Type,
+		isAutomaticPersistedQuery: o.parsedOperation.IsAutomaticPersistedQuery,
 	}
 
 	o.cache.persistedOperationCacheLock.Lock()
@@ -776,6 +837,108 @@ func (o *OperationKit) ValidateQueryDepth(maxQueryDepth int, operation, definiti
 	return false, globalComplexityResult.Depth, nil
 }
 
+// CalculateQueryCost estimates the cost of the operation and stores it on the ParsedOperation.
+// If maxQueryCost is greater than 0, operations exceeding it are rejected.
+func (o *OperationKit) CalculateQueryCost(maxQueryCost, defaultListSize int, operation, definition *ast.Document) (bool, int, error) {
+	cost, cacheHit := 0, false
+	var cacheKey uint64
+	if o.cache != nil && o.cache.queryCostCache != nil {
+		cacheKey = o.queryCostCacheKey(operation)
+		cost, cacheHit = o.cache.queryCostCache.Get(cacheKey)
+	}
+
+	if !cacheHit {
+		var err error
+		cost, err = calculateOperationCost(operation, definition, defaultListSize)
+		if err != nil {
+			return false, 0, err
+		}
+		if o.cache != nil && o.cache.queryCostCache != nil {
+			o.cache.queryCostCache.Set(cacheKey, cost, 1)
+		}
+	}
+
+	o.parsedOperation.Cost = cost
+
+	if maxQueryCost > 0 && cost > maxQueryCost {
+		return cacheHit, cost, &httpGraphqlError{
+			message:    fmt.Sprintf("The estimated query cost %d exceeds the max query cost allowed (%d)", cost, maxQueryCost),
+			statusCode: http.StatusBadRequest,
+		}
+	}
+	return cacheHit, cost, nil
+}
+
+// queryCostCacheKey includes the variables because slicing arguments are read from them. Operations that
+// differ only in their variables share the same ID.
+func (o *OperationKit) queryCostCacheKey(operation *ast.Document) uint64 {
+	var id [8]byte
+	binary.LittleEndian.PutUint64(id[:], o.parsedOperation.ID)
+	_, _ = o.kit.keyGen.Write(id[:])
+	_, _ = o.kit.keyGen.Write(operation.Input.Variables)
+	sum := o.kit.keyGen.Sum64()
+	o.kit.keyGen.Reset()
+	return sum
+}
+
+// CollectRootFields stores the coordinates of the root fields of the normalized operation on the ParsedOperation,
+// e.g. Mutation.deleteEmployee. Fields of inline fragments on the root type are included.
+func (o *OperationKit) CollectRootFields(definition *ast.Document) {
+	operationRef := o.normalizedOperationDefinitionRef()
+	if operationRef == -1 {
+		return
+	}
+	rootTypeName := o.rootTypeName(definition, operationRef)
+	o.parsedOperation.RootFields = o.appendRootFields(nil, rootTypeName, o.kit.doc.OperationDefinitions[operationRef].SelectionSet)
+}
+
+func (o *OperationKit) rootTypeName(definition *ast.Document, operationRef int) string {
+	switch o.kit.doc.OperationDefinitions[operationRef].OperationType {
+	case ast.OperationTypeQuery:
+		return string(definition.Index.QueryTypeName)
+	case ast.OperationTypeMutation:
+		return string(definition.Index.MutationTypeName)
+	case ast.OperationTypeSubscription:
+		return string(definition.Index.SubscriptionTypeName)
+	}
+	return ""
+}
+
+func (o *OperationKit) appendRootFields(rootFields []string, rootTypeName string, selectionSet int) []string {
+	if selectionSet == -1 {
+		return rootFields
+	}
+	for _, selectionRef := range o.kit.doc.SelectionSets[selectionSet].SelectionRefs {
+		selection := o.kit.doc.Selections[selectionRef]
+		switch selection.Kind {
+		case ast.SelectionKindField:
+			coordinate := rootTypeName + "." + o.kit.doc.FieldNameString(selection.Ref)
+			if !slices.Contains(rootFields, coordinate) {
+				rootFields = append(rootFields, coordinate)
+			}
+		case ast.SelectionKindInlineFragment:
+			rootFields = o.appendRootFields(rootFields, rootTypeName, o.kit.doc.InlineFragments[selection.Ref].SelectionSet)
+		}
+	}
+	return rootFields
+}
+
+// normalizedOperationDefinitionRef returns the operation of the normalized document. The document only contains
+// a single operation if it was parsed from the normalized representation of a cache entry.
+func (o *OperationKit) normalizedOperationDefinitionRef() int {
+	operationRef, operationCount := -1, 0
+	for i := range o.kit.doc.RootNodes {
+		if o.kit.doc.RootNodes[i].Kind == ast.NodeKindOperationDefinition {
+			operationRef = o.kit.doc.RootNodes[i].Ref
+			operationCount++
+		}
+	}
+	if operationCount > 1 {
+		return o.operationDefinitionRef
+	}
+	return operationRef
+}
+
 var (
 	literalIF = []byte("if")
 )
@@ -836,6 +999,7 @@ func NewOperationProcessor(opts OperationProcessorOptions) *OperationProcessor {
 		executor:                 opts.Executor,
 		maxOperationSizeInBytes:  opts.MaxOperationSizeInBytes,
 		persistedOperationClient: opts.PersistedOperationClient,
+		apq:                      opts.AutomaticPersistedQueries,
 		parseKits:                make(map[int]*parseKit, opts.ParseKitPoolSize),
 		parseKitSemaphore:        make(chan int, opts.ParseKitPoolSize),
 	}
@@ -852,26 +1016,43 @@ func NewOperationProcessor(opts OperationProcessorOptions) *OperationProcessor {
 		}
 	}
 	if opts.NormalizationCache != nil {
-		if processor.operationCache == nil {
-			processor.operationCache = &OperationCache{}
-		}
-		processor.operationCache.normalizationCache = opts.NormalizationCache
+		processor.cache().normalizationCache = opts.NormalizationCache
 	}
 	if opts.ValidationCache != nil {
-		if processor.operationCache == nil {
-			processor.operationCache = &OperationCache{}
-		}
-		processor.operationCache.validationCache = opts.ValidationCache
+		processor.cache().validationCache = opts.ValidationCache
 	}
 	if opts.QueryDepthCache != nil {
-		if processor.operationCache == nil {
-			processor.operationCache = &OperationCache{}
-		}
-		processor.operationCache.queryDepthCache = opts.QueryDepthCache
+		processor.cache().queryDepthCache = opts.QueryDepthCache
+	}
+	if opts.QueryCostCache != nil {
+		processor.cache().queryCostCache = opts.QueryCostCache
+	}
+	if opts.FieldCountCache != nil {
+		processor.cache().fieldCountCache = opts.FieldCountCache
+	}
+	if opts.AliasCountCache != nil {
+		processor.cache().aliasCountCache = opts.AliasCountCache
+	}
+	if opts.RootFieldCountCache != nil {
+		processor.cache().rootFieldCountCache = opts.RootFieldCountCache
+	}
+	if opts.DirectiveCountCache != nil {
+		processor.cache().directiveCountCache = opts.DirectiveCountCache
+	}
+	if opts.TokenCountCache != nil {
+		processor.cache().tokenCountCache = opts.TokenCountCache
 	}
 	return processor
 }
 
+// cache returns the operation cache and creates it if the first cache is configured
+func (p *OperationProcessor) cache() *OperationCache {
+	if p.operationCache == nil {
+		p.operationCache = &OperationCache{}
+	}
+	return p.operationCache
+}
+
 func (p *OperationProcessor) getKit() *parseKit {
 	i := <-p.parseKitSemaphore
 	return p.parseKits[i]
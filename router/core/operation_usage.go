@@ -0,0 +1,89 @@
+package core
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// operationUsage counts how often the operations of a graph mux are planned or served from the execution plan cache.
+// The most used operations are planned by the graph mux of the next execution config before it serves traffic.
+// Only the first limit operations are tracked, later operations are ignored until the next config reload.
+type operationUsage struct {
+	limit      int64
+	size       atomic.Int64
+	operations sync.Map // map[uint64]*operationUsageEntry
+}
+
+type operationUsageEntry struct {
+	operation cacheWarmupOperation
+	count     atomic.Uint64
+}
+
+// operationUsageCount is a snapshot of the usage of an operation
+type operationUsageCount struct {
+	// ID is the hash of the normalized operation
+	ID        uint64
+	Operation cacheWarmupOperation
+	Count     uint64
+}
+
+// newOperationUsage creates a tracker for up to limit operations. The counts of the previous
+// execution config are carried over at half of their value, so that operations which are no
+// longer used are dropped after a few config reloads.
+func newOperationUsage(limit int64, previous []operationUsageCount) *operationUsage {
+	u := &operationUsage{
+		limit: limit,
+	}
+	for _, usage := range previous {
+		if usage.Count/2 == 0 || u.size.Load() >= u.limit {
+			continue
+		}
+		entry := &operationUsageEntry{operation: usage.Operation}
+		entry.count.Store(usage.Count / 2)
+		u.operations.Store(usage.ID, entry)
+		u.size.Add(1)
+	}
+	return u
+}
+
+func (u *operationUsage) track(opContext *operationContext) {
+	if entry, ok := u.operations.Load(opContext.hash); ok {
+		entry.(*operationUsageEntry).count.Add(1)
+		return
+	}
+	if u.size.Load() >= u.limit {
+		return
+	}
+	entry, loaded := u.operations.LoadOrStore(opContext.hash, &operationUsageEntry{
+		operation: cacheWarmupOperation{
+			Query:         opContext.content,
+			OperationName: opContext.name,
+		},
+	})
+	if !loaded {
+		u.size.Add(1)
+	}
+	entry.(*operationUsageEntry).count.Add(1)
+}
+
+// top returns the n most used operations, the most used operation first
+func (u *operationUsage) top(n int) []operationUsageCount {
+	counts := make([]operationUsageCount, 0, u.size.Load())
+	u.operations.Range(func(key, value any) bool {
+		entry := value.(*operationUsageEntry)
+		counts = append(counts, operationUsageCount{
+			ID:        key.(uint64),
+			Operation: entry.operation,
+			Count:     entry.count.Load(),
+		})
+		return true
+	})
+	sort.Slice(counts, func(i, j int) bool {
+		return counts[i].Count > counts[j].Count
+	})
+	if len(counts) > n {
+		counts = counts[:n]
+	}
+	return counts
+}
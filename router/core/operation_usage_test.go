@@ -0,0 +1,53 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOperationUsage(t *testing.T) {
+	t.Run("returns the most used operations first", func(t *testing.T) {
+		u := newOperationUsage(10, nil)
+		a := &operationContext{hash: 1, name: "A", content: "query A { a }"}
+		b := &operationContext{hash: 2, name: "B", content: "query B { b }"}
+		c := &operationContext{hash: 3, name: "C", content: "query C { c }"}
+
+		u.track(a)
+		u.track(b)
+		u.track(b)
+		u.track(b)
+		u.track(c)
+		u.track(c)
+
+		require.Equal(t, []operationUsageCount{
+			{ID: 2, Operation: cacheWarmupOperation{Query: "query B { b }", OperationName: "B"}, Count: 3},
+			{ID: 3, Operation: cacheWarmupOperation{Query: "query C { c }", OperationName: "C"}, Count: 2},
+		}, u.top(2))
+	})
+
+	t.Run("ignores new operations above the limit", func(t *testing.T) {
+		u := newOperationUsage(1, nil)
+
+		u.track(&operationContext{hash: 1, content: "{ a }"})
+		u.track(&operationContext{hash: 2, content: "{ b }"})
+		u.track(&operationContext{hash: 1, content: "{ a }"})
+
+		require.Equal(t, []operationUsageCount{
+			{ID: 1, Operation: cacheWarmupOperation{Query: "{ a }"}, Count: 2},
+		}, u.top(10))
+	})
+
+	t.Run("carries over half of the previous counts", func(t *testing.T) {
+		u := newOperationUsage(10, []operationUsageCount{
+			{ID: 1, Operation: cacheWarmupOperation{Query: "{ a }"}, Count: 8},
+			{ID: 2, Operation: cacheWarmupOperation{Query: "{ b }"}, Count: 1},
+		})
+
+		u.track(&operationContext{hash: 1, content: "{ a }"})
+
+		require.Equal(t, []operationUsageCount{
+			{ID: 1, Operation: cacheWarmupOperation{Query: "{ a }"}, Count: 5},
+		}, u.top(10))
+	})
+}
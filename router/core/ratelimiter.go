@@ -4,54 +4,145 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"sync"
 
 	"github.com/go-redis/redis_rate/v10"
 	"github.com/redis/go-redis/v9"
 	"github.com/wundergraph/graphql-go-tools/v2/pkg/engine/resolve"
+	"go.uber.org/zap"
+
+	"github.com/wundergraph/cosmo/router/pkg/config"
 )
 
 var (
 	ErrRateLimitExceeded = errors.New("rate limit exceeded")
 )
 
+const (
+	RateLimitBackendRedis  = "redis"
+	RateLimitBackendMemory = "memory"
+
+	RateLimitFailureModeOpen   = "open"
+	RateLimitFailureModeClosed = "closed"
+)
+
 type CosmoRateLimiterOptions struct {
-	RedisClient *redis.Client
+	RedisClient redis.UniversalClient
+	// MemoryStorage keeps the rate limit state in process. It is used if no RedisClient is set
+	MemoryStorage *MemoryRateLimitStorage
+	// FailureMode decides if fetches are allowed or denied when the storage is unreachable, defaults to RateLimitFailureModeOpen
+	FailureMode string
+	Logger      *zap.Logger
 	Debug       bool
+	// Strategy is the algorithm used to enforce the limits, defaults to RateLimitStrategySimple
+	Strategy string
+	// DefaultLimit is the limit of requests without keys and the fallback for the limits of the keys
+	DefaultLimit redis_rate.Limit
+	// Keys partition the rate limit by values of the request
+	Keys []config.RateLimitKey
 }
 
-func NewCosmoRateLimiter(opts *CosmoRateLimiterOptions) *CosmoRateLimiter {
-	limiter := redis_rate.NewLimiter(opts.RedisClient)
-	return &CosmoRateLimiter{
-		client:  opts.RedisClient,
-		limiter: limiter,
-		debug:   opts.Debug,
+func NewCosmoRateLimiter(opts *CosmoRateLimiterOptions) (*CosmoRateLimiter, error) {
+	var limiter rateLimitStrategy
+	switch {
+	case opts.RedisClient != nil:
+		redisLimiter, err := newRateLimitStrategy(opts.Strategy, opts.RedisClient)
+		if err != nil {
+			return nil, err
+		}
+		limiter = redisLimiter
+	case opts.MemoryStorage != nil:
+		limiter = opts.MemoryStorage
+	default:
+		return nil, errors.New("rate limiter requires either a redis client or a memory storage")
+	}
+	switch opts.FailureMode {
+	case RateLimitFailureModeOpen, RateLimitFailureModeClosed:
+	case "":
+		opts.FailureMode = RateLimitFailureModeOpen
+	default:
+		return nil, fmt.Errorf("unknown rate limit failure mode: %s", opts.FailureMode)
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if err := validateRateLimit(opts.DefaultLimit, opts.Strategy); err != nil {
+		return nil, fmt.Errorf("invalid default rate limit: %w", err)
 	}
+	keys := make([]rateLimitKeyDefinition, 0, len(opts.Keys))
+	for _, key := range opts.Keys {
+		definition, err := newRateLimitKeyDefinition(key)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateRateLimit(mergeRateLimit(definition.limit, opts.DefaultLimit), opts.Strategy); err != nil {
+			return nil, fmt.Errorf("invalid rate limit of key '%s': %w", key.Expression, err)
+		}
+		keys = append(keys, definition)
+	}
+	return &CosmoRateLimiter{
+		client:      opts.RedisClient,
+		limiter:     limiter,
+		failureMode: opts.FailureMode,
+		logger:      logger,
+		debug:       opts.Debug,
+		keys:        keys,
+	}, nil
 }
 
 type CosmoRateLimiter struct {
-	client  *redis.Client
-	limiter *redis_rate.Limiter
-	debug   bool
+	client      redis.UniversalClient
+	limiter     rateLimitStrategy
+	failureMode string
+	logger      *zap.Logger
+	debug       bool
+	keys        []rateLimitKeyDefinition
 }
 
 func (c *CosmoRateLimiter) RateLimitPreFetch(ctx *resolve.Context, info *resolve.FetchInfo, input json.RawMessage) (result *resolve.RateLimitDeny, err error) {
 	if c.isIntrospectionQuery(info.RootFields) {
 		return nil, nil
 	}
+	statsCtx := c.getRateLimitStatsCtx(ctx)
 	requestRate := c.calculateRate()
-	limit := redis_rate.Limit{
-		Rate:   ctx.RateLimitOptions.Rate,
-		Burst:  ctx.RateLimitOptions.Burst,
-		Period: ctx.RateLimitOptions.Period,
+	if statsCtx != nil && statsCtx.operationCost > 0 {
+		requestRate = statsCtx.operationCost
 	}
-	allow, err := c.limiter.AllowN(ctx.Context(), ctx.RateLimitOptions.RateLimitKey, limit, requestRate)
-	if err != nil {
-		return nil, err
+
+	var (
+		allowed = true
+		charged bool
+		stats   RateLimitStats
+	)
+	// The fetch must be allowed by all keys, the stats reflect the most restrictive one.
+	// The keys after a denying key aren't charged, so that a denied fetch doesn't use up the global budget.
+	for _, key := range c.fetchKeys(ctx, statsCtx, info) {
+		keyAllowed, allow, err := c.allowKey(ctx, statsCtx, key, requestRate)
+		if err != nil {
+			if keyAllowed, err = c.storageFailure(err); err != nil {
+				return nil, err
+			}
+		}
+		allowed = allowed && keyAllowed
+		if allow != nil {
+			if !charged || allow.Remaining < stats.Remaining {
+				stats.Remaining = allow.Remaining
+			}
+			stats.RetryAfterMilliseconds = max(stats.RetryAfterMilliseconds, allow.RetryAfter.Milliseconds())
+			stats.ResetAfterMilliseconds = max(stats.ResetAfterMilliseconds, allow.ResetAfter.Milliseconds())
+			charged = true
+		}
+		if !allowed {
+			break
+		}
 	}
-	c.setRateLimitStats(ctx, requestRate, allow.Remaining, allow.RetryAfter.Milliseconds(), allow.ResetAfter.Milliseconds())
-	if allow.Allowed >= requestRate {
+	if charged {
+		c.setRateLimitStats(ctx, requestRate, stats.Remaining, stats.RetryAfterMilliseconds, stats.ResetAfterMilliseconds)
+	}
+	if allowed {
 		return nil, nil
 	}
 	if ctx.RateLimitOptions.RejectExceedingRequests {
@@ -60,11 +151,101 @@ func (c *CosmoRateLimiter) RateLimitPreFetch(ctx *resolve.Context, info *resolve
 	return &resolve.RateLimitDeny{}, nil
 }
 
+// fetchKeys returns the keys the fetch is limited by. The values of the keys are sent by the client, so the
+// resolved keys only add limits and the global limit always applies. It is the last key.
+func (c *CosmoRateLimiter) fetchKeys(ctx *resolve.Context, statsCtx *rateLimitStatsCtx, info *resolve.FetchInfo) []rateLimitKey {
+	global := redis_rate.Limit{
+		Rate:   ctx.RateLimitOptions.Rate,
+		Burst:  ctx.RateLimitOptions.Burst,
+		Period: ctx.RateLimitOptions.Period,
+	}
+	globalKey := rateLimitKey{key: ctx.RateLimitOptions.RateLimitKey, limit: global}
+	if statsCtx == nil || len(statsCtx.keys) == 0 {
+		return []rateLimitKey{globalKey}
+	}
+	keys := make([]rateLimitKey, len(statsCtx.keys), len(statsCtx.keys)+1)
+	for i, key := range statsCtx.keys {
+		keys[i] = key
+		keys[i].key = ctx.RateLimitOptions.RateLimitKey + ":" + key.key
+		if key.perSubgraph {
+			keys[i].key += ":" + info.DataSourceID
+		}
+		keys[i].limit = mergeRateLimit(key.limit, global)
+	}
+	return append(keys, globalKey)
+}
+
+// mergeRateLimit fills the values that aren't set on the limit of a key with the default limit
+func mergeRateLimit(limit, defaultLimit redis_rate.Limit) redis_rate.Limit {
+	if limit.Rate == 0 {
+		limit.Rate = defaultLimit.Rate
+	}
+	if limit.Burst == 0 {
+		limit.Burst = defaultLimit.Burst
+	}
+	if limit.Period == 0 {
+		limit.Period = defaultLimit.Period
+	}
+	return limit
+}
+
+// validateRateLimit rejects limits that would deny every request or can't be computed. The sliding window
+// strategy doesn't use the burst.
+func validateRateLimit(limit redis_rate.Limit, strategy string) error {
+	if limit.Rate <= 0 {
+		return fmt.Errorf("rate must be greater than 0, got %d", limit.Rate)
+	}
+	if limit.Period <= 0 {
+		return fmt.Errorf("period must be greater than 0, got %s", limit.Period)
+	}
+	if limit.Burst <= 0 && strategy != RateLimitStrategySlidingWindow {
+		return fmt.Errorf("burst must be greater than 0, got %d", limit.Burst)
+	}
+	return nil
+}
+
+// allowKey charges the request rate to the key. If the operation cost is used as the rate, the cost covers the
+// whole operation, so it is charged only once per key and all fetches share the decision of the first one.
+// The returned result is nil if the decision was reused.
+func (c *CosmoRateLimiter) allowKey(ctx *resolve.Context, statsCtx *rateLimitStatsCtx, key rateLimitKey, requestRate int) (bool, *redis_rate.Result, error) {
+	if statsCtx == nil || statsCtx.operationCost == 0 {
+		allow, err := c.limiter.AllowN(ctx.Context(), key.key, key.limit, requestRate)
+		if err != nil {
+			return false, nil, err
+		}
+		return allow.Allowed >= requestRate, allow, nil
+	}
+	var (
+		decision = statsCtx.decision(key.key)
+		allow    *redis_rate.Result
+	)
+	decision.once.Do(func() {
+		allow, decision.err = c.limiter.AllowN(ctx.Context(), key.key, key.limit, requestRate)
+		decision.allowed = decision.err == nil && allow.Allowed >= requestRate
+	})
+	return decision.allowed, allow, decision.err
+}
+
+// storageFailure applies the failure mode if the rate limit storage is unreachable.
+// Canceled requests and invalid limits are not treated as a storage failure.
+func (c *CosmoRateLimiter) storageFailure(err error) (bool, error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, errInvalidRateLimit) {
+		return false, err
+	}
+	c.logger.Warn("Rate limit storage unavailable, applying failure mode",
+		zap.String("failure_mode", c.failureMode),
+		zap.Error(err),
+	)
+	return c.failureMode == RateLimitFailureModeOpen, nil
+}
+
 type RateLimitStats struct {
 	RequestRate            int   `json:"requestRate"`
 	Remaining              int   `json:"remaining"`
 	RetryAfterMilliseconds int64 `json:"retryAfterMs"`
 	ResetAfterMilliseconds int64 `json:"resetAfterMs"`
+	// OperationCost is the estimated cost of the operation, only set if the cost analysis is enabled
+	OperationCost int `json:"operationCost,omitempty"`
 }
 
 func (c *CosmoRateLimiter) RenderResponseExtension(ctx *resolve.Context, out io.Writer) error {
@@ -103,11 +284,10 @@ func (c *CosmoRateLimiter) statsJSON(ctx *resolve.Context) ([]byte, error) {
 }
 
 func (c *CosmoRateLimiter) setRateLimitStats(ctx *resolve.Context, requestRate, remaining int, retryAfter, resetAfter int64) {
-	v := ctx.Context().Value(rateLimitStatsCtxKey{})
-	if v == nil {
+	statsCtx := c.getRateLimitStatsCtx(ctx)
+	if statsCtx == nil {
 		return
 	}
-	statsCtx := v.(*rateLimitStatsCtx)
 	statsCtx.mux.Lock()
 	statsCtx.stats.RequestRate = statsCtx.stats.RequestRate + requestRate
 	statsCtx.stats.Remaining = remaining
@@ -117,23 +297,66 @@ func (c *CosmoRateLimiter) setRateLimitStats(ctx *resolve.Context, requestRate,
 }
 
 func (c *CosmoRateLimiter) getRateLimitStats(ctx *resolve.Context) RateLimitStats {
-	v := ctx.Context().Value(rateLimitStatsCtxKey{})
-	if v == nil {
+	statsCtx := c.getRateLimitStatsCtx(ctx)
+	if statsCtx == nil {
 		return RateLimitStats{}
 	}
-	statsCtx := v.(*rateLimitStatsCtx)
+	statsCtx.mux.Lock()
+	defer statsCtx.mux.Unlock()
 	return statsCtx.stats
 }
 
+func (c *CosmoRateLimiter) getRateLimitStatsCtx(ctx *resolve.Context) *rateLimitStatsCtx {
+	v := ctx.Context().Value(rateLimitStatsCtxKey{})
+	if v == nil {
+		return nil
+	}
+	return v.(*rateLimitStatsCtx)
+}
+
 type rateLimitStatsCtx struct {
 	stats RateLimitStats
 	mux   sync.Mutex
+
+	// keys are the rate limit keys resolved for the request
+	keys []rateLimitKey
+	// operationCost is used as the request rate instead of the per fetch rate if greater than 0
+	operationCost int
+	decisions     map[string]*rateLimitDecision
+}
+
+type rateLimitDecision struct {
+	once    sync.Once
+	allowed bool
+	err     error
+}
+
+func (s *rateLimitStatsCtx) decision(key string) *rateLimitDecision {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.decisions == nil {
+		s.decisions = make(map[string]*rateLimitDecision)
+	}
+	decision, ok := s.decisions[key]
+	if !ok {
+		decision = &rateLimitDecision{}
+		s.decisions[key] = decision
+	}
+	return decision
 }
 
 type rateLimitStatsCtxKey struct{}
 
 func WithRateLimiterStats(ctx *resolve.Context) *resolve.Context {
-	stats := &rateLimitStatsCtx{}
+	return withRateLimiterStats(ctx, nil, 0)
+}
+
+func withRateLimiterStats(ctx *resolve.Context, keys []rateLimitKey, operationCost int) *resolve.Context {
+	stats := &rateLimitStatsCtx{
+		stats:         RateLimitStats{OperationCost: operationCost},
+		keys:          keys,
+		operationCost: operationCost,
+	}
 	withStats := context.WithValue(ctx.Context(), rateLimitStatsCtxKey{}, stats)
 	return ctx.WithContext(withStats)
 }
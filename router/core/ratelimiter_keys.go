@@ -0,0 +1,132 @@
+package core
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/go-redis/redis_rate/v10"
+	"go.uber.org/zap"
+
+	"github.com/wundergraph/cosmo/router/pkg/config"
+)
+
+const (
+	rateLimitKeySourceClientName    = "client_name"
+	rateLimitKeySourceClientVersion = "client_version"
+	rateLimitKeySourceIP            = "ip"
+	rateLimitKeySourceOperationName = "operation_name"
+	rateLimitKeySourceClaim         = "claim"
+	rateLimitKeySourceHeader        = "header"
+)
+
+// rateLimitKeyDefinition is the parsed form of a configured rate limit key
+type rateLimitKeyDefinition struct {
+	expression  string
+	source      string
+	name        string
+	limit       redis_rate.Limit
+	perSubgraph bool
+}
+
+// rateLimitKey is a rate limit key resolved for a request
+type rateLimitKey struct {
+	key         string
+	limit       redis_rate.Limit
+	perSubgraph bool
+}
+
+func newRateLimitKeyDefinition(key config.RateLimitKey) (rateLimitKeyDefinition, error) {
+	definition := rateLimitKeyDefinition{
+		expression: key.Expression,
+		limit: redis_rate.Limit{
+			Rate:   key.Rate,
+			Burst:  key.Burst,
+			Period: key.Period,
+		},
+		perSubgraph: key.PerSubgraph,
+	}
+	source, name, _ := strings.Cut(key.Expression, ":")
+	switch source {
+	case rateLimitKeySourceClientName, rateLimitKeySourceClientVersion, rateLimitKeySourceIP, rateLimitKeySourceOperationName:
+		if name != "" {
+			return definition, fmt.Errorf("invalid rate limit key expression '%s': %s does not accept a name", key.Expression, source)
+		}
+	case rateLimitKeySourceClaim, rateLimitKeySourceHeader:
+		if name == "" {
+			return definition, fmt.Errorf("invalid rate limit key expression '%s': %s requires a name", key.Expression, source)
+		}
+	default:
+		return definition, fmt.Errorf("invalid rate limit key expression '%s'", key.Expression)
+	}
+	definition.source = source
+	definition.name = name
+	return definition, nil
+}
+
+// resolveKeys builds the rate limit keys of the request. Keys without a value for the request are skipped.
+// The client ip is the remote address rewritten by the RealIP middleware from the forwarded headers.
+func (c *CosmoRateLimiter) resolveKeys(reqCtx *requestContext) []rateLimitKey {
+	if len(c.keys) == 0 || reqCtx == nil {
+		return nil
+	}
+	keys := make([]rateLimitKey, 0, len(c.keys))
+	for _, definition := range c.keys {
+		value := definition.value(reqCtx)
+		if value == "" {
+			c.logger.Debug("Rate limit key has no value for the request, skipping key",
+				zap.String("expression", definition.expression),
+			)
+			continue
+		}
+		keys = append(keys, rateLimitKey{
+			key:         definition.expression + ":" + value,
+			limit:       definition.limit,
+			perSubgraph: definition.perSubgraph,
+		})
+	}
+	if len(keys) == 0 {
+		c.logger.Debug("No rate limit key has a value for the request, only the global rate limit applies")
+	}
+	return keys
+}
+
+func (d rateLimitKeyDefinition) value(reqCtx *requestContext) string {
+	switch d.source {
+	case rateLimitKeySourceClientName:
+		if reqCtx.operation != nil {
+			return reqCtx.operation.clientInfo.Name
+		}
+	case rateLimitKeySourceClientVersion:
+		if reqCtx.operation != nil {
+			return reqCtx.operation.clientInfo.Version
+		}
+	case rateLimitKeySourceOperationName:
+		if reqCtx.operation != nil {
+			return reqCtx.operation.name
+		}
+	case rateLimitKeySourceIP:
+		if reqCtx.request == nil {
+			return ""
+		}
+		host, _, err := net.SplitHostPort(reqCtx.request.RemoteAddr)
+		if err != nil {
+			return reqCtx.request.RemoteAddr
+		}
+		return host
+	case rateLimitKeySourceHeader:
+		if reqCtx.request != nil {
+			return reqCtx.request.Header.Get(d.name)
+		}
+	case rateLimitKeySourceClaim:
+		if reqCtx.request == nil {
+			return ""
+		}
+		auth := reqCtx.Authentication()
+		if auth == nil {
+			return ""
+		}
+		return claimValue(auth.Claims(), d.name)
+	}
+	return ""
+}
@@ -0,0 +1,195 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis_rate/v10"
+)
+
+// errInvalidRateLimit is returned for limits that weren't validated when the rate limiter was created.
+// It's a configuration error and not a storage failure.
+var errInvalidRateLimit = errors.New("invalid rate limit")
+
+// memoryRateLimitSweepInterval is the number of calls after which expired keys are removed
+const memoryRateLimitSweepInterval = 1024
+
+// MemoryRateLimitStorage is an in-process alternative to Redis for single instance deployments.
+// It implements the same strategies as the Redis backend but the state is not shared between router instances.
+// The storage must be shared by all graph muxes, so that a config reload doesn't reset the limits.
+type MemoryRateLimitStorage struct {
+	mu       sync.Mutex
+	strategy string
+	entries  map[string]*memoryRateLimitEntry
+	calls    int
+	now      func() time.Time
+}
+
+type memoryRateLimitEntry struct {
+	expiresAt time.Time
+
+	// tat is the theoretical arrival time of the GCRA (simple) strategy
+	tat time.Time
+
+	// window, current and previous are the counters of the sliding window strategy
+	window   int64
+	current  int
+	previous int
+
+	// tokens and refilledAt are the state of the token bucket strategy
+	tokens     float64
+	refilledAt time.Time
+}
+
+func NewMemoryRateLimitStorage(strategy string) (*MemoryRateLimitStorage, error) {
+	switch strategy {
+	case RateLimitStrategySimple, RateLimitStrategySlidingWindow, RateLimitStrategyTokenBucket:
+	case "":
+		strategy = RateLimitStrategySimple
+	default:
+		return nil, fmt.Errorf("unknown rate limit strategy: %s", strategy)
+	}
+	return &MemoryRateLimitStorage{
+		strategy: strategy,
+		entries:  make(map[string]*memoryRateLimitEntry),
+		now:      time.Now,
+	}, nil
+}
+
+func (m *MemoryRateLimitStorage) AllowN(_ context.Context, key string, limit redis_rate.Limit, n int) (*redis_rate.Result, error) {
+	if limit.Rate <= 0 || limit.Period <= 0 {
+		return nil, fmt.Errorf("%w: %s", errInvalidRateLimit, limit)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	entry, ok := m.entries[key]
+	if !ok || !now.Before(entry.expiresAt) {
+		entry = &memoryRateLimitEntry{}
+		m.entries[key] = entry
+	}
+
+	switch m.strategy {
+	case RateLimitStrategySlidingWindow:
+		return entry.allowSlidingWindow(now, limit, n), nil
+	case RateLimitStrategyTokenBucket:
+		return entry.allowTokenBucket(now, limit, n), nil
+	default:
+		return entry.allowGCRA(now, limit, n), nil
+	}
+}
+
+func (m *MemoryRateLimitStorage) sweep(now time.Time) {
+	m.calls++
+	if m.calls < memoryRateLimitSweepInterval {
+		return
+	}
+	m.calls = 0
+	for key, entry := range m.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(m.entries, key)
+		}
+	}
+}
+
+// allowGCRA follows the implementation of redis_rate
+func (e *memoryRateLimitEntry) allowGCRA(now time.Time, limit redis_rate.Limit, n int) *redis_rate.Result {
+	emissionInterval := limit.Period / time.Duration(limit.Rate)
+	burstOffset := emissionInterval * time.Duration(limit.Burst)
+
+	tat := e.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	newTat := tat.Add(emissionInterval * time.Duration(n))
+	diff := now.Sub(newTat.Add(-burstOffset))
+	remaining := diff / emissionInterval
+
+	result := &redis_rate.Result{Limit: limit}
+	if remaining < 0 {
+		result.ResetAfter = tat.Sub(now)
+		result.RetryAfter = -diff
+		if n > limit.Burst {
+			result.RetryAfter = -1
+		}
+		return result
+	}
+	e.tat = newTat
+	e.expiresAt = newTat
+	result.Allowed = n
+	result.Remaining = int(remaining)
+	result.ResetAfter = newTat.Sub(now)
+	result.RetryAfter = -1
+	return result
+}
+
+// allowSlidingWindow weights the counter of the previous window with the part of it that still overlaps the sliding window
+func (e *memoryRateLimitEntry) allowSlidingWindow(now time.Time, limit redis_rate.Limit, n int) *redis_rate.Result {
+	window := limit.Period.Milliseconds()
+	nowMs := now.UnixMilli()
+	current := nowMs / window
+	switch {
+	case e.window == current-1:
+		e.previous, e.current = e.current, 0
+	case e.window != current:
+		e.previous, e.current = 0, 0
+	}
+	e.window = current
+
+	elapsed := nowMs - current*window
+	used := float64(e.previous)*float64(window-elapsed)/float64(window) + float64(e.current)
+
+	result := &redis_rate.Result{Limit: limit, RetryAfter: -1}
+	if used+float64(n) <= float64(limit.Rate) {
+		e.current += n
+		used += float64(n)
+		result.Allowed = n
+	} else if n <= limit.Rate {
+		retryAfter := window - elapsed
+		if e.previous > 0 {
+			retryAfter = min(retryAfter, int64(math.Ceil((used+float64(n)-float64(limit.Rate))*float64(window)/float64(e.previous))))
+		}
+		result.RetryAfter = time.Duration(retryAfter) * time.Millisecond
+	}
+	result.Remaining = max(int(float64(limit.Rate)-used), 0)
+
+	resetAfter := time.Duration(window-elapsed) * time.Millisecond
+	if e.current > 0 {
+		resetAfter += limit.Period
+	}
+	result.ResetAfter = resetAfter
+	e.expiresAt = now.Add(resetAfter)
+	return result
+}
+
+// allowTokenBucket refills the bucket lazily based on the time elapsed since the last request
+func (e *memoryRateLimitEntry) allowTokenBucket(now time.Time, limit redis_rate.Limit, n int) *redis_rate.Result {
+	capacity := float64(limit.Burst)
+	tokensPerNanosecond := float64(limit.Rate) / float64(limit.Period)
+	if e.refilledAt.IsZero() {
+		e.tokens = capacity
+	} else if elapsed := now.Sub(e.refilledAt); elapsed > 0 {
+		e.tokens = math.Min(capacity, e.tokens+float64(elapsed)*tokensPerNanosecond)
+	}
+	e.refilledAt = now
+
+	result := &redis_rate.Result{Limit: limit, RetryAfter: -1}
+	if e.tokens >= float64(n) {
+		e.tokens -= float64(n)
+		result.Allowed = n
+	} else if float64(n) <= capacity {
+		result.RetryAfter = time.Duration(math.Ceil((float64(n) - e.tokens) / tokensPerNanosecond))
+	}
+	result.Remaining = int(e.tokens)
+	result.ResetAfter = time.Duration(math.Ceil((capacity - e.tokens) / tokensPerNanosecond))
+	e.expiresAt = now.Add(result.ResetAfter)
+	return result
+}
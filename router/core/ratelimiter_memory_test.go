@@ -0,0 +1,97 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis_rate/v10"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/wundergraph/cosmo/router/pkg/config"
+)
+
+func TestMemoryRateLimitStorage(t *testing.T) {
+	limit := redis_rate.Limit{Rate: 2, Burst: 2, Period: time.Second}
+
+	for _, strategy := range []string{RateLimitStrategySimple, RateLimitStrategySlidingWindow, RateLimitStrategyTokenBucket} {
+		strategy := strategy
+		t.Run(strategy, func(t *testing.T) {
+			storage, err := NewMemoryRateLimitStorage(strategy)
+			require.NoError(t, err)
+
+			now := time.UnixMilli(10_000)
+			storage.now = func() time.Time { return now }
+
+			for i := 0; i < 2; i++ {
+				result, err := storage.AllowN(context.Background(), "key", limit, 1)
+				require.NoError(t, err)
+				require.Equal(t, 1, result.Allowed)
+				require.Equal(t, 1-i, result.Remaining)
+			}
+
+			result, err := storage.AllowN(context.Background(), "key", limit, 1)
+			require.NoError(t, err)
+			require.Equal(t, 0, result.Allowed)
+			require.Greater(t, result.RetryAfter, time.Duration(0))
+
+			// other keys have their own budget
+			result, err = storage.AllowN(context.Background(), "other", limit, 1)
+			require.NoError(t, err)
+			require.Equal(t, 1, result.Allowed)
+
+			// requests larger than the limit are never allowed
+			result, err = storage.AllowN(context.Background(), "other", limit, 3)
+			require.NoError(t, err)
+			require.Equal(t, 0, result.Allowed)
+			require.Equal(t, time.Duration(-1), result.RetryAfter)
+
+			now = now.Add(2 * time.Second)
+
+			result, err = storage.AllowN(context.Background(), "key", limit, 2)
+			require.NoError(t, err)
+			require.Equal(t, 2, result.Allowed)
+		})
+	}
+
+	t.Run("unknown strategy", func(t *testing.T) {
+		_, err := NewMemoryRateLimitStorage("unknown")
+		require.Error(t, err)
+	})
+}
+
+func TestCosmoRateLimiterValidatesLimits(t *testing.T) {
+	storage, err := NewMemoryRateLimitStorage(RateLimitStrategySimple)
+	require.NoError(t, err)
+
+	newLimiter := func(strategy string, defaultLimit redis_rate.Limit, keys ...config.RateLimitKey) error {
+		_, err := NewCosmoRateLimiter(&CosmoRateLimiterOptions{
+			MemoryStorage: storage,
+			Strategy:      strategy,
+			DefaultLimit:  defaultLimit,
+			Keys:          keys,
+		})
+		return err
+	}
+
+	require.NoError(t, newLimiter(RateLimitStrategySimple, redis_rate.Limit{Rate: 10, Burst: 10, Period: time.Second}))
+	// The sliding window doesn't use the burst
+	require.NoError(t, newLimiter(RateLimitStrategySlidingWindow, redis_rate.Limit{Rate: 10, Period: time.Second}))
+
+	require.EqualError(t, newLimiter(RateLimitStrategySimple, redis_rate.Limit{Burst: 10, Period: time.Second}),
+		"invalid default rate limit: rate must be greater than 0, got 0")
+	require.EqualError(t, newLimiter(RateLimitStrategyTokenBucket, redis_rate.Limit{Rate: 10, Period: time.Second}),
+		"invalid default rate limit: burst must be greater than 0, got 0")
+	require.EqualError(t, newLimiter(RateLimitStrategySimple, redis_rate.Limit{Rate: 10, Burst: 10, Period: time.Second},
+		config.RateLimitKey{Expression: "client_name", Period: -time.Second}),
+		"invalid rate limit of key 'client_name': period must be greater than 0, got -1s")
+
+	// Invalid limits are configuration errors, the failure mode only applies to the storage
+	_, err = storage.AllowN(context.Background(), "key", redis_rate.Limit{}, 1)
+	require.ErrorIs(t, err, errInvalidRateLimit)
+	limiter := &CosmoRateLimiter{failureMode: RateLimitFailureModeOpen, logger: zap.NewNop()}
+	allowed, err := limiter.storageFailure(err)
+	require.False(t, allowed)
+	require.ErrorIs(t, err, errInvalidRateLimit)
+}
@@ -0,0 +1,169 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis_rate/v10"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	RateLimitStrategySimple        = "simple"
+	RateLimitStrategySlidingWindow = "sliding_window"
+	RateLimitStrategyTokenBucket   = "token_bucket"
+)
+
+// rateLimitStrategy is implemented by all rate limit algorithms.
+// The signature matches redis_rate.Limiter, which implements the GCRA (simple) strategy.
+type rateLimitStrategy interface {
+	AllowN(ctx context.Context, key string, limit redis_rate.Limit, n int) (*redis_rate.Result, error)
+}
+
+func newRateLimitStrategy(strategy string, client redis.UniversalClient) (rateLimitStrategy, error) {
+	switch strategy {
+	case RateLimitStrategySimple, "":
+		return redis_rate.NewLimiter(client), nil
+	case RateLimitStrategySlidingWindow:
+		return &slidingWindowLimiter{client: client}, nil
+	case RateLimitStrategyTokenBucket:
+		return &tokenBucketLimiter{client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit strategy: %s", strategy)
+	}
+}
+
+// slidingWindowScript approximates a sliding window by weighting the counter of the previous window
+// with the part of it that still overlaps the sliding window.
+// KEYS[1] is the counter of the current window, KEYS[2] the counter of the previous window.
+var slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local elapsed = tonumber(ARGV[3])
+local cost = tonumber(ARGV[4])
+
+local current = tonumber(redis.call("GET", KEYS[1]) or "0")
+local previous = tonumber(redis.call("GET", KEYS[2]) or "0")
+local used = previous * (window - elapsed) / window + current
+
+if used + cost <= limit then
+  if cost > 0 then
+    current = redis.call("INCRBY", KEYS[1], cost)
+    redis.call("PEXPIRE", KEYS[1], window * 2)
+  end
+  used = used + cost
+  local reset_after = window - elapsed
+  if current > 0 then
+    reset_after = reset_after + window
+  end
+  return {cost, math.floor(limit - used), -1, reset_after}
+end
+
+local retry_after = -1
+if cost <= limit then
+  retry_after = window - elapsed
+  if previous > 0 then
+    retry_after = math.min(retry_after, math.ceil((used + cost - limit) * window / previous))
+  end
+end
+local reset_after = window - elapsed
+if current > 0 then
+  reset_after = reset_after + window
+end
+return {0, math.max(math.floor(limit - used), 0), retry_after, reset_after}
+`)
+
+// slidingWindowLimiter allows limit.Rate requests in any window of limit.Period.
+type slidingWindowLimiter struct {
+	client redis.UniversalClient
+}
+
+func (l *slidingWindowLimiter) AllowN(ctx context.Context, key string, limit redis_rate.Limit, n int) (*redis_rate.Result, error) {
+	window := limit.Period.Milliseconds()
+	if window <= 0 {
+		return nil, fmt.Errorf("invalid rate limit period: %s", limit.Period)
+	}
+	now := time.Now().UnixMilli()
+	current := now / window
+	// the hash tag keeps both windows in the same slot of a Redis cluster
+	keys := []string{
+		"{" + key + "}:" + strconv.FormatInt(current, 10),
+		"{" + key + "}:" + strconv.FormatInt(current-1, 10),
+	}
+	values, err := slidingWindowScript.Run(ctx, l.client, keys, limit.Rate, window, now-current*window, n).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+	return scriptResult(limit, values), nil
+}
+
+// tokenBucketScript refills the bucket lazily based on the time elapsed since the last request.
+// KEYS[1] holds the number of tokens and the time of the last refill.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local period = tonumber(ARGV[3])
+local now = tonumber(ARGV[4])
+local cost = tonumber(ARGV[5])
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+  tokens = capacity
+  ts = now
+end
+
+tokens = math.min(capacity, tokens + math.max(now - ts, 0) * rate / period)
+
+local allowed = 0
+local retry_after = -1
+if tokens >= cost then
+  tokens = tokens - cost
+  allowed = cost
+elseif cost <= capacity then
+  retry_after = math.ceil((cost - tokens) * period / rate)
+end
+
+local reset_after = math.ceil((capacity - tokens) * period / rate)
+redis.call("HSET", KEYS[1], "tokens", string.format("%.6f", tokens), "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.max(reset_after, 1))
+return {allowed, math.floor(tokens), retry_after, reset_after}
+`)
+
+// tokenBucketLimiter holds up to limit.Burst tokens and refills limit.Rate tokens per limit.Period.
+type tokenBucketLimiter struct {
+	client redis.UniversalClient
+}
+
+func (l *tokenBucketLimiter) AllowN(ctx context.Context, key string, limit redis_rate.Limit, n int) (*redis_rate.Result, error) {
+	period := limit.Period.Milliseconds()
+	if period <= 0 || limit.Rate <= 0 {
+		return nil, fmt.Errorf("invalid rate limit: %s", limit)
+	}
+	values, err := tokenBucketScript.Run(ctx, l.client, []string{key}, limit.Burst, limit.Rate, period, time.Now().UnixMilli(), n).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+	return scriptResult(limit, values), nil
+}
+
+// scriptResult maps the {allowed, remaining, retryAfterMs, resetAfterMs} reply of the scripts to a redis_rate.Result
+func scriptResult(limit redis_rate.Limit, values []int64) *redis_rate.Result {
+	result := &redis_rate.Result{
+		Limit:      limit,
+		RetryAfter: -1,
+	}
+	if len(values) != 4 {
+		return result
+	}
+	result.Allowed = int(values[0])
+	result.Remaining = int(values[1])
+	if values[2] >= 0 {
+		result.RetryAfter = time.Duration(values[2]) * time.Millisecond
+	}
+	result.ResetAfter = time.Duration(values[3]) * time.Millisecond
+	return result
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/wundergraph/cosmo/router/pkg/config"
+)
+
+// newRedisClient creates a Sentinel failover client if a master name is configured, a cluster client if
+// cluster addresses are configured and a single node client from the url otherwise.
+func newRedisClient(cfg *config.RedisConfiguration) (redis.UniversalClient, error) {
+	tlsConfig, err := newRedisTLSConfig(cfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.SentinelMasterName != "" {
+		if len(cfg.ClusterAddrs) == 0 {
+			return nil, errors.New("redis sentinel requires at least one sentinel address in cluster_addrs")
+		}
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       cfg.SentinelMasterName,
+			SentinelAddrs:    cfg.ClusterAddrs,
+			SentinelPassword: cfg.SentinelPassword,
+			Username:         cfg.Username,
+			Password:         cfg.Password,
+			TLSConfig:        tlsConfig,
+		}), nil
+	}
+
+	// A cluster client is created even for a single seed node, the remaining nodes are discovered from it
+	if len(cfg.ClusterAddrs) > 0 {
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:     cfg.ClusterAddrs,
+			Username:  cfg.Username,
+			Password:  cfg.Password,
+			TLSConfig: tlsConfig,
+		}), nil
+	}
+
+	options, err := redis.ParseURL(cfg.Url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the redis connection url: %w", err)
+	}
+	if cfg.Username != "" {
+		options.Username = cfg.Username
+	}
+	if cfg.Password != "" {
+		options.Password = cfg.Password
+	}
+	if tlsConfig != nil {
+		// Keep the server name derived from a rediss:// url
+		if options.TLSConfig != nil && tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = options.TLSConfig.ServerName
+		}
+		options.TLSConfig = tlsConfig
+	}
+
+	return redis.NewClient(options), nil
+}
+
+func newRedisTLSConfig(cfg *config.RedisTLSConfiguration) (*tls.Config, error) {
+	if cfg == nil || !cfg.Enabled {
+		return nil, nil
+	}
+
+	return newClientTLSConfig("redis", cfg.CaFile, cfg.CertFile, cfg.KeyFile, cfg.InsecureSkipVerify)
+}
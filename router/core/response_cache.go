@@ -0,0 +1,183 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	cachedirective "github.com/pquerna/cachecontrol/cacheobject"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"go.uber.org/zap"
+
+	"github.com/wundergraph/cosmo/router/pkg/config"
+)
+
+// responseCache serves complete responses of queries. It is created for every graph mux,
+// because the responses depend on the schema of the mux.
+type responseCache struct {
+	storage     ResponseCacheStorage
+	defaultTTL  time.Duration
+	varyHeaders []string
+	varyClaims  []string
+	// namespace separates the responses of different config versions and feature flags
+	namespace string
+	logger    *zap.Logger
+}
+
+func newResponseCache(storage ResponseCacheStorage, cfg *config.ResponseCacheConfiguration, namespace string, logger *zap.Logger) *responseCache {
+	return &responseCache{
+		storage:     storage,
+		defaultTTL:  cfg.DefaultTTL,
+		varyHeaders: cfg.VaryHeaders,
+		varyClaims:  cfg.VaryClaims,
+		namespace:   namespace,
+		logger:      logger,
+	}
+}
+
+// key returns false if the response of the operation must not be cached. Only queries are cached and responses
+// for authenticated clients only if the key contains at least one of their claims. Responses of requests that
+// skip the loader, include the query plan or are traced contain debug information and aren't cached either.
+func (c *responseCache) key(reqCtx *requestContext, operationCtx *operationContext) (string, bool) {
+	if reqCtx == nil || operationCtx == nil || operationCtx.opType != OperationTypeQuery {
+		return "", false
+	}
+	if operationCtx.executionOptions.SkipLoader || operationCtx.executionOptions.IncludeQueryPlanInResponse || operationCtx.traceOptions.Enable {
+		return "", false
+	}
+
+	var claims map[string]any
+	if auth := reqCtx.Authentication(); auth != nil {
+		if len(c.varyClaims) == 0 {
+			return "", false
+		}
+		claims = auth.Claims()
+	}
+
+	h := sha256.New()
+	write := func(value string) {
+		_, _ = h.Write([]byte(value))
+		_, _ = h.Write([]byte{0})
+	}
+
+	write(c.namespace)
+	if operationCtx.persistedID != "" {
+		write(operationCtx.persistedID)
+	} else {
+		write(strconv.FormatUint(operationCtx.hash, 10))
+	}
+	write(operationCtx.name)
+	write(string(operationCtx.variables))
+	for _, name := range c.varyHeaders {
+		write(strings.Join(reqCtx.request.Header.Values(name), ","))
+	}
+	for _, name := range c.varyClaims {
+		write(claimValue(claims, name))
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// serve writes the cached response of the key. It returns false if the response isn't cached
+// or the client asked to bypass caches.
+func (c *responseCache) serve(w http.ResponseWriter, r *http.Request, key string) bool {
+	reqDir, _ := cachedirective.ParseRequestCacheControl(r.Header.Get("Cache-Control"))
+	if reqDir != nil && (reqDir.NoCache || reqDir.NoStore) {
+		return false
+	}
+
+	values, err := c.storage.Get(r.Context(), []string{key})
+	if err != nil {
+		c.logger.Warn("Failed to read from the response cache", zap.Error(err))
+		return false
+	}
+	body, ttl, ok := decodeCacheEntry(values[0])
+	if !ok {
+		return false
+	}
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", int(ttl.Seconds())))
+	c.write(w, r, body)
+	return true
+}
+
+// store writes the resolved response and caches it for the duration of the Cache-Control header
+// computed by the header rules. Responses with errors aren't cached. Cache hits aren't rate limited,
+// so the rate limit extension of the client that resolved the response isn't stored.
+func (c *responseCache) store(ctx context.Context, w http.ResponseWriter, r *http.Request, key string, body []byte) {
+	if propagation := getResponseHeaderPropagation(ctx); propagation != nil {
+		for k, v := range propagation.header {
+			for _, el := range v {
+				w.Header().Add(k, el)
+			}
+		}
+	}
+
+	if !gjson.GetBytes(body, "errors").Exists() {
+		res := &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     w.Header(),
+			Request:    r,
+		}
+		if ttl, cacheable := cacheControlTTL(res, c.defaultTTL); cacheable {
+			if err := c.storage.Set(r.Context(), []string{key}, [][]byte{encodeCacheEntry(withoutRateLimitExtension(body), ttl)}, ttl); err != nil {
+				c.logger.Warn("Failed to write to the response cache", zap.Error(err))
+			}
+		}
+	}
+
+	c.write(w, r, body)
+}
+
+// write answers GET requests with 304 Not Modified if the client already has the response
+func (c *responseCache) write(w http.ResponseWriter, r *http.Request, body []byte) {
+	etag := `"` + strconv.FormatUint(xxhash.Sum64(body), 16) + `"`
+	w.Header().Set("ETag", etag)
+	for _, name := range c.varyHeaders {
+		w.Header().Add("Vary", name)
+	}
+
+	if r.Method == http.MethodGet && etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	_, _ = w.Write(body)
+}
+
+// withoutRateLimitExtension removes the rate limit stats of the resolving request from the response
+func withoutRateLimitExtension(body []byte) []byte {
+	if !gjson.GetBytes(body, "extensions.rateLimit").Exists() {
+		return body
+	}
+	stripped, err := sjson.DeleteBytes(body, "extensions.rateLimit")
+	if err != nil {
+		return body
+	}
+	if extensions := gjson.GetBytes(stripped, "extensions"); extensions.IsObject() && len(extensions.Map()) == 0 {
+		if stripped, err = sjson.DeleteBytes(stripped, "extensions"); err != nil {
+			return body
+		}
+	}
+	return stripped
+}
+
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		// If-None-Match uses the weak comparison
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
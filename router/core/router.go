@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -18,6 +19,7 @@ import (
 
 	"github.com/wundergraph/cosmo/router/internal/persistedoperation"
 	"github.com/wundergraph/cosmo/router/internal/persistedoperation/cdn"
+	"github.com/wundergraph/cosmo/router/internal/persistedoperation/filesystem"
 	"github.com/wundergraph/cosmo/router/internal/persistedoperation/s3"
 	"github.com/wundergraph/cosmo/router/pkg/execution_config"
 	"github.com/wundergraph/cosmo/router/pkg/routerconfig"
@@ -95,6 +97,35 @@ type (
 		KeepAliveProbeInterval time.Duration
 	}
 
+	// SubgraphTrafficOptions are the transport and retry options of a single subgraph
+	SubgraphTrafficOptions struct {
+		Transport      *SubgraphTransportOptions
+		Retry          SubgraphRetryOptions
+		CircuitBreaker SubgraphCircuitBreakerOptions
+	}
+
+	SubgraphRetryOptions struct {
+		Enabled       bool
+		MaxRetryCount int
+		MaxDuration   time.Duration
+		Interval      time.Duration
+		// StatusCodes and Errors replace the default retryable status codes and network errors if set
+		StatusCodes []int
+		Errors      []string
+	}
+
+	// SubgraphCircuitBreakerOptions configures the circuit breaker of a subgraph. A failed request
+	// is a request with a connection error, a 5xx status code or a latency above LatencyThreshold.
+	SubgraphCircuitBreakerOptions struct {
+		Enabled                  bool
+		ErrorThresholdPercentage int
+		LatencyThreshold         time.Duration
+		MinimumRequests          int
+		Window                   time.Duration
+		Cooldown                 time.Duration
+		HalfOpenRequests         int
+	}
+
 	GraphQLMetricsConfig struct {
 		Enabled           bool
 		CollectorEndpoint string
@@ -175,12 +206,19 @@ type (
 		postOriginHandlers        []TransportPostHandler
 		headerRules               *config.HeaderRules
 		subgraphTransportOptions  *SubgraphTransportOptions
+		subgraphTrafficOptions    map[string]*SubgraphTrafficOptions
 		graphqlMetricsConfig      *GraphQLMetricsConfig
 		routerTrafficConfig       *config.RouterTrafficConfiguration
 		fileUploadConfig          *config.FileUpload
 		accessController          *AccessController
 		retryOptions              retrytransport.RetryOptions
-		redisClient               *redis.Client
+		retryBudget               *retrytransport.Budget
+		circuitBreakerOptions     SubgraphCircuitBreakerOptions
+		redisClient               redis.UniversalClient
+		rateLimitMemoryStorage    *MemoryRateLimitStorage
+		subgraphCacheStorage      ResponseCacheStorage
+		responseCacheStorage      ResponseCacheStorage
+		apqStorage                ResponseCacheStorage
 		processStartTime          time.Time
 		developmentMode           bool
 		healthcheck               health.Checker
@@ -194,6 +232,11 @@ type (
 		configPoller configpoller.ConfigPoller
 		selfRegister selfregister.SelfRegister
 
+		// executionConfigFallbackStorage is nil if the fallback storage is disabled
+		executionConfigFallbackStorage *execution_config.FallbackStorage
+		// executionConfigFallback is set while the next graph server is built from the fallback execution config
+		executionConfigFallback bool
+
 		registrationInfo *nodev1.RegistrationInfo
 
 		securityConfiguration config.SecurityConfiguration
@@ -210,6 +253,10 @@ type (
 
 		rateLimit *config.RateLimitConfiguration
 
+		subgraphCacheConfig *config.SubgraphCacheConfiguration
+
+		responseCacheConfig *config.ResponseCacheConfiguration
+
 		webSocketConfiguration *config.WebSocketConfiguration
 
 		subgraphErrorPropagation config.SubgraphErrorPropagationConfiguration
@@ -487,7 +534,13 @@ func NewRouter(opts ...Option) (*Router, error) {
 
 // newGraphServer creates a new server.
 func (r *Router) newServer(ctx context.Context, cfg *nodev1.RouterConfig) error {
-	server, err := newGraphServer(ctx, r, cfg, r.proxy)
+	var previousOperationUsage map[string][]operationUsageCount
+	if r.httpServer.graphServer != nil && r.engineExecutionConfiguration.ExecutionPlanCacheWarmup.Enabled {
+		// The old graph server keeps serving traffic until the new one is swapped in
+		previousOperationUsage = r.httpServer.graphServer.operationUsage(r.engineExecutionConfiguration.ExecutionPlanCacheWarmup.TopOperations)
+	}
+
+	server, err := newGraphServer(ctx, r, cfg, r.proxy, previousOperationUsage)
 	if err != nil {
 		r.logger.Error("Failed to create graph server. Keeping the old server", zap.Error(err))
 		return err
@@ -495,9 +548,34 @@ func (r *Router) newServer(ctx context.Context, cfg *nodev1.RouterConfig) error
 
 	r.httpServer.SwapGraphServer(ctx, server)
 
+	if r.executionConfigFallbackStorage != nil {
+		r.updateExecutionConfigFallback(cfg)
+	}
+
 	return nil
 }
 
+// updateExecutionConfigFallback stores the applied execution config as the last known good config and
+// reports if the router serves the config of the fallback storage
+func (r *Router) updateExecutionConfigFallback(cfg *nodev1.RouterConfig) {
+	detail := ""
+	if r.executionConfigFallback {
+		detail = fmt.Sprintf("fallback (version %s)", cfg.GetVersion())
+	}
+	if reporter, ok := r.healthcheck.(health.DetailReporter); ok {
+		reporter.SetDetail("execution_config", detail)
+	}
+
+	if r.executionConfigFallback {
+		return
+	}
+
+	if err := r.executionConfigFallbackStorage.Store(cfg); err != nil {
+		// The previous config is kept and used as a fallback
+		r.logger.Error("Failed to store execution config in the fallback storage", zap.Error(err))
+	}
+}
+
 func (r *Router) listenAndServe(cfg *nodev1.RouterConfig) error {
 	r.logger.Info("Server listening and serving",
 		zap.String("listen_addr", r.listenAddr),
@@ -743,12 +821,42 @@ func (r *Router) bootstrap(ctx context.Context) error {
 	}
 
 	if r.Config.rateLimit != nil && r.Config.rateLimit.Enabled {
-		options, err := redis.ParseURL(r.Config.rateLimit.Storage.Url)
+		switch r.Config.rateLimit.Backend {
+		case RateLimitBackendMemory:
+			storage, err := NewMemoryRateLimitStorage(r.Config.rateLimit.Strategy)
+			if err != nil {
+				return fmt.Errorf("failed to create the in-memory rate limit storage: %w", err)
+			}
+
+			r.rateLimitMemoryStorage = storage
+		case RateLimitBackendRedis, "":
+			client, err := newRedisClient(&r.Config.rateLimit.Storage)
+			if err != nil {
+				return fmt.Errorf("failed to create the rate limit redis client: %w", err)
+			}
+
+			r.redisClient = client
+		default:
+			return fmt.Errorf("unknown rate limit backend: %s", r.Config.rateLimit.Backend)
+		}
+	}
+
+	if r.Config.subgraphCacheConfig != nil && r.Config.subgraphCacheConfig.Enabled && r.Config.subgraphCacheStorage == nil {
+		storage, err := newResponseCacheStorage(&r.Config.subgraphCacheConfig.Storage, "cosmo_subgraph_cache")
 		if err != nil {
-			return fmt.Errorf("failed to parse the redis connection url: %w", err)
+			return err
+		}
+
+		r.subgraphCacheStorage = storage
+	}
+
+	if r.Config.responseCacheConfig != nil && r.Config.responseCacheConfig.Enabled && r.Config.responseCacheStorage == nil {
+		storage, err := newResponseCacheStorage(&r.Config.responseCacheConfig.Storage, "cosmo_response_cache")
+		if err != nil {
+			return err
 		}
 
-		r.redisClient = redis.NewClient(options)
+		r.responseCacheStorage = storage
 	}
 
 	if r.engineExecutionConfiguration.Debug.ReportWebSocketConnections {
@@ -795,6 +903,7 @@ func (r *Router) bootstrap(ctx context.Context) error {
 func (r *Router) buildClients() error {
 	s3Providers := map[string]config.S3StorageProvider{}
 	cdnProviders := map[string]config.CDNStorageProvider{}
+	fileSystemProviders := map[string]config.FileSystemStorageProvider{}
 
 	for _, provider := range r.storageProviders.S3 {
 		if _, ok := s3Providers[provider.ID]; ok {
@@ -810,6 +919,13 @@ func (r *Router) buildClients() error {
 		cdnProviders[provider.ID] = provider
 	}
 
+	for _, provider := range r.storageProviders.FileSystem {
+		if _, ok := fileSystemProviders[provider.ID]; ok {
+			return fmt.Errorf("duplicate file system storage provider with id '%s'", provider.ID)
+		}
+		fileSystemProviders[provider.ID] = provider
+	}
+
 	var pClient persistedoperation.Client
 
 	if provider, ok := cdnProviders[r.persistedOperationsConfig.Storage.ProviderID]; ok {
@@ -819,8 +935,10 @@ func (r *Router) buildClients() error {
 		}
 
 		c, err := cdn.NewClient(provider.URL, r.graphApiToken, cdn.Options{
-			Logger:        r.logger,
-			TraceProvider: r.tracerProvider,
+			Logger:               r.logger,
+			TraceProvider:        r.tracerProvider,
+			ManifestEnabled:      r.persistedOperationsConfig.Manifest.Enabled,
+			ManifestPollInterval: r.persistedOperationsConfig.Manifest.PollInterval,
 		})
 		if err != nil {
 			return err
@@ -849,14 +967,37 @@ func (r *Router) buildClients() error {
 		r.logger.Info("Use S3 as storage provider for persisted operations",
 			zap.String("provider_id", provider.ID),
 		)
+
+		if r.persistedOperationsConfig.Manifest.Enabled {
+			r.logger.Warn("The persisted operations manifest is only supported by the CDN provider")
+		}
+	} else if provider, ok := fileSystemProviders[r.persistedOperationsConfig.Storage.ProviderID]; ok {
+		path := filepath.Join(provider.Path, r.persistedOperationsConfig.Storage.ObjectPrefix)
+
+		c, err := filesystem.NewClient(path, &filesystem.Options{
+			Watch:  provider.Watch,
+			Logger: r.logger,
+		})
+		if err != nil {
+			return err
+		}
+		pClient = c
+
+		r.logger.Info("Use the file system as storage provider for persisted operations",
+			zap.String("provider_id", provider.ID),
+			zap.String("path", path),
+			zap.Bool("watch", provider.Watch),
+		)
 	} else if r.graphApiToken != "" {
 		if r.persistedOperationsConfig.Storage.ProviderID != "" {
 			return fmt.Errorf("unknown storage provider id '%s' for persisted operations", r.persistedOperationsConfig.Storage.ProviderID)
 		}
 
 		c, err := cdn.NewClient(r.cdnConfig.URL, r.graphApiToken, cdn.Options{
-			Logger:        r.logger,
-			TraceProvider: r.tracerProvider,
+			Logger:               r.logger,
+			TraceProvider:        r.tracerProvider,
+			ManifestEnabled:      r.persistedOperationsConfig.Manifest.Enabled,
+			ManifestPollInterval: r.persistedOperationsConfig.Manifest.PollInterval,
 		})
 		if err != nil {
 			return err
@@ -887,6 +1028,27 @@ func (r *Router) buildClients() error {
 		r.persistedOperationClient = c
 	}
 
+	if apq := r.persistedOperationsConfig.AutomaticPersistedQueries; apq.Enabled {
+		// Every client could register operations otherwise
+		if r.securityConfiguration.BlockNonPersistedOperations {
+			return errors.New("automatic persisted queries can't be enabled if non-persisted operations are blocked")
+		}
+
+		if r.apqStorage == nil {
+			storage, err := newResponseCacheStorage(&apq.Storage, "cosmo_apq")
+			if err != nil {
+				return err
+			}
+
+			r.apqStorage = storage
+		}
+
+		r.logger.Info("Automatic persisted queries enabled",
+			zap.String("provider", apq.Storage.Provider),
+			zap.Duration("ttl", apq.TTL),
+		)
+	}
+
 	var rClient routerconfig.Client
 
 	// Poller is only initialized when a config poller is configured and the router is not started with a static config
@@ -970,6 +1132,19 @@ func (r *Router) buildClients() error {
 
 	}
 
+	if r.staticExecutionConfig == nil && r.routerConfigPollerConfig != nil && r.routerConfigPollerConfig.FallbackStorage.Enabled {
+		fallbackStorage, err := execution_config.NewFallbackStorage(r.routerConfigPollerConfig.FallbackStorage.Path, r.routerConfigPollerConfig.GraphSignKey)
+		if err != nil {
+			return err
+		}
+		r.executionConfigFallbackStorage = fallbackStorage
+
+		r.logger.Info("Execution config fallback storage enabled",
+			zap.String("path", fallbackStorage.Path()),
+			zap.Bool("signed", r.routerConfigPollerConfig.GraphSignKey != ""),
+		)
+	}
+
 	return nil
 }
 
@@ -1063,12 +1238,30 @@ func (r *Router) Start(ctx context.Context) error {
 
 	cfg, err := r.configPoller.GetRouterConfig(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get initial execution config: %w", err)
+		if r.executionConfigFallbackStorage == nil {
+			return fmt.Errorf("failed to get initial execution config: %w", err)
+		}
+
+		fallbackConfig, fallbackErr := r.executionConfigFallbackStorage.Load()
+		if fallbackErr != nil {
+			return fmt.Errorf("failed to get initial execution config: %w", errors.Join(err, fmt.Errorf("failed to load fallback execution config: %w", fallbackErr)))
+		}
+
+		r.logger.Warn("Failed to get initial execution config. Starting with the last known good execution config of the fallback storage",
+			zap.Error(err),
+			zap.String("path", r.executionConfigFallbackStorage.Path()),
+			zap.String("config_version", fallbackConfig.GetVersion()),
+		)
+
+		cfg = &routerconfig.Response{Config: fallbackConfig}
+		r.executionConfigFallback = true
 	}
 
 	if err := r.newServer(ctx, cfg.Config); err != nil {
 		return err
 	}
+	// The following configs are fetched from the storage provider
+	r.executionConfigFallback = false
 
 	if r.playground {
 		graphqlEndpointURL, err := url.JoinPath(r.baseURL, r.graphqlPath)
@@ -1093,8 +1286,12 @@ func (r *Router) Start(ctx context.Context) error {
 		r.logger.Warn("Advanced Request Tracing (ART) is enabled in development mode but requires a graph token to work in production. For more information see https://cosmo-docs.wundergraph.com/router/advanced-request-tracing-art")
 	}
 
-	if r.redisClient != nil {
+	if r.redisClient != nil || r.rateLimitMemoryStorage != nil {
 		r.logger.Info("Rate limiting enabled",
+			zap.String("backend", r.rateLimit.Backend),
+			zap.String("failureMode", r.rateLimit.FailureMode),
+			zap.String("strategy", r.rateLimit.Strategy),
+			zap.Int("keys", len(r.rateLimit.Keys)),
 			zap.Int("rate", r.rateLimit.SimpleStrategy.Rate),
 			zap.Int("burst", r.rateLimit.SimpleStrategy.Burst),
 			zap.Duration("duration", r.Config.rateLimit.SimpleStrategy.Period),
@@ -1102,6 +1299,23 @@ func (r *Router) Start(ctx context.Context) error {
 		)
 	}
 
+	if r.subgraphCacheStorage != nil && r.subgraphCacheConfig != nil && r.subgraphCacheConfig.Enabled {
+		r.logger.Info("Subgraph response cache enabled",
+			zap.String("provider", r.subgraphCacheConfig.Storage.Provider),
+			zap.Duration("defaultTTL", r.subgraphCacheConfig.DefaultTTL),
+			zap.Strings("subgraphs", r.subgraphCacheConfig.Subgraphs),
+		)
+	}
+
+	if r.responseCacheStorage != nil && r.responseCacheConfig != nil && r.responseCacheConfig.Enabled {
+		r.logger.Info("Response cache enabled",
+			zap.String("provider", r.responseCacheConfig.Storage.Provider),
+			zap.Duration("defaultTTL", r.responseCacheConfig.DefaultTTL),
+			zap.Strings("varyHeaders", r.responseCacheConfig.VaryHeaders),
+			zap.Strings("varyClaims", r.responseCacheConfig.VaryClaims),
+		)
+	}
+
 	if err := r.listenAndServe(cfg.Config); err != nil {
 		r.logger.Error("Failed to start server with initial config", zap.Error(err))
 		return err
@@ -1225,15 +1439,49 @@ func (r *Router) Shutdown(ctx context.Context) (err error) {
 		go func() {
 			defer wg.Done()
 
-			if subErr := r.redisClient.FlushAll(ctx); subErr.Err() != nil {
-				err = errors.Join(err, fmt.Errorf("failed to flush redis client: %w", subErr.Err()))
-			}
+			// The data isn't flushed, the Redis deployment can be shared with other routers and services
 			if closeErr := r.redisClient.Close(); closeErr != nil {
 				err = errors.Join(err, fmt.Errorf("failed to close redis client: %w", closeErr))
 			}
 		}()
 	}
 
+	if r.subgraphCacheStorage != nil {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if closeErr := r.subgraphCacheStorage.Close(); closeErr != nil {
+				err = errors.Join(err, fmt.Errorf("failed to close subgraph cache storage: %w", closeErr))
+			}
+		}()
+	}
+
+	if r.responseCacheStorage != nil {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if closeErr := r.responseCacheStorage.Close(); closeErr != nil {
+				err = errors.Join(err, fmt.Errorf("failed to close response cache storage: %w", closeErr))
+			}
+		}()
+	}
+
+	if r.apqStorage != nil {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if closeErr := r.apqStorage.Close(); closeErr != nil {
+				err = errors.Join(err, fmt.Errorf("failed to close automatic persisted queries storage: %w", closeErr))
+			}
+		}()
+	}
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -1492,14 +1740,48 @@ func WithSubgraphTransportOptions(opts *SubgraphTransportOptions) Option {
 	}
 }
 
+// WithSubgraphTrafficOptions overrides the transport and retry options for single subgraphs. The key is the
+// name of the subgraph. Every subgraph with options gets its own transport and connection pool.
+func WithSubgraphTrafficOptions(subgraphs map[string]*SubgraphTrafficOptions) Option {
+	return func(r *Router) {
+		r.subgraphTrafficOptions = subgraphs
+	}
+}
+
 func WithSubgraphRetryOptions(enabled bool, maxRetryCount int, retryMaxDuration, retryInterval time.Duration) Option {
 	return func(r *Router) {
-		r.retryOptions = retrytransport.RetryOptions{
-			Enabled:       enabled,
-			MaxRetryCount: maxRetryCount,
-			MaxDuration:   retryMaxDuration,
-			Interval:      retryInterval,
+		r.retryOptions.Enabled = enabled
+		r.retryOptions.MaxRetryCount = maxRetryCount
+		r.retryOptions.MaxDuration = retryMaxDuration
+		r.retryOptions.Interval = retryInterval
+	}
+}
+
+// WithSubgraphRetryConditions replaces the default retryable status codes and network errors of all subgraphs.
+// A network error is retryable if its message ends with one of the errors. Empty lists keep the defaults.
+func WithSubgraphRetryConditions(statusCodes []int, retryableErrors []string) Option {
+	return func(r *Router) {
+		r.retryOptions.StatusCodes = statusCodes
+		r.retryOptions.Errors = retryableErrors
+	}
+}
+
+// WithSubgraphRetryBudget limits the retries of all subgraph requests to a percentage of the requests in the window.
+// minRetries are allowed in the window regardless of the percentage.
+func WithSubgraphRetryBudget(enabled bool, percentage, minRetries int, window time.Duration) Option {
+	return func(r *Router) {
+		if !enabled {
+			r.retryBudget = nil
+			return
 		}
+		r.retryBudget = retrytransport.NewBudget(percentage, minRetries, window)
+	}
+}
+
+// WithSubgraphCircuitBreakerOptions configures the circuit breaker of all subgraphs. Every subgraph has its own circuit.
+func WithSubgraphCircuitBreakerOptions(opts SubgraphCircuitBreakerOptions) Option {
+	return func(r *Router) {
+		r.circuitBreakerOptions = opts
 	}
 }
 
@@ -1533,6 +1815,36 @@ func WithRateLimitConfig(cfg *config.RateLimitConfiguration) Option {
 	}
 }
 
+// WithSubgraphCache enables the cache of subgraph responses. The storage is created from the configuration
+// unless a custom storage is set with WithSubgraphCacheStorage.
+func WithSubgraphCache(cfg *config.SubgraphCacheConfiguration) Option {
+	return func(r *Router) {
+		r.Config.subgraphCacheConfig = cfg
+	}
+}
+
+// WithSubgraphCacheStorage sets a custom storage for the subgraph response cache. The router closes it on shutdown.
+func WithSubgraphCacheStorage(storage ResponseCacheStorage) Option {
+	return func(r *Router) {
+		r.Config.subgraphCacheStorage = storage
+	}
+}
+
+// WithResponseCache enables the cache of complete query responses. The storage is created from the configuration
+// unless a custom storage is set with WithResponseCacheStorage.
+func WithResponseCache(cfg *config.ResponseCacheConfiguration) Option {
+	return func(r *Router) {
+		r.Config.responseCacheConfig = cfg
+	}
+}
+
+// WithResponseCacheStorage sets a custom storage for the response cache. The router closes it on shutdown.
+func WithResponseCacheStorage(storage ResponseCacheStorage) Option {
+	return func(r *Router) {
+		r.Config.responseCacheStorage = storage
+	}
+}
+
 func WithLocalhostFallbackInsideDocker(fallback bool) Option {
 	return func(r *Router) {
 		r.localhostFallbackInsideDocker = fallback
@@ -1640,6 +1952,13 @@ func WithPersistedOperationsConfig(cfg config.PersistedOperationsConfig) Option
 	}
 }
 
+// WithAutomaticPersistedQueriesStorage sets a custom storage for Automatic Persisted Queries. The router closes it on shutdown.
+func WithAutomaticPersistedQueriesStorage(storage ResponseCacheStorage) Option {
+	return func(r *Router) {
+		r.Config.apqStorage = storage
+	}
+}
+
 func WithApolloCompatibilityFlagsConfig(cfg config.ApolloCompatibilityFlags) Option {
 	return func(r *Router) {
 		if cfg.EnableAll {
@@ -0,0 +1,468 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"github.com/wundergraph/graphql-go-tools/v2/pkg/engine/resolve"
+	otrace "go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/wundergraph/cosmo/router/pkg/config"
+	"github.com/wundergraph/cosmo/router/pkg/otel"
+)
+
+const (
+	subgraphCacheStatusHit     = "hit"
+	subgraphCacheStatusPartial = "partial"
+	subgraphCacheStatusMiss    = "miss"
+)
+
+// subgraphCacheUnscopedHeaders are the headers of the subgraph request that don't change the response.
+// All other headers, e.g. the ones forwarded by the header rules, are part of the cache key.
+var subgraphCacheUnscopedHeaders = map[string]struct{}{
+	"Accept":          {},
+	"Accept-Encoding": {},
+	"Content-Length":  {},
+	"Content-Type":    {},
+	"User-Agent":      {},
+	"Traceparent":     {},
+	"Tracestate":      {},
+	"Baggage":         {},
+}
+
+// subgraphResponseCache decides which subgraph responses are cached and for how long.
+// Root fetches are cached as a whole, entity fetches are cached per entity, so that
+// an entity can be served from the cache for any request that needs it.
+type subgraphResponseCache struct {
+	storage    ResponseCacheStorage
+	defaultTTL time.Duration
+	// subgraphs is nil if the responses of all subgraphs are cached
+	subgraphs    map[string]struct{}
+	scopeHeaders []string
+	scopeClaims  []string
+	logger       *zap.Logger
+}
+
+func newSubgraphResponseCache(storage ResponseCacheStorage, cfg *config.SubgraphCacheConfiguration, logger *zap.Logger) *subgraphResponseCache {
+	c := &subgraphResponseCache{
+		storage:      storage,
+		defaultTTL:   cfg.DefaultTTL,
+		scopeHeaders: cfg.ScopeHeaders,
+		scopeClaims:  cfg.ScopeClaims,
+		logger:       logger,
+	}
+	if len(cfg.Subgraphs) > 0 {
+		c.subgraphs = make(map[string]struct{}, len(cfg.Subgraphs))
+		for _, name := range cfg.Subgraphs {
+			c.subgraphs[name] = struct{}{}
+		}
+	}
+	return c
+}
+
+func (c *subgraphResponseCache) cachesSubgraph(subgraphName string) bool {
+	if c.subgraphs == nil {
+		return true
+	}
+	_, ok := c.subgraphs[subgraphName]
+	return ok
+}
+
+// scope returns the part of the key that is shared by all entries of the subgraph request. It contains the headers
+// sent to the subgraph, so that a response is never served to a client that forwards other headers.
+func (c *subgraphResponseCache) scope(reqCtx *requestContext, subgraph *Subgraph, req *http.Request) []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteString(subgraph.Name)
+	buf.WriteByte(0)
+	buf.WriteString(req.URL.String())
+	buf.WriteByte(0)
+	headers := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		if _, unscoped := subgraphCacheUnscopedHeaders[http.CanonicalHeaderKey(name)]; !unscoped {
+			headers = append(headers, name)
+		}
+	}
+	sort.Strings(headers)
+	for _, name := range headers {
+		buf.WriteString(http.CanonicalHeaderKey(name))
+		buf.WriteByte(0)
+		buf.WriteString(strings.Join(req.Header[name], ","))
+		buf.WriteByte(0)
+	}
+	for _, name := range c.scopeHeaders {
+		buf.WriteString(strings.Join(reqCtx.request.Header.Values(name), ","))
+		buf.WriteByte(0)
+	}
+	if len(c.scopeClaims) > 0 {
+		var claims map[string]any
+		if auth := reqCtx.Authentication(); auth != nil {
+			claims = auth.Claims()
+		}
+		for _, name := range c.scopeClaims {
+			buf.WriteString(claimValue(claims, name))
+			buf.WriteByte(0)
+		}
+	}
+	return buf.Bytes()
+}
+
+func (c *subgraphResponseCache) key(parts ...[]byte) string {
+	h := sha256.New()
+	for _, part := range parts {
+		_, _ = h.Write(part)
+		_, _ = h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// get treats unreachable storage like a cache miss
+func (c *subgraphResponseCache) get(ctx context.Context, keys []string) [][]byte {
+	values, err := c.storage.Get(ctx, keys)
+	if err != nil {
+		c.logger.Warn("Failed to read from the subgraph response cache", zap.Error(err))
+		return make([][]byte, len(keys))
+	}
+	return values
+}
+
+func (c *subgraphResponseCache) set(ctx context.Context, keys []string, values [][]byte, ttl time.Duration) {
+	entries := make([][]byte, len(values))
+	for i, value := range values {
+		entries[i] = encodeCacheEntry(value, ttl)
+	}
+	if err := c.storage.Set(ctx, keys, entries, ttl); err != nil {
+		c.logger.Warn("Failed to write to the subgraph response cache", zap.Error(err))
+	}
+}
+
+// ttl returns how long the response can be cached. Only successful responses without errors are cached.
+func (c *subgraphResponseCache) ttl(resp *http.Response, body []byte) (time.Duration, bool) {
+	if resp.Request == nil || resp.StatusCode != http.StatusOK || gjson.GetBytes(body, "errors").Exists() || !gjson.GetBytes(body, "data").IsObject() {
+		return 0, false
+	}
+
+	return cacheControlTTL(resp, c.defaultTTL)
+}
+
+// subgraphCacheTransport answers subgraph queries from the cache. It sits above the retry transport,
+// so cached responses never count against the retries or the circuit of the subgraph.
+type subgraphCacheTransport struct {
+	roundTripper http.RoundTripper
+	cache        *subgraphResponseCache
+}
+
+func (t *subgraphCacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqCtx := getRequestContext(req.Context())
+	if reqCtx == nil || !isSubgraphCacheableRequest(req) {
+		return t.roundTripper.RoundTrip(req)
+	}
+	subgraph := reqCtx.ActiveSubgraph(req)
+	if subgraph == nil || !t.cache.cachesSubgraph(subgraph.Name) {
+		return t.roundTripper.RoundTrip(req)
+	}
+
+	body, err := subgraphRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	scope := t.cache.scope(reqCtx, subgraph, req)
+
+	if representations := gjson.GetBytes(body, "variables.representations"); representations.IsArray() {
+		if entities := representations.Array(); len(entities) > 0 {
+			return t.roundTripEntities(req, scope, body, entities)
+		}
+	}
+	return t.roundTripRoot(req, scope, body)
+}
+
+func (t *subgraphCacheTransport) roundTripRoot(req *http.Request, scope, body []byte) (*http.Response, error) {
+	ctx := req.Context()
+	key := t.cache.key(scope, body)
+
+	if value, ttl, ok := decodeCacheEntry(t.cache.get(ctx, []string{key})[0]); ok {
+		setSubgraphCacheStatus(ctx, subgraphCacheStatusHit)
+		return newSubgraphCacheResponse(req, value, ttl), nil
+	}
+	setSubgraphCacheStatus(ctx, subgraphCacheStatusMiss)
+
+	resp, err := t.roundTripper.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	data, ok, err := readSubgraphResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return resp, nil
+	}
+	if ttl, cacheable := t.cache.ttl(resp, data); cacheable {
+		t.cache.set(ctx, []string{key}, [][]byte{data}, ttl)
+	}
+	return resp, nil
+}
+
+// roundTripEntities only fetches the entities that aren't cached and merges them with the cached ones
+func (t *subgraphCacheTransport) roundTripEntities(req *http.Request, scope, body []byte, representations []gjson.Result) (*http.Response, error) {
+	ctx := req.Context()
+
+	// The query and the other variables are the same for all entities of the request
+	withoutRepresentations, err := sjson.DeleteBytes(body, "variables.representations")
+	if err != nil {
+		return t.roundTripper.RoundTrip(req)
+	}
+	entityScope := []byte(t.cache.key(scope, withoutRepresentations))
+
+	keys := make([]string, len(representations))
+	for i, representation := range representations {
+		keys[i] = t.cache.key(entityScope, []byte(representation.Raw))
+	}
+
+	entities := make([][]byte, len(keys))
+	var missing []int
+	var cachedTTL time.Duration
+	for i, entry := range t.cache.get(ctx, keys) {
+		value, ttl, ok := decodeCacheEntry(entry)
+		if !ok {
+			missing = append(missing, i)
+			continue
+		}
+		entities[i] = value
+		if cachedTTL == 0 || ttl < cachedTTL {
+			cachedTTL = ttl
+		}
+	}
+
+	if len(missing) == 0 {
+		setSubgraphCacheStatus(ctx, subgraphCacheStatusHit)
+		return newSubgraphCacheResponse(req, entitiesResponseBody(entities, nil), cachedTTL), nil
+	}
+
+	fetchReq := req
+	if len(missing) < len(keys) {
+		missingRepresentations := &bytes.Buffer{}
+		missingRepresentations.WriteByte('[')
+		for i, index := range missing {
+			if i > 0 {
+				missingRepresentations.WriteByte(',')
+			}
+			missingRepresentations.WriteString(representations[index].Raw)
+		}
+		missingRepresentations.WriteByte(']')
+
+		fetchBody, err := sjson.SetRawBytes(body, "variables.representations", missingRepresentations.Bytes())
+		if err != nil {
+			return t.roundTripper.RoundTrip(req)
+		}
+		fetchReq = withSubgraphRequestBody(req, fetchBody)
+		setSubgraphCacheStatus(ctx, subgraphCacheStatusPartial)
+	} else {
+		setSubgraphCacheStatus(ctx, subgraphCacheStatusMiss)
+	}
+
+	resp, err := t.roundTripper.RoundTrip(fetchReq)
+	if err != nil {
+		return nil, err
+	}
+	data, ok, err := readSubgraphResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var fetched []gjson.Result
+	if ok && resp.StatusCode == http.StatusOK {
+		if result := gjson.GetBytes(data, "data._entities"); result.IsArray() {
+			fetched = result.Array()
+		}
+	}
+	if len(fetched) != len(missing) {
+		if fetchReq == req {
+			return resp, nil
+		}
+		// The response can't be merged with the cached entities, fetch all of them instead
+		_ = resp.Body.Close()
+		return t.roundTripper.RoundTrip(withSubgraphRequestBody(req, body))
+	}
+
+	fetchedValues := make([][]byte, len(missing))
+	fetchedKeys := make([]string, len(missing))
+	for i, index := range missing {
+		fetchedValues[i] = []byte(fetched[i].Raw)
+		fetchedKeys[i] = keys[index]
+		entities[index] = fetchedValues[i]
+	}
+
+	ttl, cacheable := t.cache.ttl(resp, data)
+	if cacheable {
+		t.cache.set(ctx, fetchedKeys, fetchedValues, ttl)
+	}
+
+	if fetchReq == req {
+		return resp, nil
+	}
+
+	var graphqlErrors []byte
+	if result := gjson.GetBytes(data, "errors"); result.IsArray() {
+		graphqlErrors = remapEntityErrors(result.Array(), missing)
+	}
+	setSubgraphResponseBody(resp, entitiesResponseBody(entities, graphqlErrors))
+	// The merged response expires with the first cached entity
+	if cacheable && cachedTTL < ttl {
+		resp.Header.Set("Cache-Control", fmt.Sprintf("max-age=%d", int(cachedTTL.Seconds())))
+		resp.Header.Del("Expires")
+	}
+	return resp, nil
+}
+
+// isSubgraphCacheableRequest excludes requests with side effects and subscriptions
+func isSubgraphCacheableRequest(req *http.Request) bool {
+	if req.Header.Get("Upgrade") != "" || req.Header.Get("Accept") == "text/event-stream" {
+		return false
+	}
+	// Mutations must always reach the subgraph
+	return !resolve.SingleFlightDisallowed(req.Context())
+}
+
+// subgraphRequestBody reads the body without consuming it for the next transport
+func subgraphRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		reader, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return body, nil
+}
+
+func withSubgraphRequestBody(req *http.Request, body []byte) *http.Request {
+	r := req.Clone(req.Context())
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	r.ContentLength = int64(len(body))
+	r.Header.Del("Content-Length")
+	return r
+}
+
+// readSubgraphResponse reads and decompresses the body and replaces it with the decompressed one.
+// It returns false without reading the body if the content encoding isn't supported.
+func readSubgraphResponse(resp *http.Response) ([]byte, bool, error) {
+	var reader io.Reader = resp.Body
+	switch resp.Header.Get("Content-Encoding") {
+	case "":
+	case "gzip":
+		gzipReader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			_ = resp.Body.Close()
+			return nil, false, err
+		}
+		reader = gzipReader
+	default:
+		return nil, false, nil
+	}
+
+	body, err := io.ReadAll(reader)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, false, err
+	}
+	resp.Header.Del("Content-Encoding")
+	setSubgraphResponseBody(resp, body)
+	return body, true, nil
+}
+
+func setSubgraphResponseBody(resp *http.Response, body []byte) {
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Del("Content-Length")
+}
+
+func entitiesResponseBody(entities [][]byte, graphqlErrors []byte) []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteString(`{"data":{"_entities":[`)
+	for i, entity := range entities {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(entity)
+	}
+	buf.WriteString(`]}`)
+	if len(graphqlErrors) > 0 {
+		buf.WriteString(`,"errors":`)
+		buf.Write(graphqlErrors)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
+
+// remapEntityErrors rewrites the entity index of the error paths from the fetched subset to the original request
+func remapEntityErrors(graphqlErrors []gjson.Result, indexes []int) []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteByte('[')
+	for i, graphqlError := range graphqlErrors {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		raw := []byte(graphqlError.Raw)
+		path := graphqlError.Get("path").Array()
+		if len(path) > 1 && path[0].String() == "_entities" && path[1].Type == gjson.Number {
+			if index := int(path[1].Int()); index >= 0 && index < len(indexes) {
+				if remapped, err := sjson.SetBytes(raw, "path.1", indexes[index]); err == nil {
+					raw = remapped
+				}
+			}
+		}
+		buf.Write(raw)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes()
+}
+
+func newSubgraphCacheResponse(req *http.Request, body []byte, ttl time.Duration) *http.Response {
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", http.StatusOK, http.StatusText(http.StatusOK)),
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header: http.Header{
+			"Content-Type":  []string{"application/json; charset=utf-8"},
+			"Cache-Control": []string{fmt.Sprintf("max-age=%d", int(ttl.Seconds()))},
+		},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+func setSubgraphCacheStatus(ctx context.Context, status string) {
+	otrace.SpanFromContext(ctx).SetAttributes(otel.WgSubgraphCacheStatus.String(status))
+}
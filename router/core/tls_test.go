@@ -0,0 +1,77 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientTLSConfig(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCertificate(t)
+
+	t.Run("uses the system pool by default", func(t *testing.T) {
+		tlsConfig, err := newClientTLSConfig("nats", "", "", "", false)
+		require.NoError(t, err)
+		require.Nil(t, tlsConfig.RootCAs)
+		require.Empty(t, tlsConfig.Certificates)
+		require.False(t, tlsConfig.InsecureSkipVerify)
+	})
+
+	t.Run("loads the CA file and the client certificate", func(t *testing.T) {
+		tlsConfig, err := newClientTLSConfig("nats", certFile, certFile, keyFile, true)
+		require.NoError(t, err)
+		require.NotNil(t, tlsConfig.RootCAs)
+		require.Len(t, tlsConfig.Certificates, 1)
+		require.True(t, tlsConfig.InsecureSkipVerify)
+	})
+
+	t.Run("returns an error for an invalid CA file", func(t *testing.T) {
+		_, err := newClientTLSConfig("nats", keyFile, "", "", false)
+		require.EqualError(t, err, "failed to append the nats CA certificate to the pool")
+	})
+
+	t.Run("returns an error for a missing client key", func(t *testing.T) {
+		_, err := newClientTLSConfig("kafka", "", certFile, filepath.Join(t.TempDir(), "missing.pem"), false)
+		require.ErrorContains(t, err, "failed to load the kafka client certificate")
+	})
+}
+
+func writeSelfSignedCertificate(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}), 0o600))
+
+	return certFile, keyFile
+}
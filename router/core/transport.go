@@ -2,6 +2,7 @@ package core
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -20,6 +21,7 @@ import (
 	"github.com/wundergraph/cosmo/router/pkg/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
 
+	"github.com/wundergraph/cosmo/router/internal/circuitbreaker"
 	"github.com/wundergraph/cosmo/router/internal/docker"
 	"github.com/wundergraph/cosmo/router/internal/retrytransport"
 	"github.com/wundergraph/graphql-go-tools/v2/pkg/engine/resolve"
@@ -152,6 +154,12 @@ func (ct *CustomTransport) RoundTrip(req *http.Request) (resp *http.Response, er
 		resp, err = ct.roundTripSingleFlight(req)
 	}
 
+	// Answer with a GraphQL error while the circuit of the subgraph is open. Upgrade requests
+	// can't be answered with a GraphQL response, they fail with the error.
+	if errors.Is(err, circuitbreaker.ErrOpen) && req.Header.Get("Upgrade") == "" {
+		resp, err = subgraphUnavailableResponse(req, moduleContext.ActiveSubgraph(req)), nil
+	}
+
 	// Set the error on the request context so that it can be checked by the post handlers
 	if err != nil {
 		moduleContext.sendError = err
@@ -330,6 +338,8 @@ type TransportFactory struct {
 	metricStore                   metric.Provider
 	logger                        *zap.Logger
 	tracerProvider                *sdktrace.TracerProvider
+	circuitBreakers               *subgraphCircuitBreakers
+	subgraphCache                 *subgraphResponseCache
 }
 
 var _ ApiTransportFactory = TransportFactory{}
@@ -343,6 +353,10 @@ type TransportOptions struct {
 	MetricStore                   metric.Provider
 	Logger                        *zap.Logger
 	TracerProvider                *sdktrace.TracerProvider
+	// circuitBreakers is nil if no subgraph has a circuit breaker
+	circuitBreakers *subgraphCircuitBreakers
+	// subgraphCache is nil if the subgraph response cache is disabled
+	subgraphCache *subgraphResponseCache
 }
 
 func NewTransport(opts *TransportOptions) *TransportFactory {
@@ -355,6 +369,8 @@ func NewTransport(opts *TransportOptions) *TransportFactory {
 		metricStore:                   opts.MetricStore,
 		logger:                        opts.Logger,
 		tracerProvider:                opts.TracerProvider,
+		circuitBreakers:               opts.circuitBreakers,
+		subgraphCache:                 opts.subgraphCache,
 	}
 }
 
@@ -379,6 +395,11 @@ func (t TransportFactory) RoundTripper(enableSingleFlight bool, transport http.R
 			if subgraph != nil {
 				commonAttributeValues = append(commonAttributeValues, otel.WgSubgraphID.String(subgraph.Id))
 				commonAttributeValues = append(commonAttributeValues, otel.WgSubgraphName.String(subgraph.Name))
+				if t.circuitBreakers != nil {
+					if state, ok := t.circuitBreakers.state(subgraph.Name); ok {
+						commonAttributeValues = append(commonAttributeValues, state)
+					}
+				}
 			}
 
 			if attributes := baseAttributesFromContext(r.Context()); attributes != nil {
@@ -391,13 +412,27 @@ func (t TransportFactory) RoundTripper(enableSingleFlight bool, transport http.R
 
 		}),
 	)
+	var roundTripper http.RoundTripper = traceTransport
+	if t.circuitBreakers != nil {
+		roundTripper = &circuitBreakerTransport{
+			roundTripper: traceTransport,
+			breakers:     t.circuitBreakers,
+			metricStore:  t.metricStore,
+		}
+	}
 	tp := NewCustomTransport(
 		t.logger,
-		traceTransport,
+		roundTripper,
 		t.retryOptions,
 		t.metricStore,
 		enableSingleFlight,
 	)
+	if t.subgraphCache != nil {
+		tp.roundTripper = &subgraphCacheTransport{
+			roundTripper: tp.roundTripper,
+			cache:        t.subgraphCache,
+		}
+	}
 
 	tp.preHandlers = t.preHandlers
 	tp.postHandlers = t.postHandlers
@@ -31,6 +31,7 @@ import (
 	"github.com/wundergraph/cosmo/router/pkg/logging"
 	"github.com/wundergraph/graphql-go-tools/v2/pkg/engine/plan"
 	"github.com/wundergraph/graphql-go-tools/v2/pkg/engine/resolve"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
 )
@@ -709,6 +710,13 @@ func (h *WebSocketConnectionHandler) writeErrorMessage(operationID string, err e
 	gqlErrors := []graphqlError{
 		{Message: err.Error()},
 	}
+	var blockedErr *OperationBlockedError
+	var pqNotFoundErr *PersistedQueryNotFoundError
+	if errors.As(err, &blockedErr) {
+		gqlErrors[0] = blockedErr.graphqlError()
+	} else if errors.As(err, &pqNotFoundErr) {
+		gqlErrors[0] = pqNotFoundErr.graphqlError()
+	}
 	payload, err := json.Marshal(gqlErrors)
 	if err != nil {
 		return fmt.Errorf("encoding GraphQL errors: %w", err)
@@ -729,6 +737,9 @@ func (h *WebSocketConnectionHandler) parseAndPlan(payload []byte) (*ParsedOperat
 	}
 	defer operationKit.Free()
 
+	// The limits are recorded on the span of the upgrade request
+	span := trace.SpanFromContext(h.ctx)
+
 	if err := operationKit.UnmarshalOperationFromBody(payload); err != nil {
 		return nil, nil, err
 	}
@@ -746,6 +757,9 @@ func (h *WebSocketConnectionHandler) parseAndPlan(payload []byte) (*ParsedOperat
 	// because the operation was already parsed. This is a performance optimization, and we
 	// can do it because we know that the persisted operation is immutable (identified by the hash)
 	if !skipParse {
+		if err := h.preHandler.validateTokenCount(operationKit, span); err != nil {
+			return nil, nil, err
+		}
 		if err := operationKit.Parse(); err != nil {
 			return nil, nil, err
 		}
@@ -767,6 +781,17 @@ func (h *WebSocketConnectionHandler) parseAndPlan(payload []byte) (*ParsedOperat
 		return nil, nil, err
 	}
 
+	if err := h.preHandler.validateOperationLimits(operationKit, span); err != nil {
+		return nil, nil, err
+	}
+
+	if h.operationBlocker.MatchesRootFields() {
+		operationKit.CollectRootFields(h.preHandler.executor.RouterSchema)
+	}
+	if len(h.preHandler.fieldPolicyCoordinates) > 0 {
+		operationKit.CollectFieldArguments(h.preHandler.executor.RouterSchema, h.preHandler.fieldPolicyCoordinates)
+	}
+
 	planOptions := PlanOptions{
 		Protocol:             OperationProtocolWS,
 		ClientInfo:           h.clientInfo,
@@ -779,6 +804,11 @@ func (h *WebSocketConnectionHandler) parseAndPlan(payload []byte) (*ParsedOperat
 	if err != nil {
 		return operationKit.parsedOperation, nil, err
 	}
+
+	if err := h.operationBlocker.OperationIsDenied(opContext, authentication.FromContext(h.r.Context())); err != nil {
+		return operationKit.parsedOperation, nil, err
+	}
+
 	opContext.initialPayload = h.initialPayload
 	return operationKit.parsedOperation, opContext, nil
 }
@@ -848,7 +878,7 @@ func (h *WebSocketConnectionHandler) executeSubscription(msg *wsproto.Message, i
 		resolveCtx = WithAuthorizationExtension(resolveCtx)
 		resolveCtx.SetAuthorizer(h.graphqlHandler.authorizer)
 	}
-	resolveCtx = h.graphqlHandler.configureRateLimiting(resolveCtx)
+	resolveCtx = h.graphqlHandler.configureRateLimiting(operationCtx, resolveCtx)
 
 	// Put in a closure to evaluate err after the defer
 	defer func() {
@@ -0,0 +1,250 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned by Allow when the circuit is open and the request must not be sent
+var ErrOpen = errors.New("circuit breaker is open")
+
+// windowBuckets is the number of buckets of the rolling window. Outcomes expire bucket by bucket.
+const windowBuckets = 10
+
+type State int
+
+const (
+	// StateClosed lets all requests pass and records their outcome
+	StateClosed State = iota
+	// StateOpen rejects all requests until the cooldown has passed
+	StateOpen
+	// StateHalfOpen lets a limited number of probe requests pass to decide if the circuit can be closed again
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half_open"
+	default:
+		return "unknown"
+	}
+}
+
+// Outcome is the result of a request that was allowed by the breaker
+type Outcome int
+
+const (
+	OutcomeSuccess Outcome = iota
+	OutcomeFailure
+	// OutcomeIgnored releases the request without recording it, e.g. if the client canceled it
+	// before the subgraph answered
+	OutcomeIgnored
+)
+
+type Options struct {
+	// ErrorThresholdPercentage is the percentage of failed requests in the window that opens the circuit
+	ErrorThresholdPercentage int
+	// LatencyThreshold counts requests that take longer as failed. It is disabled if zero.
+	LatencyThreshold time.Duration
+	// MinimumRequests is the number of requests in the window before the error rate is evaluated
+	MinimumRequests int
+	// Window is the duration of the rolling window the error rate is computed on
+	Window time.Duration
+	// Cooldown is the time the circuit stays open before probe requests are allowed
+	Cooldown time.Duration
+	// HalfOpenRequests is the number of successful probe requests that close the circuit again
+	HalfOpenRequests int
+	// OnStateChange is called after every state transition. It must not call the breaker.
+	OnStateChange func(from, to State)
+}
+
+type bucket struct {
+	start    time.Time
+	requests int
+	failures int
+}
+
+// Breaker is a circuit breaker with a closed, open and half-open state. It is safe for concurrent use.
+type Breaker struct {
+	opts           Options
+	bucketDuration time.Duration
+	now            func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	openedAt time.Time
+	buckets  [windowBuckets]bucket
+	// generation is incremented on every transition, outcomes of requests that were allowed
+	// in a previous state are ignored
+	generation        uint64
+	halfOpenInFlight  int
+	halfOpenSucceeded int
+}
+
+func New(opts Options) *Breaker {
+	if opts.ErrorThresholdPercentage <= 0 {
+		opts.ErrorThresholdPercentage = 50
+	}
+	if opts.MinimumRequests <= 0 {
+		opts.MinimumRequests = 1
+	}
+	if opts.Window <= 0 {
+		opts.Window = time.Minute
+	}
+	if opts.HalfOpenRequests <= 0 {
+		opts.HalfOpenRequests = 1
+	}
+	return &Breaker{
+		opts:           opts,
+		bucketDuration: opts.Window / windowBuckets,
+		now:            time.Now,
+	}
+}
+
+// State returns the current state. An open circuit only becomes half-open with the
+// first request after the cooldown.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// Allow returns ErrOpen if the request must not be sent. Otherwise, the returned function
+// must be called with the outcome of the request once it is done.
+func (b *Breaker) Allow() (func(outcome Outcome), error) {
+	b.mu.Lock()
+
+	var transition func()
+	if b.state == StateOpen {
+		if !b.cooldownPassed() {
+			b.mu.Unlock()
+			return nil, ErrOpen
+		}
+		transition = b.setState(StateHalfOpen)
+	}
+	if b.state == StateHalfOpen {
+		if b.halfOpenInFlight+b.halfOpenSucceeded >= b.opts.HalfOpenRequests {
+			b.mu.Unlock()
+			if transition != nil {
+				transition()
+			}
+			return nil, ErrOpen
+		}
+		b.halfOpenInFlight++
+	}
+
+	generation := b.generation
+	b.mu.Unlock()
+
+	if transition != nil {
+		transition()
+	}
+
+	start := b.now()
+	return func(outcome Outcome) {
+		if outcome == OutcomeSuccess && b.opts.LatencyThreshold > 0 && b.now().Sub(start) > b.opts.LatencyThreshold {
+			outcome = OutcomeFailure
+		}
+		b.record(generation, outcome)
+	}, nil
+}
+
+func (b *Breaker) record(generation uint64, outcome Outcome) {
+	b.mu.Lock()
+
+	if generation != b.generation {
+		b.mu.Unlock()
+		return
+	}
+
+	if outcome == OutcomeIgnored {
+		// An ignored probe frees its slot, so that another request can probe the subgraph
+		if b.state == StateHalfOpen {
+			b.halfOpenInFlight--
+		}
+		b.mu.Unlock()
+		return
+	}
+
+	failed := outcome == OutcomeFailure
+
+	var transition func()
+	switch b.state {
+	case StateClosed:
+		requests, failures := b.add(failed)
+		if requests >= b.opts.MinimumRequests && failures*100 >= requests*b.opts.ErrorThresholdPercentage {
+			transition = b.setState(StateOpen)
+		}
+	case StateHalfOpen:
+		b.halfOpenInFlight--
+		if failed {
+			transition = b.setState(StateOpen)
+		} else {
+			b.halfOpenSucceeded++
+			if b.halfOpenSucceeded >= b.opts.HalfOpenRequests {
+				transition = b.setState(StateClosed)
+			}
+		}
+	}
+
+	b.mu.Unlock()
+
+	if transition != nil {
+		transition()
+	}
+}
+
+// add records the outcome in the current bucket and returns the totals of the window
+func (b *Breaker) add(failed bool) (requests, failures int) {
+	now := b.now()
+	current := &b.buckets[(now.UnixNano()/int64(b.bucketDuration))%windowBuckets]
+	if now.Sub(current.start) >= b.bucketDuration {
+		*current = bucket{start: now.Truncate(b.bucketDuration)}
+	}
+	current.requests++
+	if failed {
+		current.failures++
+	}
+	for _, bucket := range b.buckets {
+		if now.Sub(bucket.start) < b.opts.Window {
+			requests += bucket.requests
+			failures += bucket.failures
+		}
+	}
+	return requests, failures
+}
+
+func (b *Breaker) cooldownPassed() bool {
+	return b.now().Sub(b.openedAt) >= b.opts.Cooldown
+}
+
+// setState must be called with the lock held. It returns the notification of the
+// transition, which is called after the lock is released.
+func (b *Breaker) setState(state State) func() {
+	from := b.state
+	b.state = state
+	b.generation++
+	b.halfOpenInFlight = 0
+	b.halfOpenSucceeded = 0
+
+	switch state {
+	case StateOpen:
+		b.openedAt = b.now()
+	case StateClosed:
+		b.buckets = [windowBuckets]bucket{}
+	}
+
+	if b.opts.OnStateChange == nil {
+		return nil
+	}
+	return func() {
+		b.opts.OnStateChange(from, state)
+	}
+}
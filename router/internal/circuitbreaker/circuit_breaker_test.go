@@ -0,0 +1,203 @@
+package circuitbreaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func newTestBreaker(opts Options) (*Breaker, *clock) {
+	c := &clock{now: time.Unix(1700000000, 0)}
+	b := New(opts)
+	b.now = c.Now
+	return b, c
+}
+
+func request(t *testing.T, b *Breaker, failed bool) {
+	t.Helper()
+	done, err := b.Allow()
+	require.NoError(t, err)
+	if failed {
+		done(OutcomeFailure)
+	} else {
+		done(OutcomeSuccess)
+	}
+}
+
+func TestBreakerOpensOnErrorRate(t *testing.T) {
+	b, _ := newTestBreaker(Options{
+		ErrorThresholdPercentage: 50,
+		MinimumRequests:          4,
+		Window:                   10 * time.Second,
+		Cooldown:                 5 * time.Second,
+	})
+
+	request(t, b, true)
+	request(t, b, true)
+	request(t, b, true)
+	require.Equal(t, StateClosed, b.State(), "the minimum number of requests is not reached")
+
+	request(t, b, false)
+	require.Equal(t, StateOpen, b.State())
+
+	_, err := b.Allow()
+	require.ErrorIs(t, err, ErrOpen)
+}
+
+func TestBreakerStaysClosedBelowErrorRate(t *testing.T) {
+	b, _ := newTestBreaker(Options{
+		ErrorThresholdPercentage: 50,
+		MinimumRequests:          4,
+		Window:                   10 * time.Second,
+		Cooldown:                 5 * time.Second,
+	})
+
+	request(t, b, true)
+	request(t, b, false)
+	request(t, b, false)
+	request(t, b, false)
+	request(t, b, false)
+	require.Equal(t, StateClosed, b.State())
+}
+
+func TestBreakerForgetsOutcomesOutsideOfWindow(t *testing.T) {
+	b, c := newTestBreaker(Options{
+		ErrorThresholdPercentage: 50,
+		MinimumRequests:          2,
+		Window:                   10 * time.Second,
+		Cooldown:                 5 * time.Second,
+	})
+
+	request(t, b, true)
+	c.now = c.now.Add(11 * time.Second)
+	request(t, b, true)
+	require.Equal(t, StateClosed, b.State())
+}
+
+func TestBreakerCountsSlowRequestsAsFailed(t *testing.T) {
+	b, c := newTestBreaker(Options{
+		ErrorThresholdPercentage: 50,
+		LatencyThreshold:         time.Second,
+		MinimumRequests:          1,
+		Window:                   10 * time.Second,
+		Cooldown:                 5 * time.Second,
+	})
+
+	done, err := b.Allow()
+	require.NoError(t, err)
+	c.now = c.now.Add(2 * time.Second)
+	done(OutcomeSuccess)
+	require.Equal(t, StateOpen, b.State())
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	var transitions []string
+	opts := Options{
+		ErrorThresholdPercentage: 50,
+		MinimumRequests:          1,
+		Window:                   10 * time.Second,
+		Cooldown:                 5 * time.Second,
+		HalfOpenRequests:         2,
+		OnStateChange: func(from, to State) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		},
+	}
+
+	t.Run("closes after successful probes", func(t *testing.T) {
+		transitions = nil
+		b, c := newTestBreaker(opts)
+
+		request(t, b, true)
+		c.now = c.now.Add(5 * time.Second)
+		require.Equal(t, StateOpen, b.State())
+
+		first, err := b.Allow()
+		require.NoError(t, err)
+		require.Equal(t, StateHalfOpen, b.State())
+		second, err := b.Allow()
+		require.NoError(t, err)
+		_, err = b.Allow()
+		require.ErrorIs(t, err, ErrOpen, "only two probes are allowed")
+
+		first(OutcomeSuccess)
+		second(OutcomeSuccess)
+		require.Equal(t, StateClosed, b.State())
+		require.Equal(t, []string{"closed->open", "open->half_open", "half_open->closed"}, transitions)
+	})
+
+	t.Run("ignored probes don't close the circuit", func(t *testing.T) {
+		transitions = nil
+		b, c := newTestBreaker(opts)
+
+		request(t, b, true)
+		c.now = c.now.Add(5 * time.Second)
+
+		first, err := b.Allow()
+		require.NoError(t, err)
+		second, err := b.Allow()
+		require.NoError(t, err)
+
+		first(OutcomeIgnored)
+		second(OutcomeSuccess)
+		require.Equal(t, StateHalfOpen, b.State(), "the canceled probe doesn't count as a success")
+
+		// The slot of the ignored probe is free again
+		request(t, b, false)
+		require.Equal(t, StateClosed, b.State())
+	})
+
+	t.Run("opens again after a failed probe", func(t *testing.T) {
+		transitions = nil
+		b, c := newTestBreaker(opts)
+
+		request(t, b, true)
+		c.now = c.now.Add(5 * time.Second)
+
+		request(t, b, true)
+		require.Equal(t, StateOpen, b.State())
+		require.Equal(t, []string{"closed->open", "open->half_open", "half_open->open"}, transitions)
+	})
+}
+
+func TestBreakerIgnoresOutcomesOfPreviousState(t *testing.T) {
+	b, _ := newTestBreaker(Options{
+		ErrorThresholdPercentage: 50,
+		MinimumRequests:          1,
+		Window:                   10 * time.Second,
+		Cooldown:                 5 * time.Second,
+	})
+
+	slow, err := b.Allow()
+	require.NoError(t, err)
+	request(t, b, true)
+	require.Equal(t, StateOpen, b.State())
+
+	// The request was allowed before the circuit opened
+	slow(OutcomeSuccess)
+	require.Equal(t, StateOpen, b.State())
+}
+
+func TestBreakerIgnoredOutcomesAreNotRecorded(t *testing.T) {
+	b, _ := newTestBreaker(Options{
+		ErrorThresholdPercentage: 50,
+		MinimumRequests:          1,
+		Window:                   10 * time.Second,
+		Cooldown:                 5 * time.Second,
+	})
+
+	done, err := b.Allow()
+	require.NoError(t, err)
+	done(OutcomeIgnored)
+	request(t, b, false)
+	request(t, b, true)
+	require.Equal(t, StateOpen, b.State(), "one failure of two recorded requests reaches the threshold")
+}
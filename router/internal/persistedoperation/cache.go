@@ -18,13 +18,35 @@ func (c *OperationsCache) key(clientName string, operationHash string) string {
 	return clientName + operationHash
 }
 
+// sharedKey is the key of operations that are available to every client. The NUL byte can't
+// be part of a client name, so that shared keys never collide with the keys of a client.
+func (c *OperationsCache) sharedKey(operationHash string) string {
+	return "\x00" + operationHash
+}
+
 func (c *OperationsCache) Get(clientName string, operationHash string) []byte {
 	// Since we're returning nil when the item is not found, we don't need to
 	// check the return value from the cache nor the type assertion
-	item, _ := c.Cache.Get(c.key(clientName, operationHash))
+	if item, _ := c.Cache.Get(c.key(clientName, operationHash)); item != nil {
+		return item
+	}
+	item, _ := c.Cache.Get(c.sharedKey(operationHash))
 	return item
 }
 
+// Preload replaces the cached operations. Operations without a client name are available to every client.
+func (c *OperationsCache) Preload(operations []Operation) {
+	c.Cache.Clear()
+	for _, operation := range operations {
+		key := c.key(operation.ClientName, operation.Sha256Hash)
+		if operation.ClientName == "" {
+			key = c.sharedKey(operation.Sha256Hash)
+		}
+		c.Cache.Set(key, operation.Body, int64(len(operation.Body)))
+	}
+	c.Cache.Wait()
+}
+
 func (c *OperationsCache) Set(clientName, operationHash string, operationBody []byte) {
 	c.Cache.Set(c.key(clientName, operationHash), operationBody, int64(len(operationBody)))
 }
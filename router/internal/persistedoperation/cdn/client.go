@@ -9,6 +9,7 @@ import (
 	"github.com/wundergraph/cosmo/router/internal/httpclient"
 	"github.com/wundergraph/cosmo/router/internal/jwt"
 	"github.com/wundergraph/cosmo/router/internal/persistedoperation"
+	"github.com/wundergraph/cosmo/router/pkg/controlplane"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
@@ -19,11 +20,17 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type Options struct {
 	Logger        *zap.Logger
 	TraceProvider *sdktrace.TracerProvider
+	// ManifestEnabled downloads the manifest of all persisted operations at startup and every ManifestPollInterval
+	ManifestEnabled      bool
+	ManifestPollInterval time.Duration
 }
 
 type client struct {
@@ -38,9 +45,23 @@ type client struct {
 	httpClient     *http.Client
 	logger         *zap.Logger
 	tracer         trace.Tracer
+
+	// manifest is nil until the manifest is downloaded
+	manifest atomic.Pointer[manifest]
+	// loadMu guards load, which is called with the operations of every new manifest
+	loadMu sync.Mutex
+	load   func(operations []persistedoperation.Operation)
+	poller controlplane.Poller
+	cancel context.CancelFunc
 }
 
 func (cdn *client) PersistedOperation(ctx context.Context, clientName string, sha256Hash string, attributes []attribute.KeyValue) ([]byte, error) {
+	// Operations that were published after the manifest was downloaded are still loaded one by one
+	if m := cdn.manifest.Load(); m != nil {
+		if body, ok := m.Operations[sha256Hash]; ok {
+			return []byte(body), nil
+		}
+	}
 
 	ctx, span := cdn.tracer.Start(ctx, "Load Persisted Operation",
 		trace.WithSpanKind(trace.SpanKindClient),
@@ -154,7 +175,7 @@ func NewClient(endpoint string, token string, opts Options) (persistedoperation.
 
 	logger := opts.Logger.With(zap.String("component", "persisted_operations_client"))
 
-	return &client{
+	c := &client{
 		cdnURL:              u,
 		authenticationToken: token,
 		federatedGraphID:    url.PathEscape(claims.FederatedGraphID),
@@ -165,7 +186,18 @@ func NewClient(endpoint string, token string, opts Options) (persistedoperation.
 			"wundergraph/cosmo/router/cdn_persisted_operations_client",
 			trace.WithInstrumentationVersion("0.0.1"),
 		),
-	}, nil
+	}
+
+	if opts.ManifestEnabled {
+		c.startManifestPoller(opts.ManifestPollInterval)
+	}
+
+	return c, nil
 }
 
-func (cdn *client) Close() {}
+func (cdn *client) Close() {
+	if cdn.cancel != nil {
+		cdn.cancel()
+		_ = cdn.poller.Stop()
+	}
+}
@@ -0,0 +1,154 @@
+package cdn
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/goccy/go-json"
+	"github.com/wundergraph/cosmo/router/internal/persistedoperation"
+	"github.com/wundergraph/cosmo/router/pkg/controlplane"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// manifest contains all persisted operations of the federated graph by their sha256 hash
+type manifest struct {
+	Version     int               `json:"version"`
+	Revision    string            `json:"revision"`
+	GeneratedAt string            `json:"generatedAt"`
+	Operations  map[string]string `json:"operations"`
+
+	// etag is sent with the next request, so that the CDN only responds if the manifest has changed
+	etag string
+}
+
+func (m *manifest) list() []persistedoperation.Operation {
+	list := make([]persistedoperation.Operation, 0, len(m.Operations))
+	for sha256Hash, body := range m.Operations {
+		// The operations of the manifest are available to every client
+		list = append(list, persistedoperation.Operation{Sha256Hash: sha256Hash, Body: []byte(body)})
+	}
+	return list
+}
+
+// Preload calls load with the operations of the manifest. It's a noop if the manifest is disabled.
+func (cdn *client) Preload(load func(operations []persistedoperation.Operation)) {
+	cdn.loadMu.Lock()
+	defer cdn.loadMu.Unlock()
+
+	cdn.load = load
+	if m := cdn.manifest.Load(); m != nil {
+		load(m.list())
+	}
+}
+
+// startManifestPoller downloads the manifest before it returns, so that the operations are
+// available to the first request. A failed download doesn't prevent the router from starting,
+// the operations are loaded one by one until the manifest is available.
+func (cdn *client) startManifestPoller(interval time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cdn.cancel = cancel
+
+	if err := cdn.updateManifest(ctx); err != nil {
+		cdn.logger.Warn("Failed to download the persisted operations manifest", zap.Error(err))
+	}
+
+	cdn.poller = controlplane.NewPoll(interval)
+	cdn.poller.Subscribe(ctx, func() {
+		if err := cdn.updateManifest(ctx); err != nil {
+			cdn.logger.Error("Failed to update the persisted operations manifest", zap.Error(err))
+		}
+	})
+}
+
+func (cdn *client) updateManifest(ctx context.Context) error {
+	var etag string
+	if m := cdn.manifest.Load(); m != nil {
+		etag = m.etag
+	}
+
+	m, err := cdn.fetchManifest(ctx, etag)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		// not modified
+		return nil
+	}
+
+	cdn.loadMu.Lock()
+	defer cdn.loadMu.Unlock()
+
+	cdn.manifest.Store(m)
+	if cdn.load != nil {
+		cdn.load(m.list())
+	}
+
+	cdn.logger.Info("Persisted operations manifest updated",
+		zap.String("revision", m.Revision),
+		zap.Int("operations", len(m.Operations)),
+	)
+
+	return nil
+}
+
+// fetchManifest returns nil if the manifest hasn't changed since the etag
+func (cdn *client) fetchManifest(ctx context.Context, etag string) (*manifest, error) {
+	manifestPath := fmt.Sprintf("/%s/%s/operations/manifest.json",
+		cdn.organizationID,
+		cdn.federatedGraphID)
+	manifestURL := cdn.cdnURL.ResolveReference(&url.URL{Path: manifestPath})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, manifestURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Authorization", "Bearer "+cdn.authenticationToken)
+	req.Header.Set("Accept-Encoding", "gzip")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := cdn.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil, nil
+	case http.StatusUnauthorized:
+		return nil, errors.New("could not authenticate against CDN")
+	default:
+		return nil, fmt.Errorf("unexpected status code when loading persisted operations manifest, statusCode: %d", resp.StatusCode)
+	}
+
+	var reader io.Reader = resp.Body
+
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		r, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, errors.New("could not create gzip reader. " + err.Error())
+		}
+		defer r.Close()
+		reader = r
+	}
+
+	var m manifest
+	if err := json.NewDecoder(reader).Decode(&m); err != nil {
+		return nil, fmt.Errorf("could not decode the persisted operations manifest: %w", err)
+	}
+	if m.Version != 1 {
+		return nil, fmt.Errorf("unsupported version %d of the persisted operations manifest", m.Version)
+	}
+	m.etag = resp.Header.Get("ETag")
+
+	return &m, nil
+}
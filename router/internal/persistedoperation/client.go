@@ -6,6 +6,7 @@ import (
 	"github.com/dgraph-io/ristretto"
 	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
+	"sync/atomic"
 )
 
 type PersistedOperation struct {
@@ -27,6 +28,25 @@ type Client interface {
 	Close()
 }
 
+// Operation is a persisted operation of a client. Operations without a client name are available to every client.
+type Operation struct {
+	ClientName string
+	Sha256Hash string
+	Body       []byte
+}
+
+// Preloader is implemented by providers that know all of their operations up front
+type Preloader interface {
+	// Preload calls load with all operations of the provider and again every time they change
+	Preload(load func(operations []Operation))
+}
+
+// OperationLister is implemented by clients that know all of their operations up front
+type OperationLister interface {
+	// Operations returns nil if the operations aren't known
+	Operations() []Operation
+}
+
 type Options struct {
 	// CacheSize indicates the in-memory cache size, in bytes. If 0, no in-memory
 	// cache is used.
@@ -41,6 +61,8 @@ type client struct {
 
 	cache          *OperationsCache
 	providerClient Client
+	// preloaded holds the latest operations of a Preloader provider
+	preloaded *atomic.Pointer[[]Operation]
 }
 
 func NewClient(opts *Options) (Client, error) {
@@ -62,11 +84,23 @@ func NewClient(opts *Options) (Client, error) {
 		}
 	}
 
-	return &client{
+	c := &client{
 		options:        opts,
 		providerClient: opts.ProviderClient,
 		cache:          &OperationsCache{Cache: cache},
-	}, nil
+		preloaded:      &atomic.Pointer[[]Operation]{},
+	}
+
+	if preloader, ok := opts.ProviderClient.(Preloader); ok {
+		preloader.Preload(func(operations []Operation) {
+			c.preloaded.Store(&operations)
+			if cache != nil {
+				c.cache.Preload(operations)
+			}
+		})
+	}
+
+	return c, nil
 }
 
 func (c client) PersistedOperation(ctx context.Context, clientName string, sha256Hash string, attributes []attribute.KeyValue) ([]byte, error) {
@@ -84,6 +118,13 @@ func (c client) PersistedOperation(ctx context.Context, clientName string, sha25
 	return content, nil
 }
 
+func (c client) Operations() []Operation {
+	if operations := c.preloaded.Load(); operations != nil {
+		return *operations
+	}
+	return nil
+}
+
 func (c client) Close() {
 	c.providerClient.Close()
 }
@@ -0,0 +1,265 @@
+package filesystem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/wundergraph/cosmo/router/internal/persistedoperation"
+	"github.com/wundergraph/cosmo/router/pkg/watcher"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+const apolloManifestFormat = "apollo-persisted-query-manifest"
+
+type Options struct {
+	// Watch reloads the operations when a file changes
+	Watch  bool
+	Logger *zap.Logger
+}
+
+type client struct {
+	path       string
+	logger     *zap.Logger
+	operations atomic.Pointer[operations]
+
+	// reloadMu serializes the reloads of the watchers
+	reloadMu sync.Mutex
+	// load is called with all operations after every reload
+	load   func(operations []persistedoperation.Operation)
+	cancel context.CancelFunc
+}
+
+type operations struct {
+	// clients holds the operations of the directory layout by client name and hash
+	clients map[string]map[string][]byte
+	// shared holds the operations of a manifest. They are available to every client.
+	shared map[string][]byte
+}
+
+// apolloManifest is the persisted query manifest generated by the Apollo tooling
+type apolloManifest struct {
+	Format     string `json:"format"`
+	Version    int    `json:"version"`
+	Operations []struct {
+		ID   string `json:"id"`
+		Body string `json:"body"`
+	} `json:"operations"`
+}
+
+// NewClient creates a client that reads the persisted operations from the path. The path is either a directory
+// with the layout <client>/<sha256>.json or a single manifest file in the Apollo or Relay format. All operations
+// are read up front.
+func NewClient(path string, opts *Options) (persistedoperation.Client, error) {
+	c := &client{
+		path:   path,
+		logger: opts.Logger,
+	}
+
+	ops, err := readOperations(path)
+	if err != nil {
+		return nil, err
+	}
+	c.operations.Store(ops)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+
+	if opts.Watch {
+		if err := c.watch(ctx); err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to watch persisted operations: %w", err)
+		}
+	}
+
+	return c, nil
+}
+
+func (c *client) PersistedOperation(_ context.Context, clientName string, sha256Hash string, _ []attribute.KeyValue) ([]byte, error) {
+	ops := c.operations.Load()
+	if body, ok := ops.clients[clientName][sha256Hash]; ok {
+		return body, nil
+	}
+	if body, ok := ops.shared[sha256Hash]; ok {
+		return body, nil
+	}
+	return nil, &persistedoperation.PersistentOperationNotFoundError{
+		ClientName: clientName,
+		Sha256Hash: sha256Hash,
+	}
+}
+
+func (c *client) Preload(load func(operations []persistedoperation.Operation)) {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	c.load = load
+	load(c.operations.Load().list())
+}
+
+func (c *client) Close() {
+	c.cancel()
+}
+
+// watch reloads all operations on every change. The watcher only reports the events of the files of a
+// directory, so every existing client directory gets its own watcher. A client directory that is created
+// later is added to the watch list of the root directory's watcher when its create event arrives, so the
+// changes in it are picked up as well.
+func (c *client) watch(ctx context.Context) error {
+	paths := []string{c.path}
+
+	stat, err := os.Stat(c.path)
+	if err != nil {
+		return err
+	}
+	if stat.IsDir() {
+		entries, err := os.ReadDir(c.path)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				paths = append(paths, filepath.Join(c.path, entry.Name()))
+			}
+		}
+	}
+
+	for _, path := range paths {
+		w, err := watcher.NewWatcher(c.logger.With(zap.String("watcher", "persisted_operations")))
+		if err != nil {
+			return err
+		}
+		if err := w.Watch(ctx, path, func(events []watcher.Event) error {
+			c.reload()
+			// Returning an error would stop the watcher
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *client) reload() {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	ops, err := readOperations(c.path)
+	if err != nil {
+		c.logger.Error("Failed to reload persisted operations. Keeping the previous operations", zap.Error(err))
+		return
+	}
+	c.operations.Store(ops)
+
+	if c.load != nil {
+		c.load(ops.list())
+	}
+
+	c.logger.Info("Persisted operations reloaded", zap.String("path", c.path))
+}
+
+func (o *operations) list() []persistedoperation.Operation {
+	list := make([]persistedoperation.Operation, 0, len(o.shared))
+	for clientName, clientOperations := range o.clients {
+		for sha256Hash, body := range clientOperations {
+			list = append(list, persistedoperation.Operation{ClientName: clientName, Sha256Hash: sha256Hash, Body: body})
+		}
+	}
+	for sha256Hash, body := range o.shared {
+		list = append(list, persistedoperation.Operation{Sha256Hash: sha256Hash, Body: body})
+	}
+	return list
+}
+
+func readOperations(path string) (*operations, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read persisted operations: %w", err)
+	}
+	if stat.IsDir() {
+		return readDirectory(path)
+	}
+	return readManifest(path)
+}
+
+func readDirectory(path string) (*operations, error) {
+	ops := &operations{
+		clients: map[string]map[string][]byte{},
+	}
+
+	clientDirs, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read persisted operations: %w", err)
+	}
+	for _, clientDir := range clientDirs {
+		if !clientDir.IsDir() {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(path, clientDir.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read persisted operations: %w", err)
+		}
+
+		clientOperations := make(map[string][]byte, len(files))
+		for _, file := range files {
+			if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+				continue
+			}
+			filePath := filepath.Join(path, clientDir.Name(), file.Name())
+			data, err := os.ReadFile(filePath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read persisted operation %s: %w", filePath, err)
+			}
+			var po persistedoperation.PersistedOperation
+			if err := json.Unmarshal(data, &po); err != nil {
+				return nil, fmt.Errorf("failed to parse persisted operation %s: %w", filePath, err)
+			}
+			clientOperations[strings.TrimSuffix(file.Name(), ".json")] = []byte(po.Body)
+		}
+		ops.clients[clientDir.Name()] = clientOperations
+	}
+
+	return ops, nil
+}
+
+// readManifest reads a manifest in the Apollo format or a Relay map of operation IDs to queries
+func readManifest(path string) (*operations, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read persisted operation manifest: %w", err)
+	}
+
+	var apollo apolloManifest
+	if err := json.Unmarshal(data, &apollo); err == nil && apollo.Format == apolloManifestFormat {
+		if apollo.Version != 1 {
+			return nil, fmt.Errorf("unsupported version %d of the persisted query manifest", apollo.Version)
+		}
+		ops := &operations{
+			shared: make(map[string][]byte, len(apollo.Operations)),
+		}
+		for _, operation := range apollo.Operations {
+			ops.shared[operation.ID] = []byte(operation.Body)
+		}
+		return ops, nil
+	}
+
+	var relay map[string]string
+	if err := json.Unmarshal(data, &relay); err != nil {
+		return nil, errors.New("failed to parse persisted operation manifest: expected an Apollo or Relay manifest")
+	}
+	ops := &operations{
+		shared: make(map[string][]byte, len(relay)),
+	}
+	for id, body := range relay {
+		ops.shared[id] = []byte(body)
+	}
+	return ops, nil
+}
@@ -0,0 +1,137 @@
+package filesystem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/wundergraph/cosmo/router/internal/persistedoperation"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "my-client", "abc.json"), `{"version":1,"body":"query { a }"}`)
+
+	c, err := NewClient(dir, &Options{Logger: zap.NewNop()})
+	require.NoError(t, err)
+	defer c.Close()
+
+	body, err := c.PersistedOperation(context.Background(), "my-client", "abc", nil)
+	require.NoError(t, err)
+	require.Equal(t, "query { a }", string(body))
+
+	_, err = c.PersistedOperation(context.Background(), "other-client", "abc", nil)
+	var notFound *persistedoperation.PersistentOperationNotFoundError
+	require.ErrorAs(t, err, &notFound)
+}
+
+func TestManifest(t *testing.T) {
+	t.Run("apollo", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "manifest.json")
+		writeFile(t, path, `{"format":"apollo-persisted-query-manifest","version":1,"operations":[{"id":"abc","name":"A","type":"query","body":"query A { a }"}]}`)
+
+		c, err := NewClient(path, &Options{Logger: zap.NewNop()})
+		require.NoError(t, err)
+		defer c.Close()
+
+		body, err := c.PersistedOperation(context.Background(), "any-client", "abc", nil)
+		require.NoError(t, err)
+		require.Equal(t, "query A { a }", string(body))
+	})
+
+	t.Run("relay", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "persisted-queries.json")
+		writeFile(t, path, `{"abc":"query A { a }"}`)
+
+		c, err := NewClient(path, &Options{Logger: zap.NewNop()})
+		require.NoError(t, err)
+		defer c.Close()
+
+		body, err := c.PersistedOperation(context.Background(), "any-client", "abc", nil)
+		require.NoError(t, err)
+		require.Equal(t, "query A { a }", string(body))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "manifest.json")
+		writeFile(t, path, `[]`)
+
+		_, err := NewClient(path, &Options{Logger: zap.NewNop()})
+		require.Error(t, err)
+	})
+}
+
+func TestPreload(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "my-client", "abc.json"), `{"version":1,"body":"query { a }"}`)
+
+	c, err := NewClient(dir, &Options{Logger: zap.NewNop()})
+	require.NoError(t, err)
+	defer c.Close()
+
+	var preloaded []persistedoperation.Operation
+	c.(persistedoperation.Preloader).Preload(func(operations []persistedoperation.Operation) {
+		preloaded = operations
+	})
+	require.Equal(t, []persistedoperation.Operation{
+		{ClientName: "my-client", Sha256Hash: "abc", Body: []byte("query { a }")},
+	}, preloaded)
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "my-client", "abc.json"), `{"version":1,"body":"query { a }"}`)
+
+	c, err := NewClient(dir, &Options{Watch: true, Logger: zap.NewNop()})
+	require.NoError(t, err)
+	defer c.Close()
+
+	var reloads atomic.Int64
+	c.(persistedoperation.Preloader).Preload(func(operations []persistedoperation.Operation) {
+		reloads.Add(1)
+	})
+
+	writeFile(t, filepath.Join(dir, "my-client", "def.json"), `{"version":1,"body":"query { b }"}`)
+
+	require.Eventually(t, func() bool {
+		body, err := c.PersistedOperation(context.Background(), "my-client", "def", nil)
+		return err == nil && string(body) == "query { b }"
+	}, 5*time.Second, 20*time.Millisecond)
+	require.Greater(t, reloads.Load(), int64(1))
+}
+
+func TestWatchNewClientDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "my-client", "abc.json"), `{"version":1,"body":"query { a }"}`)
+
+	c, err := NewClient(dir, &Options{Watch: true, Logger: zap.NewNop()})
+	require.NoError(t, err)
+	defer c.Close()
+
+	writeFile(t, filepath.Join(dir, "new-client", "abc.json"), `{"version":1,"body":"query { a }"}`)
+
+	require.Eventually(t, func() bool {
+		body, err := c.PersistedOperation(context.Background(), "new-client", "abc", nil)
+		return err == nil && string(body) == "query { a }"
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// Changes in the directory are picked up after it was loaded
+	writeFile(t, filepath.Join(dir, "new-client", "def.json"), `{"version":1,"body":"query { b }"}`)
+
+	require.Eventually(t, func() bool {
+		body, err := c.PersistedOperation(context.Background(), "new-client", "def", nil)
+		return err == nil && string(body) == "query { b }"
+	}, 5*time.Second, 20*time.Millisecond)
+}
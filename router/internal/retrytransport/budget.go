@@ -0,0 +1,87 @@
+package retrytransport
+
+import (
+	"sync"
+	"time"
+)
+
+// budgetBuckets is the number of buckets of the rolling window. Requests and retries expire bucket by bucket.
+const budgetBuckets = 10
+
+type budgetBucket struct {
+	start    time.Time
+	requests int
+	retries  int
+}
+
+// Budget limits the retries to a percentage of the requests in a rolling window. Without a budget,
+// every failed request is retried up to the max retry count, which multiplies the load on a subgraph
+// that is already overloaded. It is safe for concurrent use.
+type Budget struct {
+	percentage     int
+	minRetries     int
+	window         time.Duration
+	bucketDuration time.Duration
+	now            func() time.Time
+
+	mu      sync.Mutex
+	buckets [budgetBuckets]budgetBucket
+}
+
+// NewBudget returns a budget that allows retries up to the given percentage of the requests in the window.
+// MinRetries retries are allowed in the window regardless of the percentage, so that routers with little
+// traffic can still retry.
+func NewBudget(percentage, minRetries int, window time.Duration) *Budget {
+	if window <= 0 {
+		window = 10 * time.Second
+	}
+	return &Budget{
+		percentage:     percentage,
+		minRetries:     minRetries,
+		window:         window,
+		bucketDuration: window / budgetBuckets,
+		now:            time.Now,
+	}
+}
+
+// Request records a request that was sent for the first time
+func (b *Budget) Request() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.current().requests++
+}
+
+// Retry returns true and withdraws a retry from the budget if the budget allows it
+func (b *Budget) Retry() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	current := b.current()
+
+	var requests, retries int
+	now := b.now()
+	for _, bucket := range b.buckets {
+		if now.Sub(bucket.start) < b.window {
+			requests += bucket.requests
+			retries += bucket.retries
+		}
+	}
+
+	if retries >= b.minRetries && retries*100 >= requests*b.percentage {
+		return false
+	}
+
+	current.retries++
+	return true
+}
+
+// current returns the bucket of the current time, expired buckets are reset
+func (b *Budget) current() *budgetBucket {
+	now := b.now()
+	current := &b.buckets[(now.UnixNano()/int64(b.bucketDuration))%budgetBuckets]
+	if now.Sub(current.start) >= b.bucketDuration {
+		*current = budgetBucket{start: now.Truncate(b.bucketDuration)}
+	}
+	return current
+}
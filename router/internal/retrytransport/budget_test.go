@@ -0,0 +1,29 @@
+package retrytransport
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudget(t *testing.T) {
+
+	now := time.Unix(1700000000, 0)
+	b := NewBudget(10, 1, 10*time.Second)
+	b.now = func() time.Time { return now }
+
+	for i := 0; i < 20; i++ {
+		b.Request()
+	}
+
+	// 10% of 20 requests
+	assert.True(t, b.Retry())
+	assert.True(t, b.Retry())
+	assert.False(t, b.Retry())
+
+	// The requests and retries expire with the window
+	now = now.Add(11 * time.Second)
+	assert.True(t, b.Retry(), "the minimum retries are always allowed")
+	assert.False(t, b.Retry())
+}
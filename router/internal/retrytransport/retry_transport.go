@@ -2,12 +2,15 @@ package retrytransport
 
 import (
 	"errors"
-	"github.com/cloudflare/backoff"
-	"go.uber.org/zap"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
+
+	"github.com/cloudflare/backoff"
+	"go.uber.org/zap"
 )
 
 var defaultRetryableErrors = []error{
@@ -40,6 +43,47 @@ type RetryOptions struct {
 	MaxDuration   time.Duration
 	OnRetry       func(count int, req *http.Request, resp *http.Response, err error)
 	ShouldRetry   ShouldRetryFunc
+	// StatusCodes are the retryable status codes. The default status codes are used if empty.
+	StatusCodes []int
+	// Errors are the retryable network errors, an error is retryable if its message ends with one of them.
+	// The default errors are used if empty.
+	Errors []string
+	// Budget limits the retries of all transports that share it. The retries are not limited if nil.
+	Budget *Budget
+}
+
+// IsRetryable returns true if the error or the status code of the response is retryable
+// according to the configured status codes and errors.
+func (o *RetryOptions) IsRetryable(err error, resp *http.Response) bool {
+	if err != nil {
+		s := strings.ToLower(err.Error())
+		if len(o.Errors) == 0 {
+			for _, retryableError := range defaultRetryableErrors {
+				if strings.HasSuffix(s, strings.ToLower(retryableError.Error())) {
+					return true
+				}
+			}
+		}
+		for _, retryableError := range o.Errors {
+			if strings.HasSuffix(s, strings.ToLower(retryableError)) {
+				return true
+			}
+		}
+	}
+
+	if resp != nil {
+		statusCodes := o.StatusCodes
+		if len(statusCodes) == 0 {
+			statusCodes = defaultRetryableStatusCodes
+		}
+		for _, retryableStatusCode := range statusCodes {
+			if resp.StatusCode == retryableStatusCode {
+				return true
+			}
+		}
+	}
+
+	return false
 }
 
 type RetryHTTPTransport struct {
@@ -57,6 +101,9 @@ func NewRetryHTTPTransport(roundTripper http.RoundTripper, retryOptions RetryOpt
 }
 
 func (rt *RetryHTTPTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if rt.RetryOptions.Budget != nil {
+		rt.RetryOptions.Budget.Request()
+	}
 
 	resp, err := rt.RoundTripper.RoundTrip(req)
 	// Short circuit if the request was successful.
@@ -70,14 +117,29 @@ func (rt *RetryHTTPTransport) RoundTrip(req *http.Request) (*http.Response, erro
 	// Retry logic
 	retries := 0
 	for rt.RetryOptions.ShouldRetry(err, req, resp) && retries < rt.RetryOptions.MaxRetryCount {
+		// A body that was already sent can only be sent again if it can be recreated
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			break
+		}
+
+		if rt.RetryOptions.Budget != nil && !rt.RetryOptions.Budget.Retry() {
+			rt.Logger.Debug("Retry budget exhausted, not retrying request",
+				zap.String("url", req.URL.String()),
+			)
+			break
+		}
+
 		if rt.RetryOptions.OnRetry != nil {
 			rt.RetryOptions.OnRetry(retries, req, resp, err)
 		}
 
 		retries++
 
-		// Wait for the specified backoff period
+		// Wait for the specified backoff period or the period the server asked for
 		sleepDuration := b.Duration()
+		if retryAfter, ok := retryAfterDuration(resp); ok {
+			sleepDuration = min(retryAfter, rt.RetryOptions.MaxDuration)
+		}
 
 		rt.Logger.Debug("Retrying request",
 			zap.Int("retry", retries),
@@ -85,8 +147,25 @@ func (rt *RetryHTTPTransport) RoundTrip(req *http.Request) (*http.Response, erro
 			zap.Duration("sleep", sleepDuration),
 		)
 
-		// Wait for the specified backoff period
-		time.Sleep(sleepDuration)
+		// The response is discarded, release the connection before waiting
+		drainBody(resp)
+
+		// Wait for the specified backoff period, but not longer than the client waits for the response
+		timer := time.NewTimer(sleepDuration)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
 
 		// Retry the request
 		resp, err = rt.RoundTripper.RoundTrip(req)
@@ -107,28 +186,35 @@ func isResponseOK(resp *http.Response) bool {
 	return resp.StatusCode >= 200 && resp.StatusCode < 300
 }
 
-func IsRetryableError(err error, resp *http.Response) bool {
-
-	if err != nil {
-		// Network
-		s := err.Error()
-		for _, retryableError := range defaultRetryableErrors {
-			if strings.HasSuffix(
-				strings.ToLower(s),
-				strings.ToLower(retryableError.Error())) {
-				return true
-			}
-		}
+// retryAfterDuration returns the duration of the Retry-After header of 429 and 503 responses.
+// The header contains either the number of seconds to wait or an HTTP date.
+func retryAfterDuration(resp *http.Response) (time.Duration, bool) {
+	if resp == nil || (resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable) {
+		return 0, false
+	}
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0), true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
 	}
+	return 0, false
+}
 
-	if resp != nil {
-		// HTTP
-		for _, retryableStatusCode := range defaultRetryableStatusCodes {
-			if resp.StatusCode == retryableStatusCode {
-				return true
-			}
-		}
+func drainBody(resp *http.Response) {
+	if resp == nil || resp.Body == nil {
+		return
 	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+}
 
-	return false
+// IsRetryableError returns true if the error or the status code of the response is retryable
+// according to the default status codes and errors.
+func IsRetryableError(err error, resp *http.Response) bool {
+	return (&RetryOptions{}).IsRetryable(err, resp)
 }
@@ -1,12 +1,17 @@
 package retrytransport
 
 import (
-	"github.com/stretchr/testify/assert"
-	"go.uber.org/zap"
+	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
 )
 
 type MockTransport struct {
@@ -108,3 +113,183 @@ func TestRetryOnNetErrors(t *testing.T) {
 	assert.Equal(t, len(defaultRetryableErrors), retries)
 
 }
+
+func TestRetryStopsWhenContextIsCanceled(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+
+	tr := RetryHTTPTransport{
+		RoundTripper: &MockTransport{
+			handler: func(req *http.Request) (*http.Response, error) {
+				attempts++
+				cancel()
+				return &http.Response{
+					StatusCode: http.StatusBadGateway,
+				}, nil
+			},
+		},
+		RetryOptions: RetryOptions{
+			MaxRetryCount: 5,
+			Interval:      time.Second,
+			MaxDuration:   10 * time.Second,
+			ShouldRetry: func(err error, req *http.Request, resp *http.Response) bool {
+				return IsRetryableError(err, resp)
+			},
+		},
+		Logger: zap.NewNop(),
+	}
+
+	req := httptest.NewRequest("GET", "http://localhost:3000/graphql", nil).WithContext(ctx)
+
+	start := time.Now()
+	_, err := tr.RoundTrip(req)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestRetryAfterIsCappedByMaxDuration(t *testing.T) {
+
+	attempts := 0
+
+	tr := RetryHTTPTransport{
+		RoundTripper: &MockTransport{
+			handler: func(req *http.Request) (*http.Response, error) {
+				attempts++
+				if attempts == 1 {
+					return &http.Response{
+						StatusCode: http.StatusTooManyRequests,
+						Header:     http.Header{"Retry-After": []string{"3600"}},
+					}, nil
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+				}, nil
+			},
+		},
+		RetryOptions: RetryOptions{
+			MaxRetryCount: 1,
+			Interval:      time.Millisecond,
+			MaxDuration:   50 * time.Millisecond,
+			ShouldRetry: func(err error, req *http.Request, resp *http.Response) bool {
+				return IsRetryableError(err, resp)
+			},
+		},
+		Logger: zap.NewNop(),
+	}
+
+	req := httptest.NewRequest("GET", "http://localhost:3000/graphql", nil)
+
+	start := time.Now()
+	resp, err := tr.RoundTrip(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestRetryAfterDuration(t *testing.T) {
+
+	resp := &http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Header:     http.Header{"Retry-After": []string{"2"}},
+	}
+	d, ok := retryAfterDuration(resp)
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Second, d)
+
+	resp.Header.Set("Retry-After", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	d, ok = retryAfterDuration(resp)
+	assert.True(t, ok)
+	assert.InDelta(t, time.Minute, d, float64(2*time.Second))
+
+	resp.StatusCode = http.StatusInternalServerError
+	_, ok = retryAfterDuration(resp)
+	assert.False(t, ok, "Retry-After is only respected on 429 and 503 responses")
+}
+
+func TestRetryConfiguredStatusCodesAndErrors(t *testing.T) {
+
+	opts := RetryOptions{
+		StatusCodes: []int{http.StatusConflict},
+		Errors:      []string{"broken pipe"},
+	}
+
+	assert.True(t, opts.IsRetryable(nil, &http.Response{StatusCode: http.StatusConflict}))
+	assert.False(t, opts.IsRetryable(nil, &http.Response{StatusCode: http.StatusBadGateway}))
+	assert.True(t, opts.IsRetryable(syscall.EPIPE, nil))
+	assert.False(t, opts.IsRetryable(defaultRetryableErrors[0], nil))
+}
+
+func TestRetrySendsBodyAgain(t *testing.T) {
+
+	var bodies []string
+
+	tr := RetryHTTPTransport{
+		RoundTripper: &MockTransport{
+			handler: func(req *http.Request) (*http.Response, error) {
+				body, _ := io.ReadAll(req.Body)
+				bodies = append(bodies, string(body))
+				if len(bodies) == 1 {
+					return &http.Response{
+						StatusCode: http.StatusBadGateway,
+					}, nil
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+				}, nil
+			},
+		},
+		RetryOptions: RetryOptions{
+			MaxRetryCount: 1,
+			Interval:      time.Millisecond,
+			MaxDuration:   10 * time.Millisecond,
+			ShouldRetry: func(err error, req *http.Request, resp *http.Response) bool {
+				return IsRetryableError(err, resp)
+			},
+		},
+		Logger: zap.NewNop(),
+	}
+
+	req, err := http.NewRequest("POST", "http://localhost:3000/graphql", strings.NewReader(`{"query":"{ employees { id } }"}`))
+	assert.Nil(t, err)
+
+	resp, err := tr.RoundTrip(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{`{"query":"{ employees { id } }"}`, `{"query":"{ employees { id } }"}`}, bodies)
+}
+
+func TestRetryBudgetLimitsRetries(t *testing.T) {
+
+	attempts := 0
+
+	tr := RetryHTTPTransport{
+		RoundTripper: &MockTransport{
+			handler: func(req *http.Request) (*http.Response, error) {
+				attempts++
+				return &http.Response{
+					StatusCode: http.StatusBadGateway,
+				}, nil
+			},
+		},
+		RetryOptions: RetryOptions{
+			MaxRetryCount: 5,
+			Interval:      time.Millisecond,
+			MaxDuration:   time.Millisecond,
+			ShouldRetry: func(err error, req *http.Request, resp *http.Response) bool {
+				return IsRetryableError(err, resp)
+			},
+			Budget: NewBudget(20, 2, time.Minute),
+		},
+		Logger: zap.NewNop(),
+	}
+
+	req := httptest.NewRequest("GET", "http://localhost:3000/graphql", nil)
+
+	resp, err := tr.RoundTrip(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.Equal(t, 3, attempts, "only the minimum of two retries is allowed")
+}
@@ -0,0 +1,91 @@
+package authentication
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+var errInvalidAPIKey = errors.New("invalid API key")
+
+// APIKey is a single API key. Only the hash of the key is stored.
+type APIKey struct {
+	// Name identifies the key. It is used as the "sub" claim if the claims don't contain one.
+	Name string `yaml:"name"`
+	// Hash is the hex encoded SHA-256 hash of the key.
+	Hash string `yaml:"hash"`
+	// Scopes are attached to the request as the "scope" claim.
+	Scopes []string `yaml:"scopes"`
+	// Claims are attached to the request, e.g. the tenant of the key.
+	Claims map[string]any `yaml:"claims"`
+}
+
+type apiKey struct {
+	hash   []byte
+	claims Claims
+}
+
+type apiKeyTokenDecoder struct {
+	keys []apiKey
+}
+
+// Decode implements TokenDecoder. The token is the plain API key.
+func (d *apiKeyTokenDecoder) Decode(token string) (Claims, error) {
+	sum := sha256.Sum256([]byte(token))
+	for _, key := range d.keys {
+		if subtle.ConstantTimeCompare(sum[:], key.hash) == 1 {
+			// The claims are shared across requests, hand out a copy
+			return maps.Clone(key.claims), nil
+		}
+	}
+	return nil, errInvalidAPIKey
+}
+
+func (d *apiKeyTokenDecoder) Close() {}
+
+// LoadAPIKeys reads API keys from a YAML or JSON file.
+func LoadAPIKeys(path string) ([]APIKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read API keys from %q: %w", path, err)
+	}
+	var keys []APIKey
+	if err := yaml.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("could not parse API keys from %q: %w", path, err)
+	}
+	return keys, nil
+}
+
+// NewAPIKeyTokenDecoder returns a TokenDecoder that accepts the given API keys. The keys
+// are compared by their SHA-256 hash, so the plain keys never have to be configured.
+func NewAPIKeyTokenDecoder(keys []APIKey) (TokenDecoder, error) {
+	decoder := &apiKeyTokenDecoder{
+		keys: make([]apiKey, 0, len(keys)),
+	}
+	for i, key := range keys {
+		hash, err := hex.DecodeString(strings.TrimPrefix(key.Hash, "sha256:"))
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("API key %d (%s) must have a hex encoded SHA-256 hash", i, key.Name)
+		}
+		claims := Claims{}
+		maps.Copy(claims, key.Claims)
+		if _, ok := claims["sub"]; !ok && key.Name != "" {
+			claims["sub"] = key.Name
+		}
+		if len(key.Scopes) > 0 {
+			claims["scope"] = strings.Join(key.Scopes, " ")
+		}
+		decoder.keys = append(decoder.keys, apiKey{
+			hash:   hash,
+			claims: claims,
+		})
+	}
+	return decoder, nil
+}
@@ -34,6 +34,10 @@ func (a *httpHeaderAuthenticator) Authenticate(ctx context.Context, p Provider)
 	var errs error
 	for _, header := range a.headerNames {
 		authorization := headers.Get(header)
+		if authorization == "" {
+			// An empty prefix matches a missing header, the request doesn't carry a token
+			continue
+		}
 		for _, prefix := range a.headerValuePrefixes {
 			if strings.HasPrefix(authorization, prefix) {
 				tokenString := strings.TrimSpace(authorization[len(prefix):])
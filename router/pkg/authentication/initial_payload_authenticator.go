@@ -48,6 +48,9 @@ func (a *websocketInitialPayloadAuthenticator) Authenticate(ctx context.Context,
 				errs = errors.Join(errs, fmt.Errorf("JWT token is not a string"))
 				continue
 			}
+			if authorization == "" {
+				continue
+			}
 			for _, prefix := range a.headerValuePrefixes {
 				if strings.HasPrefix(authorization, prefix) {
 					authorization := strings.TrimSpace(authorization[len(prefix):])
@@ -0,0 +1,193 @@
+package authentication
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/ristretto"
+)
+
+const (
+	defaultIntrospectionTimeout   = 5 * time.Second
+	defaultIntrospectionCacheTTL  = time.Minute
+	defaultIntrospectionCacheSize = 1024
+)
+
+// IntrospectionTokenDecoderOptions contains the available options for the OAuth2 token introspection decoder
+type IntrospectionTokenDecoderOptions struct {
+	// URL is the introspection endpoint of the authorization server, it is mandatory.
+	URL string
+	// ClientID and ClientSecret are used to authenticate against the introspection endpoint
+	// with HTTP basic authentication.
+	ClientID     string
+	ClientSecret string
+	// Timeout of the introspection request. It defaults to 5s.
+	Timeout time.Duration
+	// CacheTTL is the maximum time an introspection result is cached. Active tokens are never
+	// cached beyond their expiry. It defaults to 1m, a negative value disables the cache.
+	CacheTTL time.Duration
+	// CacheSize is the maximum number of cached introspection results. It defaults to 1024.
+	CacheSize int64
+	// HTTPClient is used for the introspection requests. A client with Timeout is created if nil.
+	HTTPClient *http.Client
+}
+
+type introspectionResult struct {
+	active bool
+	claims Claims
+}
+
+type introspectionTokenDecoder struct {
+	url          string
+	clientID     string
+	clientSecret string
+	cacheTTL     time.Duration
+	httpClient   *http.Client
+	cache        *ristretto.Cache[string, introspectionResult]
+	closeOnce    sync.Once
+}
+
+// Decode implements TokenDecoder. It asks the authorization server if the token is active
+// according to RFC 7662 and returns the introspection response as claims.
+func (d *introspectionTokenDecoder) Decode(token string) (Claims, error) {
+	if d.cache != nil {
+		if result, ok := d.cache.Get(token); ok {
+			return result.decode()
+		}
+	}
+
+	result, err := d.introspect(token)
+	if err != nil {
+		return nil, fmt.Errorf("could not introspect token: %w", err)
+	}
+
+	if d.cache != nil {
+		ttl := d.cacheTTL
+		if exp, err := introspectionExpiry(result.claims); err == nil && !exp.IsZero() {
+			ttl = min(ttl, time.Until(exp))
+		}
+		if ttl > 0 {
+			d.cache.SetWithTTL(token, result, 1, ttl)
+		}
+	}
+
+	return result.decode()
+}
+
+func (d *introspectionTokenDecoder) introspect(token string) (introspectionResult, error) {
+	form := url.Values{
+		"token":           {token},
+		"token_type_hint": {"access_token"},
+	}
+	req, err := http.NewRequest(http.MethodPost, d.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return introspectionResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if d.clientID != "" {
+		req.SetBasicAuth(url.QueryEscape(d.clientID), url.QueryEscape(d.clientSecret))
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return introspectionResult{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return introspectionResult{}, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var claims Claims
+	if err := json.NewDecoder(resp.Body).Decode(&claims); err != nil {
+		return introspectionResult{}, fmt.Errorf("invalid introspection response: %w", err)
+	}
+	active, _ := claims["active"].(bool)
+	return introspectionResult{
+		active: active,
+		claims: claims,
+	}, nil
+}
+
+// decode returns a copy of the claims, because the cached result is shared by all requests with the token
+func (r introspectionResult) decode() (Claims, error) {
+	if !r.active {
+		return nil, &TokenValidationError{Reason: "token is not active"}
+	}
+	return maps.Clone(r.claims), nil
+}
+
+// introspectionExpiry returns the "exp" claim of the introspection response, if present
+func introspectionExpiry(claims Claims) (time.Time, error) {
+	switch exp := claims["exp"].(type) {
+	case nil:
+		return time.Time{}, nil
+	case float64:
+		return time.Unix(int64(exp), 0), nil
+	default:
+		return time.Time{}, errors.New("invalid exp claim")
+	}
+}
+
+// Close releases the cache. The decoder is shared by the HTTP and the websocket authenticator,
+// so it is closed more than once.
+func (d *introspectionTokenDecoder) Close() {
+	d.closeOnce.Do(func() {
+		if d.cache != nil {
+			d.cache.Close()
+		}
+	})
+}
+
+// NewIntrospectionTokenDecoder returns a TokenDecoder that validates opaque tokens with an OAuth2 token
+// introspection endpoint (RFC 7662). See IntrospectionTokenDecoderOptions for the available options.
+func NewIntrospectionTokenDecoder(opts IntrospectionTokenDecoderOptions) (TokenDecoder, error) {
+	if opts.URL == "" {
+		return nil, fmt.Errorf("introspection URL must be provided")
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultIntrospectionTimeout
+	}
+	if opts.CacheTTL == 0 {
+		opts.CacheTTL = defaultIntrospectionCacheTTL
+	}
+	if opts.CacheSize <= 0 {
+		opts.CacheSize = defaultIntrospectionCacheSize
+	}
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: opts.Timeout}
+	}
+
+	decoder := &introspectionTokenDecoder{
+		url:          opts.URL,
+		clientID:     opts.ClientID,
+		clientSecret: opts.ClientSecret,
+		cacheTTL:     opts.CacheTTL,
+		httpClient:   httpClient,
+	}
+
+	if opts.CacheTTL > 0 {
+		cache, err := ristretto.NewCache[string, introspectionResult](&ristretto.Config[string, introspectionResult]{
+			MaxCost:     opts.CacheSize,
+			NumCounters: opts.CacheSize * 10,
+			BufferItems: 64,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create introspection cache: %w", err)
+		}
+		decoder.cache = cache
+	}
+
+	return decoder, nil
+}
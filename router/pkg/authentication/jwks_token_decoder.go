@@ -1,7 +1,9 @@
 package authentication
 
 import (
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/MicahParks/keyfunc/v2"
@@ -13,23 +15,108 @@ type TokenDecoder interface {
 	Close()
 }
 
-type jwksTokenDecoder struct {
+// TokenValidationError is returned when a token is well-formed but is rejected by the
+// claim validation. The Reason is safe to be returned to the client.
+type TokenValidationError struct {
+	Reason string
+}
+
+func (e *TokenValidationError) Error() string {
+	return e.Reason
+}
+
+// TokenValidationOptions configures the validation of the registered claims of a token.
+// The zero value only validates the signature and the time based claims.
+type TokenValidationOptions struct {
+	// Issuer is the expected value of the "iss" claim. It is not validated if empty.
+	Issuer string
+	// Audiences are the accepted values of the "aud" claim. The token must contain at least one of them.
+	Audiences []string
+	// AllowedAlgorithms are the accepted signing algorithms. All algorithms are accepted if empty.
+	AllowedAlgorithms []string
+	// RequiredClaims are the claims that must be present in the token.
+	RequiredClaims []string
+	// Leeway is the allowed clock skew when validating the "exp" and "nbf" claims.
+	Leeway time.Duration
+}
+
+// jwtTokenDecoder decodes and validates JWTs. The keys to verify the signature
+// are either loaded from a JWKS or a static secret.
+type jwtTokenDecoder struct {
 	// JSON Web Key Set, automatically updated in the background
-	// by keyfunc.
-	jwks *keyfunc.JWKS
+	// by keyfunc. It is nil if the tokens are signed with a static secret.
+	jwks       *keyfunc.JWKS
+	keys       jwt.Keyfunc
+	parser     *jwt.Parser
+	validation TokenValidationOptions
 }
 
 // Decode implements TokenDecoder.
-func (j *jwksTokenDecoder) Decode(tokenString string) (Claims, error) {
-	token, err := jwt.Parse(tokenString, j.jwks.Keyfunc)
+func (j *jwtTokenDecoder) Decode(tokenString string) (Claims, error) {
+	token, err := j.parser.Parse(tokenString, j.keyfunc)
 	if err != nil {
+		var validationErr *TokenValidationError
+		if errors.As(err, &validationErr) {
+			return nil, validationErr
+		}
+		switch {
+		case errors.Is(err, jwt.ErrTokenExpired):
+			return nil, &TokenValidationError{Reason: "token is expired"}
+		case errors.Is(err, jwt.ErrTokenNotValidYet):
+			return nil, &TokenValidationError{Reason: "token is not valid yet"}
+		}
 		return nil, fmt.Errorf("could not validate token: %w", err)
 	}
 	claims := token.Claims.(jwt.MapClaims)
+	if err := j.validateClaims(claims); err != nil {
+		return nil, err
+	}
 	return Claims(claims), nil
 }
 
+// keyfunc rejects tokens signed with an algorithm that isn't allowed before the key is looked up
+func (j *jwtTokenDecoder) keyfunc(token *jwt.Token) (any, error) {
+	if len(j.validation.AllowedAlgorithms) > 0 && !slices.Contains(j.validation.AllowedAlgorithms, token.Method.Alg()) {
+		return nil, &TokenValidationError{Reason: fmt.Sprintf("token signing algorithm '%s' is not allowed", token.Method.Alg())}
+	}
+	return j.keys(token)
+}
+
+func (j *jwtTokenDecoder) validateClaims(claims jwt.MapClaims) error {
+	if j.validation.Issuer != "" {
+		issuer, err := claims.GetIssuer()
+		if err != nil || issuer != j.validation.Issuer {
+			return &TokenValidationError{Reason: "token issuer is not accepted"}
+		}
+	}
+	if len(j.validation.Audiences) > 0 {
+		audiences, err := claims.GetAudience()
+		if err != nil || !slices.ContainsFunc(audiences, func(audience string) bool {
+			return slices.Contains(j.validation.Audiences, audience)
+		}) {
+			return &TokenValidationError{Reason: "token audience is not accepted"}
+		}
+	}
+	for _, claim := range j.validation.RequiredClaims {
+		if _, ok := claims[claim]; !ok {
+			return &TokenValidationError{Reason: fmt.Sprintf("token is missing the required claim '%s'", claim)}
+		}
+	}
+	return nil
+}
+
 func NewJwksTokenDecoder(url string, refreshInterval time.Duration) (TokenDecoder, error) {
+	return NewValidatingJwksTokenDecoder(url, refreshInterval, TokenValidationOptions{})
+}
+
+// NewValidatingJwksTokenDecoder returns a TokenDecoder that validates the claims of the token
+// in addition to its signature. See TokenValidationOptions for the available options.
+func NewValidatingJwksTokenDecoder(url string, refreshInterval time.Duration, validation TokenValidationOptions) (TokenDecoder, error) {
+	for _, alg := range validation.AllowedAlgorithms {
+		if jwt.GetSigningMethod(alg) == nil {
+			return nil, fmt.Errorf("unknown signing algorithm %q", alg)
+		}
+	}
 
 	jwks, err := keyfunc.Get(url, keyfunc.Options{
 		RefreshInterval: refreshInterval,
@@ -38,12 +125,40 @@ func NewJwksTokenDecoder(url string, refreshInterval time.Duration) (TokenDecode
 		return nil, fmt.Errorf("error initializing JWKS from %q: %w", url, err)
 	}
 
-	return &jwksTokenDecoder{
-		jwks: jwks,
+	return &jwtTokenDecoder{
+		jwks:       jwks,
+		keys:       jwks.Keyfunc,
+		parser:     jwt.NewParser(jwt.WithLeeway(validation.Leeway)),
+		validation: validation,
+	}, nil
+}
+
+// NewSharedSecretTokenDecoder returns a TokenDecoder for JWTs that are signed with a static HMAC secret.
+// It only accepts HS256 unless other HMAC algorithms are allowed explicitly. Everyone who knows the
+// secret can mint valid tokens, so it is meant for local development.
+func NewSharedSecretTokenDecoder(secret []byte, validation TokenValidationOptions) (TokenDecoder, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("secret must be provided")
+	}
+	if len(validation.AllowedAlgorithms) == 0 {
+		validation.AllowedAlgorithms = []string{jwt.SigningMethodHS256.Alg()}
+	}
+	for _, alg := range validation.AllowedAlgorithms {
+		if _, ok := jwt.GetSigningMethod(alg).(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("signing algorithm %q is not an HMAC algorithm", alg)
+		}
+	}
+
+	return &jwtTokenDecoder{
+		keys: func(token *jwt.Token) (any, error) {
+			return secret, nil
+		},
+		parser:     jwt.NewParser(jwt.WithLeeway(validation.Leeway)),
+		validation: validation,
 	}, nil
 }
 
-func (j *jwksTokenDecoder) Close() {
+func (j *jwtTokenDecoder) Close() {
 	if j.jwks != nil {
 		j.jwks.EndBackground()
 	}
@@ -126,6 +126,66 @@ type TrafficShapingRules struct {
 	All GlobalSubgraphRequestRule `yaml:"all"`
 	// Apply to requests from clients to the router
 	Router RouterTrafficConfiguration `yaml:"router"`
+	// Subgraphs override the rules of All for single subgraphs. The key is the name of the subgraph.
+	Subgraphs map[string]SubgraphRequestRule `yaml:"subgraphs,omitempty"`
+	// RetryBudget limits the retries of all subgraph requests
+	RetryBudget RetryBudget `yaml:"retry_budget"`
+}
+
+// RetryBudget limits the retries to a percentage of the subgraph requests across the router
+type RetryBudget struct {
+	Enabled    bool `yaml:"enabled" envDefault:"false"`
+	Percentage int  `yaml:"percentage" envDefault:"20"`
+	// MinRetries are allowed in the window regardless of the percentage
+	MinRetries int           `yaml:"min_retries" envDefault:"10"`
+	Window     time.Duration `yaml:"window" envDefault:"10s"`
+}
+
+// SubgraphRule returns the rules of All with the overrides of the subgraph applied
+func (t *TrafficShapingRules) SubgraphRule(name string) GlobalSubgraphRequestRule {
+	rule := t.All
+	override, ok := t.Subgraphs[name]
+	if !ok {
+		return rule
+	}
+	if override.BackoffJitterRetry != nil {
+		retry := override.BackoffJitterRetry
+		setIfNotNil(&rule.BackoffJitterRetry.Enabled, retry.Enabled)
+		setIfNotNil(&rule.BackoffJitterRetry.Algorithm, retry.Algorithm)
+		setIfNotNil(&rule.BackoffJitterRetry.MaxAttempts, retry.MaxAttempts)
+		setIfNotNil(&rule.BackoffJitterRetry.MaxDuration, retry.MaxDuration)
+		setIfNotNil(&rule.BackoffJitterRetry.Interval, retry.Interval)
+		if retry.StatusCodes != nil {
+			rule.BackoffJitterRetry.StatusCodes = retry.StatusCodes
+		}
+		if retry.Errors != nil {
+			rule.BackoffJitterRetry.Errors = retry.Errors
+		}
+	}
+	setIfNotNil(&rule.RequestTimeout, override.RequestTimeout)
+	setIfNotNil(&rule.DialTimeout, override.DialTimeout)
+	setIfNotNil(&rule.ResponseHeaderTimeout, override.ResponseHeaderTimeout)
+	setIfNotNil(&rule.ExpectContinueTimeout, override.ExpectContinueTimeout)
+	setIfNotNil(&rule.TLSHandshakeTimeout, override.TLSHandshakeTimeout)
+	setIfNotNil(&rule.KeepAliveIdleTimeout, override.KeepAliveIdleTimeout)
+	setIfNotNil(&rule.KeepAliveProbeInterval, override.KeepAliveProbeInterval)
+	if override.CircuitBreaker != nil {
+		circuitBreaker := override.CircuitBreaker
+		setIfNotNil(&rule.CircuitBreaker.Enabled, circuitBreaker.Enabled)
+		setIfNotNil(&rule.CircuitBreaker.ErrorThresholdPercentage, circuitBreaker.ErrorThresholdPercentage)
+		setIfNotNil(&rule.CircuitBreaker.LatencyThreshold, circuitBreaker.LatencyThreshold)
+		setIfNotNil(&rule.CircuitBreaker.MinimumRequests, circuitBreaker.MinimumRequests)
+		setIfNotNil(&rule.CircuitBreaker.Window, circuitBreaker.Window)
+		setIfNotNil(&rule.CircuitBreaker.Cooldown, circuitBreaker.Cooldown)
+		setIfNotNil(&rule.CircuitBreaker.HalfOpenRequests, circuitBreaker.HalfOpenRequests)
+	}
+	return rule
+}
+
+func setIfNotNil[T any](dst *T, src *T) {
+	if src != nil {
+		*dst = *src
+	}
 }
 
 type FileUpload struct {
@@ -142,13 +202,49 @@ type RouterTrafficConfiguration struct {
 type GlobalSubgraphRequestRule struct {
 	BackoffJitterRetry BackoffJitterRetry `yaml:"retry"`
 	// See https://blog.cloudflare.com/the-complete-guide-to-golang-net-http-timeouts/
-	RequestTimeout         time.Duration `yaml:"request_timeout,omitempty" envDefault:"60s"`
-	DialTimeout            time.Duration `yaml:"dial_timeout,omitempty" envDefault:"30s"`
-	ResponseHeaderTimeout  time.Duration `yaml:"response_header_timeout,omitempty" envDefault:"0s"`
-	ExpectContinueTimeout  time.Duration `yaml:"expect_continue_timeout,omitempty" envDefault:"0s"`
-	TLSHandshakeTimeout    time.Duration `yaml:"tls_handshake_timeout,omitempty" envDefault:"10s"`
-	KeepAliveIdleTimeout   time.Duration `yaml:"keep_alive_idle_timeout,omitempty" envDefault:"0s"`
-	KeepAliveProbeInterval time.Duration `yaml:"keep_alive_probe_interval,omitempty" envDefault:"30s"`
+	RequestTimeout         time.Duration  `yaml:"request_timeout,omitempty" envDefault:"60s"`
+	DialTimeout            time.Duration  `yaml:"dial_timeout,omitempty" envDefault:"30s"`
+	ResponseHeaderTimeout  time.Duration  `yaml:"response_header_timeout,omitempty" envDefault:"0s"`
+	ExpectContinueTimeout  time.Duration  `yaml:"expect_continue_timeout,omitempty" envDefault:"0s"`
+	TLSHandshakeTimeout    time.Duration  `yaml:"tls_handshake_timeout,omitempty" envDefault:"10s"`
+	KeepAliveIdleTimeout   time.Duration  `yaml:"keep_alive_idle_timeout,omitempty" envDefault:"0s"`
+	KeepAliveProbeInterval time.Duration  `yaml:"keep_alive_probe_interval,omitempty" envDefault:"30s"`
+	CircuitBreaker         CircuitBreaker `yaml:"circuit_breaker"`
+}
+
+// SubgraphRequestRule overrides the fields of GlobalSubgraphRequestRule that are set
+type SubgraphRequestRule struct {
+	BackoffJitterRetry     *SubgraphBackoffJitterRetry `yaml:"retry,omitempty"`
+	RequestTimeout         *time.Duration              `yaml:"request_timeout,omitempty"`
+	DialTimeout            *time.Duration              `yaml:"dial_timeout,omitempty"`
+	ResponseHeaderTimeout  *time.Duration              `yaml:"response_header_timeout,omitempty"`
+	ExpectContinueTimeout  *time.Duration              `yaml:"expect_continue_timeout,omitempty"`
+	TLSHandshakeTimeout    *time.Duration              `yaml:"tls_handshake_timeout,omitempty"`
+	KeepAliveIdleTimeout   *time.Duration              `yaml:"keep_alive_idle_timeout,omitempty"`
+	KeepAliveProbeInterval *time.Duration              `yaml:"keep_alive_probe_interval,omitempty"`
+	CircuitBreaker         *SubgraphCircuitBreaker     `yaml:"circuit_breaker,omitempty"`
+}
+
+// SubgraphBackoffJitterRetry overrides the fields of BackoffJitterRetry that are set
+type SubgraphBackoffJitterRetry struct {
+	Enabled     *bool          `yaml:"enabled,omitempty"`
+	Algorithm   *string        `yaml:"algorithm,omitempty"`
+	MaxAttempts *int           `yaml:"max_attempts,omitempty"`
+	MaxDuration *time.Duration `yaml:"max_duration,omitempty"`
+	Interval    *time.Duration `yaml:"interval,omitempty"`
+	StatusCodes []int          `yaml:"status_codes,omitempty"`
+	Errors      []string       `yaml:"errors,omitempty"`
+}
+
+// SubgraphCircuitBreaker overrides the fields of CircuitBreaker that are set
+type SubgraphCircuitBreaker struct {
+	Enabled                  *bool          `yaml:"enabled,omitempty"`
+	ErrorThresholdPercentage *int           `yaml:"error_threshold_percentage,omitempty"`
+	LatencyThreshold         *time.Duration `yaml:"latency_threshold,omitempty"`
+	MinimumRequests          *int           `yaml:"minimum_requests,omitempty"`
+	Window                   *time.Duration `yaml:"window,omitempty"`
+	Cooldown                 *time.Duration `yaml:"cooldown,omitempty"`
+	HalfOpenRequests         *int           `yaml:"half_open_requests,omitempty"`
 }
 
 type GraphqlMetrics struct {
@@ -162,6 +258,25 @@ type BackoffJitterRetry struct {
 	MaxAttempts int           `yaml:"max_attempts" envDefault:"5"`
 	MaxDuration time.Duration `yaml:"max_duration" envDefault:"10s"`
 	Interval    time.Duration `yaml:"interval" envDefault:"3s"`
+	// StatusCodes are the retryable status codes. The default status codes are used if empty.
+	StatusCodes []int `yaml:"status_codes,omitempty"`
+	// Errors are the retryable network errors, matched by the end of the error message.
+	// The default errors are used if empty.
+	Errors []string `yaml:"errors,omitempty"`
+}
+
+// CircuitBreaker stops sending requests to a subgraph while too many of its requests fail
+type CircuitBreaker struct {
+	Enabled bool `yaml:"enabled" envDefault:"false"`
+	// ErrorThresholdPercentage is the percentage of failed requests in the window that opens the circuit
+	ErrorThresholdPercentage int `yaml:"error_threshold_percentage" envDefault:"50"`
+	// LatencyThreshold counts slower requests as failed, it is disabled with 0s
+	LatencyThreshold time.Duration `yaml:"latency_threshold" envDefault:"0s"`
+	// MinimumRequests is the number of requests in the window before the circuit can open
+	MinimumRequests  int           `yaml:"minimum_requests" envDefault:"20"`
+	Window           time.Duration `yaml:"window" envDefault:"60s"`
+	Cooldown         time.Duration `yaml:"cooldown" envDefault:"30s"`
+	HalfOpenRequests int           `yaml:"half_open_requests" envDefault:"1"`
 }
 
 type HeaderRules struct {
@@ -295,13 +410,54 @@ type EngineExecutionConfiguration struct {
 	EnableValidationCache                  bool                     `envDefault:"true" env:"ENGINE_ENABLE_VALIDATION_CACHE" yaml:"enable_validation_cache"`
 	ValidationCacheSize                    int64                    `envDefault:"1024" env:"ENGINE_VALIDATION_CACHE_SIZE" yaml:"validation_cache_size,omitempty"`
 	ResolverMaxRecyclableParserSize        int                      `envDefault:"32768" env:"ENGINE_RESOLVER_MAX_RECYCLABLE_PARSER_SIZE" yaml:"resolver_max_recyclable_parser_size,omitempty"`
+	// ExecutionPlanCacheWarmup plans the most used operations of the previous execution config after a config reload
+	ExecutionPlanCacheWarmup ExecutionPlanCacheWarmupConfiguration `yaml:"execution_plan_cache_warmup"`
+}
+
+type ExecutionPlanCacheWarmupConfiguration struct {
+	Enabled bool `yaml:"enabled" envDefault:"false" env:"ENGINE_EXECUTION_PLAN_CACHE_WARMUP_ENABLED"`
+	// TopOperations is the number of most used operations that are planned
+	TopOperations int           `yaml:"top_operations,omitempty" envDefault:"100" env:"ENGINE_EXECUTION_PLAN_CACHE_WARMUP_TOP_OPERATIONS"`
+	Workers       int           `yaml:"workers,omitempty" envDefault:"4" env:"ENGINE_EXECUTION_PLAN_CACHE_WARMUP_WORKERS"`
+	Timeout       time.Duration `yaml:"timeout,omitempty" envDefault:"30s" env:"ENGINE_EXECUTION_PLAN_CACHE_WARMUP_TIMEOUT"`
 }
 
 type SecurityConfiguration struct {
-	DepthLimit                  QueryDepthConfiguration `yaml:"depth_limit"`
-	BlockMutations              bool                    `yaml:"block_mutations" envDefault:"false" env:"SECURITY_BLOCK_MUTATIONS"`
-	BlockSubscriptions          bool                    `yaml:"block_subscriptions" envDefault:"false" env:"SECURITY_BLOCK_SUBSCRIPTIONS"`
-	BlockNonPersistedOperations bool                    `yaml:"block_non_persisted_operations" envDefault:"false" env:"SECURITY_BLOCK_NON_PERSISTED_OPERATIONS"`
+	DepthLimit   QueryDepthConfiguration `yaml:"depth_limit"`
+	CostAnalysis QueryCostConfiguration  `yaml:"cost_analysis"`
+	// ComplexityLimits complement the depth limit against alias amplification and very wide selections
+	ComplexityLimits            ComplexityLimits `yaml:"complexity_limits"`
+	BlockMutations              bool             `yaml:"block_mutations" envDefault:"false" env:"SECURITY_BLOCK_MUTATIONS"`
+	BlockSubscriptions          bool             `yaml:"block_subscriptions" envDefault:"false" env:"SECURITY_BLOCK_SUBSCRIPTIONS"`
+	BlockNonPersistedOperations bool             `yaml:"block_non_persisted_operations" envDefault:"false" env:"SECURITY_BLOCK_NON_PERSISTED_OPERATIONS"`
+	// OperationRules are evaluated in order for every root field of the operation. The first matching rule decides
+	// if the root field is allowed or blocked.
+	OperationRules []OperationRule `yaml:"operation_rules,omitempty"`
+}
+
+type OperationRule struct {
+	// Name is reported in the error extensions if the rule blocks an operation
+	Name string `yaml:"name"`
+	// Action is either "allow" or "deny"
+	Action string             `yaml:"action"`
+	Match  OperationRuleMatch `yaml:"match"`
+}
+
+// OperationRuleMatch matches if all configured conditions match. A condition matches if any of its values match.
+type OperationRuleMatch struct {
+	OperationTypes []string `yaml:"operation_types,omitempty"`
+	OperationNames []string `yaml:"operation_names,omitempty"`
+	// RootFields are coordinates in the format Type.field, e.g. Mutation.deleteEmployee
+	RootFields     []string             `yaml:"root_fields,omitempty"`
+	ClientNames    []string             `yaml:"client_names,omitempty"`
+	ClientVersions []string             `yaml:"client_versions,omitempty"`
+	Claims         []OperationRuleClaim `yaml:"claims,omitempty"`
+}
+
+type OperationRuleClaim struct {
+	// Path is the dot separated path of the claim
+	Path   string   `yaml:"path"`
+	Values []string `yaml:"values"`
 }
 
 type QueryDepthConfiguration struct {
@@ -311,6 +467,33 @@ type QueryDepthConfiguration struct {
 	IgnorePersistedOperations bool  `yaml:"ignore_persisted_operations,omitempty" envDefault:"false" env:"SECURITY_QUERY_DEPTH_IGNORE_PERSISTED_OPERATIONS"`
 }
 
+type ComplexityLimits struct {
+	// FieldCount limits the total number of fields, including nested fields
+	FieldCount     ComplexityLimit `yaml:"field_count" envPrefix:"SECURITY_FIELD_COUNT_"`
+	AliasCount     ComplexityLimit `yaml:"alias_count" envPrefix:"SECURITY_ALIAS_COUNT_"`
+	RootFieldCount ComplexityLimit `yaml:"root_field_count" envPrefix:"SECURITY_ROOT_FIELD_COUNT_"`
+	DirectiveCount ComplexityLimit `yaml:"directive_count" envPrefix:"SECURITY_DIRECTIVE_COUNT_"`
+	// TokenCount limits the number of tokens of the operation document, it is checked before the document is parsed
+	TokenCount ComplexityLimit `yaml:"token_count" envPrefix:"SECURITY_TOKEN_COUNT_"`
+}
+
+type ComplexityLimit struct {
+	Enabled                   bool  `yaml:"enabled" envDefault:"false" env:"ENABLED"`
+	Limit                     int   `yaml:"limit,omitempty" envDefault:"0" env:"LIMIT"`
+	CacheSize                 int64 `yaml:"cache_size,omitempty" envDefault:"1024" env:"CACHE_SIZE"`
+	IgnorePersistedOperations bool  `yaml:"ignore_persisted_operations,omitempty" envDefault:"false" env:"IGNORE_PERSISTED_OPERATIONS"`
+}
+
+type QueryCostConfiguration struct {
+	Enabled bool `yaml:"enabled" envDefault:"false" env:"SECURITY_QUERY_COST_ENABLED"`
+	// MaxCost rejects operations with a higher estimated cost. A value of 0 disables the limit.
+	MaxCost int `yaml:"max_cost,omitempty" envDefault:"0" env:"SECURITY_QUERY_COST_MAX_COST"`
+	// DefaultListSize is the assumed size of list fields without a @listSize hint.
+	DefaultListSize           int   `yaml:"default_list_size,omitempty" envDefault:"10" env:"SECURITY_QUERY_COST_DEFAULT_LIST_SIZE"`
+	CacheSize                 int64 `yaml:"cache_size,omitempty" envDefault:"1024" env:"SECURITY_QUERY_COST_CACHE_SIZE"`
+	IgnorePersistedOperations bool  `yaml:"ignore_persisted_operations,omitempty" envDefault:"false" env:"SECURITY_QUERY_COST_IGNORE_PERSISTED_OPERATIONS"`
+}
+
 type OverrideRoutingURLConfiguration struct {
 	Subgraphs map[string]string `yaml:"subgraphs"`
 }
@@ -331,11 +514,62 @@ type AuthenticationProviderJWKS struct {
 	HeaderNames         []string      `yaml:"header_names"`
 	HeaderValuePrefixes []string      `yaml:"header_value_prefixes"`
 	RefreshInterval     time.Duration `yaml:"refresh_interval" envDefault:"1m"`
+	// Issuer is the expected value of the "iss" claim
+	Issuer string `yaml:"issuer,omitempty"`
+	// Audiences are the accepted values of the "aud" claim, the token must contain at least one of them
+	Audiences []string `yaml:"audiences,omitempty"`
+	// AllowedAlgorithms restricts the signing algorithms. All algorithms of the JWKS are accepted if empty
+	AllowedAlgorithms []string `yaml:"allowed_algorithms,omitempty"`
+	// RequiredClaims are the claims that must be present in the token
+	RequiredClaims []string `yaml:"required_claims,omitempty"`
+	// Leeway is the allowed clock skew when validating the time based claims
+	Leeway time.Duration `yaml:"leeway,omitempty"`
+}
+
+type AuthenticationAPIKey struct {
+	Name string `yaml:"name"`
+	// Hash is the hex encoded SHA-256 hash of the key
+	Hash   string         `yaml:"hash"`
+	Scopes []string       `yaml:"scopes,omitempty"`
+	Claims map[string]any `yaml:"claims,omitempty"`
+}
+
+type AuthenticationProviderAPIKeys struct {
+	HeaderNames         []string `yaml:"header_names"`
+	HeaderValuePrefixes []string `yaml:"header_value_prefixes"`
+	// File is a YAML or JSON file with additional keys
+	File string                 `yaml:"file,omitempty"`
+	Keys []AuthenticationAPIKey `yaml:"keys,omitempty"`
+}
+
+type AuthenticationProviderSharedSecret struct {
+	Secret              string        `yaml:"secret"`
+	HeaderNames         []string      `yaml:"header_names"`
+	HeaderValuePrefixes []string      `yaml:"header_value_prefixes"`
+	AllowedAlgorithms   []string      `yaml:"allowed_algorithms,omitempty"`
+	Issuer              string        `yaml:"issuer,omitempty"`
+	Audiences           []string      `yaml:"audiences,omitempty"`
+	RequiredClaims      []string      `yaml:"required_claims,omitempty"`
+	Leeway              time.Duration `yaml:"leeway,omitempty"`
+}
+
+type AuthenticationProviderIntrospection struct {
+	URL                 string        `yaml:"url"`
+	ClientID            string        `yaml:"client_id,omitempty"`
+	ClientSecret        string        `yaml:"client_secret,omitempty"`
+	HeaderNames         []string      `yaml:"header_names"`
+	HeaderValuePrefixes []string      `yaml:"header_value_prefixes"`
+	Timeout             time.Duration `yaml:"timeout,omitempty"`
+	CacheTTL            time.Duration `yaml:"cache_ttl,omitempty"`
+	CacheSize           int64         `yaml:"cache_size,omitempty"`
 }
 
 type AuthenticationProvider struct {
-	Name string                      `yaml:"name"`
-	JWKS *AuthenticationProviderJWKS `yaml:"jwks"`
+	Name          string                               `yaml:"name"`
+	JWKS          *AuthenticationProviderJWKS          `yaml:"jwks"`
+	APIKeys       *AuthenticationProviderAPIKeys       `yaml:"api_keys,omitempty"`
+	SharedSecret  *AuthenticationProviderSharedSecret  `yaml:"shared_secret,omitempty"`
+	Introspection *AuthenticationProviderIntrospection `yaml:"introspection,omitempty"`
 }
 
 type AuthenticationConfiguration struct {
@@ -346,20 +580,106 @@ type AuthorizationConfiguration struct {
 	RequireAuthentication bool `yaml:"require_authentication" envDefault:"false" env:"REQUIRE_AUTHENTICATION"`
 	// RejectOperationIfUnauthorized makes the router reject the whole GraphQL Operation if one field fails to authorize
 	RejectOperationIfUnauthorized bool `yaml:"reject_operation_if_unauthorized" envDefault:"false" env:"REJECT_OPERATION_IF_UNAUTHORIZED"`
+	// Scopes configures where the scopes for the @requiresScopes directive are read from
+	Scopes AuthorizationScopes `yaml:"scopes,omitempty"`
+	// FieldPolicies are evaluated by the router in addition to the authorization directives of the schema
+	FieldPolicies []FieldPolicy `yaml:"field_policies,omitempty"`
+}
+
+type AuthorizationScopes struct {
+	// ClaimPaths are dot separated paths to the claims that contain the scopes, e.g. "realm_access.roles".
+	// The claims can be space separated strings or arrays. It defaults to the "scope" claim.
+	ClaimPaths []string `yaml:"claim_paths,omitempty" env:"AUTHORIZATION_SCOPES_CLAIM_PATHS"`
+	// PrefixMappings rewrite the prefix of the scopes, the first matching mapping is applied
+	PrefixMappings []ScopePrefixMapping `yaml:"prefix_mappings,omitempty"`
+}
+
+type ScopePrefixMapping struct {
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+}
+
+// FieldPolicy authorizes a field based on the claims of the request and the arguments of the field.
+// The field is allowed if any of the conditions in Allow matches.
+type FieldPolicy struct {
+	// Name is reported in the authorization extension if the policy denies a field
+	Name string `yaml:"name"`
+	// Coordinate of the field in the format Type.field, e.g. Query.employee
+	Coordinate string                 `yaml:"coordinate"`
+	Allow      []FieldPolicyCondition `yaml:"allow"`
+}
+
+// FieldPolicyCondition matches if all of its claims match
+type FieldPolicyCondition struct {
+	Claims []FieldPolicyClaim `yaml:"claims"`
+}
+
+// FieldPolicyClaim compares a claim either with the configured values or with an argument of the field.
+// Array claims, e.g. roles, match if any element matches.
+type FieldPolicyClaim struct {
+	// Path is the dot separated path of the claim
+	Path     string   `yaml:"path"`
+	Values   []string `yaml:"values,omitempty"`
+	Argument string   `yaml:"argument,omitempty"`
 }
 
 type RateLimitConfiguration struct {
-	Enabled        bool                    `yaml:"enabled" envDefault:"false" env:"RATE_LIMIT_ENABLED"`
-	Strategy       string                  `yaml:"strategy" envDefault:"simple" env:"RATE_LIMIT_STRATEGY"`
+	Enabled bool `yaml:"enabled" envDefault:"false" env:"RATE_LIMIT_ENABLED"`
+	// Strategy is the algorithm used to enforce the limits. One of "simple" (GCRA), "sliding_window" or "token_bucket"
+	Strategy string `yaml:"strategy" envDefault:"simple" env:"RATE_LIMIT_STRATEGY"`
+	// SimpleStrategy holds the default limits. They are used by all strategies.
 	SimpleStrategy RateLimitSimpleStrategy `yaml:"simple_strategy"`
-	Storage        RedisConfiguration      `yaml:"storage"`
+	// Keys partition the rate limit by values of the request. Each key has its own budget,
+	// the limits of SimpleStrategy always apply in addition.
+	Keys []RateLimitKey `yaml:"keys,omitempty"`
+	// Backend is where the rate limit state is stored. One of "redis" or "memory"
+	Backend string             `yaml:"backend" envDefault:"redis" env:"RATE_LIMIT_BACKEND"`
+	Storage RedisConfiguration `yaml:"storage"`
+	// FailureMode decides if requests are allowed ("open") or denied ("closed") when the storage is unreachable
+	FailureMode string `yaml:"failure_mode" envDefault:"open" env:"RATE_LIMIT_FAILURE_MODE"`
 	// Debug ensures that retryAfter and resetAfter are set to stable values for testing
 	Debug bool `yaml:"debug" envDefault:"false" env:"RATE_LIMIT_DEBUG"`
 }
 
 type RedisConfiguration struct {
+	// Url is used to connect to a single Redis instance. It is ignored if ClusterAddrs or SentinelMasterName is set.
 	Url       string `yaml:"url,omitempty" envDefault:"redis://localhost:6379" env:"RATE_LIMIT_REDIS_URL"`
 	KeyPrefix string `yaml:"key_prefix,omitempty" envDefault:"cosmo_rate_limit" env:"RATE_LIMIT_REDIS_KEY_PREFIX"`
+	// ClusterAddrs are the seed nodes of a Redis Cluster, or the sentinel addresses if SentinelMasterName is set
+	ClusterAddrs []string `yaml:"cluster_addrs,omitempty" env:"RATE_LIMIT_REDIS_CLUSTER_ADDRS"`
+	// SentinelMasterName enables the Sentinel failover client for the given master
+	SentinelMasterName string `yaml:"sentinel_master_name,omitempty" env:"RATE_LIMIT_REDIS_SENTINEL_MASTER_NAME"`
+	// SentinelPassword authenticates against the sentinels, if it differs from Password
+	SentinelPassword string `yaml:"sentinel_password,omitempty" env:"RATE_LIMIT_REDIS_SENTINEL_PASSWORD"`
+	// Username and Password take precedence over the credentials of the Url
+	Username string                 `yaml:"username,omitempty" env:"RATE_LIMIT_REDIS_USERNAME"`
+	Password string                 `yaml:"password,omitempty" env:"RATE_LIMIT_REDIS_PASSWORD"`
+	TLS      *RedisTLSConfiguration `yaml:"tls,omitempty" envPrefix:"RATE_LIMIT_REDIS_TLS_"`
+}
+
+// RedisTLSConfiguration is shared by all Redis connections of the router
+type RedisTLSConfiguration struct {
+	Enabled bool `yaml:"enabled" envDefault:"false" env:"ENABLED"`
+	// CaFile is a PEM encoded CA bundle used to verify the server certificate instead of the system pool
+	CaFile string `yaml:"ca_file,omitempty" env:"CA_FILE"`
+	// CertFile and KeyFile enable client certificate authentication
+	CertFile           string `yaml:"cert_file,omitempty" env:"CERT_FILE"`
+	KeyFile            string `yaml:"key_file,omitempty" env:"KEY_FILE"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify" envDefault:"false" env:"INSECURE_SKIP_VERIFY"`
+}
+
+type RateLimitKey struct {
+	// Expression selects the value of the request the key is built from.
+	// One of client_name, client_version, ip, operation_name, claim:<claim> or header:<header>
+	// The ip is taken from the True-Client-IP, X-Real-IP or X-Forwarded-For header before the connection address,
+	// the proxy in front of the router must overwrite these headers
+	Expression string `yaml:"expression"`
+	// Rate, Burst and Period overwrite the default limits of the strategy for this key
+	Rate   int           `yaml:"rate,omitempty"`
+	Burst  int           `yaml:"burst,omitempty"`
+	Period time.Duration `yaml:"period,omitempty"`
+	// PerSubgraph tracks the budget of the key for each subgraph individually
+	PerSubgraph bool `yaml:"per_subgraph,omitempty"`
 }
 
 type RateLimitSimpleStrategy struct {
@@ -369,6 +689,58 @@ type RateLimitSimpleStrategy struct {
 	RejectExceedingRequests bool          `yaml:"reject_exceeding_requests" envDefault:"false" env:"RATE_LIMIT_SIMPLE_REJECT_EXCEEDING_REQUESTS"`
 }
 
+type SubgraphCacheConfiguration struct {
+	Enabled bool `yaml:"enabled" envDefault:"false" env:"SUBGRAPH_CACHE_ENABLED"`
+	// DefaultTTL is used for responses without an expiration in their Cache-Control or Expires header.
+	// Such responses aren't cached if it is zero.
+	DefaultTTL time.Duration `yaml:"default_ttl,omitempty" envDefault:"0s" env:"SUBGRAPH_CACHE_DEFAULT_TTL"`
+	// Subgraphs limits the cache to the subgraphs with the given names. All subgraphs are cached if it is empty.
+	Subgraphs []string `yaml:"subgraphs,omitempty" env:"SUBGRAPH_CACHE_SUBGRAPHS"`
+	// ScopeHeaders and ScopeClaims partition the cache by the values of the client request,
+	// so that responses for one user are never served to another. The headers forwarded to the subgraph
+	// are always part of the key.
+	ScopeHeaders []string     `yaml:"scope_headers,omitempty" env:"SUBGRAPH_CACHE_SCOPE_HEADERS"`
+	ScopeClaims  []string     `yaml:"scope_claims,omitempty" env:"SUBGRAPH_CACHE_SCOPE_CLAIMS"`
+	Storage      CacheStorage `yaml:"storage" envPrefix:"SUBGRAPH_CACHE_"`
+}
+
+// ResponseCacheConfiguration configures the cache of complete query responses.
+// Responses served from the cache aren't rate limited.
+type ResponseCacheConfiguration struct {
+	Enabled bool `yaml:"enabled" envDefault:"false" env:"RESPONSE_CACHE_ENABLED"`
+	// DefaultTTL is used for responses without an expiration in the Cache-Control header computed by the
+	// header rules. Such responses aren't cached if it is zero.
+	DefaultTTL time.Duration `yaml:"default_ttl,omitempty" envDefault:"0s" env:"RESPONSE_CACHE_DEFAULT_TTL"`
+	// VaryHeaders and VaryClaims are the values of the client request the response depends on.
+	// Responses for authenticated clients are only cached if at least one claim is part of the key.
+	VaryHeaders []string     `yaml:"vary_headers,omitempty" env:"RESPONSE_CACHE_VARY_HEADERS"`
+	VaryClaims  []string     `yaml:"vary_claims,omitempty" env:"RESPONSE_CACHE_VARY_CLAIMS"`
+	Storage     CacheStorage `yaml:"storage" envPrefix:"RESPONSE_CACHE_"`
+}
+
+type CacheStorage struct {
+	// Provider is where the entries are stored. One of "memory" or "redis"
+	Provider string `yaml:"provider" envDefault:"memory" env:"STORAGE_PROVIDER"`
+	// MaxSize is the maximum size of the in-memory storage
+	MaxSize BytesString       `yaml:"max_size,omitempty" envDefault:"100MB" env:"STORAGE_MAX_SIZE"`
+	Redis   CacheRedisStorage `yaml:"redis,omitempty" envPrefix:"REDIS_"`
+}
+
+type CacheRedisStorage struct {
+	// Url is used to connect to a single Redis instance. Use the rediss:// scheme to connect with TLS.
+	Url string `yaml:"url,omitempty" envDefault:"redis://localhost:6379" env:"URL"`
+	// KeyPrefix defaults to a prefix per cache, so that all caches can share a Redis instance
+	KeyPrefix string `yaml:"key_prefix,omitempty" env:"KEY_PREFIX"`
+	// ClusterAddrs are the seed nodes of a Redis Cluster, or the sentinel addresses if SentinelMasterName is set
+	ClusterAddrs []string `yaml:"cluster_addrs,omitempty" env:"CLUSTER_ADDRS"`
+	// SentinelMasterName enables the Sentinel failover client for the given master
+	SentinelMasterName string                 `yaml:"sentinel_master_name,omitempty" env:"SENTINEL_MASTER_NAME"`
+	SentinelPassword   string                 `yaml:"sentinel_password,omitempty" env:"SENTINEL_PASSWORD"`
+	Username           string                 `yaml:"username,omitempty" env:"USERNAME"`
+	Password           string                 `yaml:"password,omitempty" env:"PASSWORD"`
+	TLS                *RedisTLSConfiguration `yaml:"tls,omitempty" envPrefix:"TLS_"`
+}
+
 type CDNConfiguration struct {
 	URL       string      `yaml:"url" env:"CDN_URL" envDefault:"https://cosmo-cdn.wundergraph.com"`
 	CacheSize BytesString `yaml:"cache_size,omitempty" env:"CDN_CACHE_SIZE" envDefault:"100MB"`
@@ -386,12 +758,55 @@ type NatsCredentialsAuthentication struct {
 type NatsAuthentication struct {
 	UserInfo                     NatsCredentialsAuthentication `yaml:"user_info"`
 	NatsTokenBasedAuthentication `yaml:"token,inline"`
+	// CredentialsFile is a .creds file with the user JWT and NKey seed for decentralized JWT authentication
+	CredentialsFile string `yaml:"credentials_file,omitempty"`
+	// NKeySeedFile is a file with the NKey seed of the user
+	NKeySeedFile string `yaml:"nkey_seed_file,omitempty"`
+}
+
+type NatsTLSConfiguration struct {
+	Enabled bool `yaml:"enabled" envDefault:"false"`
+	// CaFile is a PEM encoded CA bundle used to verify the server certificate instead of the system pool
+	CaFile string `yaml:"ca_file,omitempty"`
+	// CertFile and KeyFile enable client certificate authentication (mTLS)
+	CertFile           string `yaml:"cert_file,omitempty"`
+	KeyFile            string `yaml:"key_file,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify" envDefault:"false"`
 }
 
 type NatsEventSource struct {
-	ID             string              `yaml:"id,omitempty"`
-	URL            string              `yaml:"url,omitempty"`
-	Authentication *NatsAuthentication `yaml:"authentication,omitempty"`
+	ID             string                `yaml:"id,omitempty"`
+	URL            string                `yaml:"url,omitempty"`
+	Authentication *NatsAuthentication   `yaml:"authentication,omitempty"`
+	TLS            *NatsTLSConfiguration `yaml:"tls,omitempty"`
+	// MaxReconnects is the number of reconnect attempts, -1 reconnects forever. Nil uses the client default.
+	MaxReconnects       *int          `yaml:"max_reconnects,omitempty"`
+	ReconnectWait       time.Duration `yaml:"reconnect_wait,omitempty"`
+	PingInterval        time.Duration `yaml:"ping_interval,omitempty"`
+	MaxPingsOutstanding int           `yaml:"max_pings_outstanding,omitempty"`
+	// PendingBufferSize is the number of messages buffered per subscription before messages are dropped as a slow consumer
+	PendingBufferSize int                        `yaml:"pending_buffer_size,omitempty"`
+	JetStream         NatsJetStreamConfiguration `yaml:"jetstream,omitempty"`
+}
+
+// NatsJetStreamConfiguration configures the consumers of the subscriptions to JetStream streams
+type NatsJetStreamConfiguration struct {
+	// EphemeralConsumers creates a consumer per subscription instead of the durable consumer of the stream configuration
+	EphemeralConsumers bool `yaml:"ephemeral_consumers" envDefault:"false"`
+	// InactiveThreshold is the time after which an ephemeral consumer without subscription is removed
+	InactiveThreshold time.Duration `yaml:"inactive_threshold,omitempty"`
+	// AckPolicy is explicit, all or none
+	AckPolicy  string        `yaml:"ack_policy,omitempty"`
+	AckWait    time.Duration `yaml:"ack_wait,omitempty"`
+	MaxDeliver int           `yaml:"max_deliver,omitempty"`
+	// DeliverPolicy is all, last, new, last_per_subject, by_start_sequence or by_start_time
+	DeliverPolicy string    `yaml:"deliver_policy,omitempty"`
+	StartSequence uint64    `yaml:"start_sequence,omitempty"`
+	StartTime     time.Time `yaml:"start_time,omitempty"`
+	// FetchBatchSize is the maximum number of messages of a fetch
+	FetchBatchSize int `yaml:"fetch_batch_size,omitempty"`
+	// FetchMaxWait is how long a fetch waits for messages
+	FetchMaxWait time.Duration `yaml:"fetch_max_wait,omitempty"`
 }
 
 type KafkaSASLPlainAuthentication struct {
@@ -399,19 +814,92 @@ type KafkaSASLPlainAuthentication struct {
 	Username *string `yaml:"username,omitempty"`
 }
 
+type KafkaSASLSCRAMAuthentication struct {
+	// Mechanism is either SCRAM-SHA-256 or SCRAM-SHA-512
+	Mechanism string  `yaml:"mechanism,omitempty"`
+	Password  *string `yaml:"password,omitempty"`
+	Username  *string `yaml:"username,omitempty"`
+}
+
+// KafkaSASLOAuthBearerAuthentication authenticates with a static token or fetches tokens from
+// the token endpoint with the client credentials grant
+type KafkaSASLOAuthBearerAuthentication struct {
+	Token        *string  `yaml:"token,omitempty"`
+	TokenURL     string   `yaml:"token_url,omitempty"`
+	ClientID     string   `yaml:"client_id,omitempty"`
+	ClientSecret string   `yaml:"client_secret,omitempty"`
+	Scopes       []string `yaml:"scopes,omitempty"`
+	// Extensions are sent to the broker with the token, e.g. the logical cluster of Confluent Cloud
+	Extensions map[string]string `yaml:"extensions,omitempty"`
+}
+
 type KafkaAuthentication struct {
-	SASLPlain KafkaSASLPlainAuthentication `yaml:"sasl_plain,omitempty"`
+	SASLPlain       KafkaSASLPlainAuthentication        `yaml:"sasl_plain,omitempty"`
+	SASLSCRAM       *KafkaSASLSCRAMAuthentication       `yaml:"sasl_scram,omitempty"`
+	SASLOAuthBearer *KafkaSASLOAuthBearerAuthentication `yaml:"sasl_oauthbearer,omitempty"`
 }
 
 type KafkaTLSConfiguration struct {
 	Enabled bool `yaml:"enabled" envDefault:"false"`
+	// CaFile is a PEM encoded CA bundle used to verify the broker certificates instead of the system pool
+	CaFile string `yaml:"ca_file,omitempty"`
+	// CertFile and KeyFile enable client certificate authentication (mTLS)
+	CertFile           string `yaml:"cert_file,omitempty"`
+	KeyFile            string `yaml:"key_file,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify" envDefault:"false"`
+}
+
+// KafkaConsumerConfiguration configures how the subscriptions of a Kafka event source consume the topics
+type KafkaConsumerConfiguration struct {
+	// StartOffset is the position a new subscription starts at: latest, earliest or timestamp
+	StartOffset string `yaml:"start_offset,omitempty"`
+	// StartTimestamp is the position of the timestamp start offset
+	StartTimestamp time.Time `yaml:"start_timestamp,omitempty"`
+	// Group consumes the topics with a consumer group instead of a client per subscription
+	Group *KafkaConsumerGroupConfiguration `yaml:"group,omitempty"`
+}
+
+// KafkaConsumerGroupConfiguration consumes every record with one router of the group. The router publishes
+// the record to a NATS subject, so that the subscriptions of all routers receive it.
+type KafkaConsumerGroupConfiguration struct {
+	ID string `yaml:"id,omitempty"`
+	// FanOutProviderID is the ID of the NATS event source that distributes the records to all routers
+	FanOutProviderID string `yaml:"fan_out_provider_id,omitempty"`
+	// FanOutSubjectPrefix is prepended to the topic to build the NATS subject
+	FanOutSubjectPrefix string `yaml:"fan_out_subject_prefix,omitempty"`
+	// Commit is either "after_fan_out" for at-least-once delivery or "auto" for at-most-once delivery
+	Commit string `yaml:"commit,omitempty"`
+}
+
+// KafkaProducerConfiguration configures how the publish events of a Kafka event source are written
+type KafkaProducerConfiguration struct {
+	// Acks is the number of acknowledgements required for a write: all, leader or none
+	Acks string `yaml:"acks,omitempty"`
+	// Idempotent enables the idempotent producer. It's enabled by default if all acks are required.
+	Idempotent *bool `yaml:"idempotent,omitempty"`
+	// Compression is the compression codec of the record batches: none, gzip, snappy, lz4 or zstd
+	Compression string        `yaml:"compression,omitempty"`
+	Linger      time.Duration `yaml:"linger,omitempty"`
+	// Keys sets the record key of the events published to a topic
+	Keys []KafkaProducerKey `yaml:"keys,omitempty"`
+	// PropagateHeaders are the names of the client request headers that are added to the records
+	PropagateHeaders []string `yaml:"propagate_headers,omitempty"`
+}
+
+// KafkaProducerKey takes the record key from an argument of the publish operation or a JSON path into the arguments
+type KafkaProducerKey struct {
+	Topic    string `yaml:"topic"`
+	Argument string `yaml:"argument,omitempty"`
+	Path     string `yaml:"path,omitempty"`
 }
 
 type KafkaEventSource struct {
-	ID             string                 `yaml:"id,omitempty"`
-	Brokers        []string               `yaml:"brokers,omitempty"`
-	Authentication *KafkaAuthentication   `yaml:"authentication,omitempty"`
-	TLS            *KafkaTLSConfiguration `yaml:"tls,omitempty"`
+	ID             string                     `yaml:"id,omitempty"`
+	Brokers        []string                   `yaml:"brokers,omitempty"`
+	Authentication *KafkaAuthentication       `yaml:"authentication,omitempty"`
+	TLS            *KafkaTLSConfiguration     `yaml:"tls,omitempty"`
+	Consumer       KafkaConsumerConfiguration `yaml:"consumer,omitempty"`
+	Producer       KafkaProducerConfiguration `yaml:"producer,omitempty"`
 }
 
 type EventProviders struct {
@@ -529,8 +1017,9 @@ type SubgraphErrorPropagationConfiguration struct {
 }
 
 type StorageProviders struct {
-	S3  []S3StorageProvider  `yaml:"s3,omitempty"`
-	CDN []CDNStorageProvider `yaml:"cdn,omitempty"`
+	S3         []S3StorageProvider         `yaml:"s3,omitempty"`
+	CDN        []CDNStorageProvider        `yaml:"cdn,omitempty"`
+	FileSystem []FileSystemStorageProvider `yaml:"file_system,omitempty"`
 }
 
 type PersistedOperationsStorageConfig struct {
@@ -553,6 +1042,14 @@ type CDNStorageProvider struct {
 	URL string `yaml:"url,omitempty" envDefault:"https://cosmo-cdn.wundergraph.com"`
 }
 
+// FileSystemStorageProvider reads the persisted operations from a local directory or manifest file
+type FileSystemStorageProvider struct {
+	ID   string `yaml:"id,omitempty"`
+	Path string `yaml:"path,omitempty"`
+	// Watch reloads the operations when a file changes
+	Watch bool `yaml:"watch,omitempty"`
+}
+
 type PersistedOperationsCDNProvider struct {
 	URL string `yaml:"url,omitempty" envDefault:"https://cosmo-cdn.wundergraph.com"`
 }
@@ -567,9 +1064,17 @@ type ExecutionConfigFile struct {
 	Watch bool   `yaml:"watch,omitempty" envDefault:"false" env:"EXECUTION_CONFIG_FILE_WATCH"`
 }
 
+// ExecutionConfigFallbackStorage keeps the last applied execution config on the local disk. It's used when
+// the storage provider isn't available while the router starts.
+type ExecutionConfigFallbackStorage struct {
+	Enabled bool   `yaml:"enabled" envDefault:"false" env:"EXECUTION_CONFIG_FALLBACK_STORAGE_ENABLED"`
+	Path    string `yaml:"path,omitempty" env:"EXECUTION_CONFIG_FALLBACK_STORAGE_PATH"`
+}
+
 type ExecutionConfig struct {
-	File    ExecutionConfigFile    `yaml:"file,omitempty"`
-	Storage ExecutionConfigStorage `yaml:"storage,omitempty"`
+	File            ExecutionConfigFile            `yaml:"file,omitempty"`
+	Storage         ExecutionConfigStorage         `yaml:"storage,omitempty"`
+	FallbackStorage ExecutionConfigFallbackStorage `yaml:"fallback_storage,omitempty"`
 }
 
 type PersistedOperationsCacheConfig struct {
@@ -577,8 +1082,33 @@ type PersistedOperationsCacheConfig struct {
 }
 
 type PersistedOperationsConfig struct {
-	Cache   PersistedOperationsCacheConfig   `yaml:"cache"`
-	Storage PersistedOperationsStorageConfig `yaml:"storage"`
+	Cache                     PersistedOperationsCacheConfig    `yaml:"cache"`
+	Storage                   PersistedOperationsStorageConfig  `yaml:"storage"`
+	AutomaticPersistedQueries AutomaticPersistedQueriesConfig   `yaml:"automatic_persisted_queries"`
+	Manifest                  PersistedOperationsManifestConfig `yaml:"manifest"`
+	// Warmup plans the operations that are known up front before a new config serves traffic
+	Warmup CacheWarmupConfiguration `yaml:"warmup" envPrefix:"PERSISTED_OPERATIONS_"`
+}
+
+// PersistedOperationsManifestConfig loads all persisted operations of the graph from the CDN at once
+type PersistedOperationsManifestConfig struct {
+	Enabled      bool          `yaml:"enabled" envDefault:"false" env:"PERSISTED_OPERATIONS_MANIFEST_ENABLED"`
+	PollInterval time.Duration `yaml:"poll_interval,omitempty" envDefault:"10s" env:"PERSISTED_OPERATIONS_MANIFEST_POLL_INTERVAL"`
+}
+
+type CacheWarmupConfiguration struct {
+	Enabled bool `yaml:"enabled" envDefault:"false" env:"WARMUP_ENABLED"`
+	// Workers is the number of operations that are planned concurrently
+	Workers int `yaml:"workers,omitempty" envDefault:"4" env:"WARMUP_WORKERS"`
+	// Timeout limits the duration of the warmup. Operations that aren't planned in time are planned on their first request.
+	Timeout time.Duration `yaml:"timeout,omitempty" envDefault:"30s" env:"WARMUP_TIMEOUT"`
+}
+
+type AutomaticPersistedQueriesConfig struct {
+	Enabled bool `yaml:"enabled" envDefault:"false" env:"PERSISTED_OPERATIONS_APQ_ENABLED"`
+	// TTL is how long a registered operation is kept. Operations are kept until they are evicted if it is zero.
+	TTL     time.Duration `yaml:"ttl,omitempty" envDefault:"24h" env:"PERSISTED_OPERATIONS_APQ_TTL"`
+	Storage CacheStorage  `yaml:"storage" envPrefix:"PERSISTED_OPERATIONS_APQ_"`
 }
 
 type ApolloCompatibilityFlags struct {
@@ -625,6 +1155,8 @@ type Config struct {
 	Authentication                AuthenticationConfiguration `yaml:"authentication,omitempty"`
 	Authorization                 AuthorizationConfiguration  `yaml:"authorization,omitempty"`
 	RateLimit                     RateLimitConfiguration      `yaml:"rate_limit,omitempty"`
+	SubgraphCache                 SubgraphCacheConfiguration  `yaml:"subgraph_cache,omitempty"`
+	ResponseCache                 ResponseCacheConfiguration  `yaml:"response_cache,omitempty"`
 	LocalhostFallbackInsideDocker bool                        `yaml:"localhost_fallback_inside_docker" envDefault:"true" env:"LOCALHOST_FALLBACK_INSIDE_DOCKER"`
 	CDN                           CDNConfiguration            `yaml:"cdn,omitempty"`
 	DevelopmentMode               bool                        `yaml:"dev_mode" envDefault:"false" env:"DEV_MODE"`
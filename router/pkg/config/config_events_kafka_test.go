@@ -101,6 +101,112 @@ events:
 	require.ErrorContains(t, err, "router config validation error: jsonschema validation failed with 'https://raw.githubusercontent.com/wundergraph/cosmo/main/router/pkg/config/config.schema.json#'\n- at '/events/providers/kafka/0/authentication': oneOf failed, none matched\n  - at '/events/providers/kafka/0/authentication/sasl_plain': missing property 'username'")
 }
 
+func TestValidAuthenticatedKafkaProviderWithSaslScram(t *testing.T) {
+	f := createTempFileFromFixture(t, `
+version: "1"
+
+graph:
+  token: "token"
+
+events:
+  providers:
+    kafka:
+      - id: my-kafka
+        brokers:
+          - "localhost:9092"
+        tls:
+          enabled: true
+          ca_file: "ca.pem"
+          cert_file: "client.pem"
+          key_file: "client-key.pem"
+        authentication:
+          sasl_scram:
+            mechanism: SCRAM-SHA-512
+            username: "admin"
+            password: "admin"
+
+`)
+
+	_, err := LoadConfig(f, "")
+	require.NoError(t, err)
+}
+
+func TestValidAuthenticatedKafkaProviderWithSaslOAuthBearer(t *testing.T) {
+	f := createTempFileFromFixture(t, `
+version: "1"
+
+graph:
+  token: "token"
+
+events:
+  providers:
+    kafka:
+      - id: my-kafka
+        brokers:
+          - "localhost:9092"
+        authentication:
+          sasl_oauthbearer:
+            token_url: "https://auth.example.com/oauth/token"
+            client_id: "cosmo-router"
+            client_secret: "secret"
+
+`)
+
+	_, err := LoadConfig(f, "")
+	require.NoError(t, err)
+}
+
+func TestInvalidAuthenticatedKafkaProviderWithMultipleMechanisms(t *testing.T) {
+	f := createTempFileFromFixture(t, `
+version: "1"
+
+graph:
+  token: "token"
+
+events:
+  providers:
+    kafka:
+      - id: my-kafka
+        brokers:
+          - "localhost:9092"
+        authentication:
+          sasl_plain:
+            username: "admin"
+            password: "admin"
+          sasl_scram:
+            mechanism: SCRAM-SHA-256
+            username: "admin"
+            password: "admin"
+
+`)
+
+	_, err := LoadConfig(f, "")
+	require.ErrorContains(t, err, "maxProperties")
+}
+
+func TestInvalidKafkaProviderWithClientCertificateWithoutKey(t *testing.T) {
+	f := createTempFileFromFixture(t, `
+version: "1"
+
+graph:
+  token: "token"
+
+events:
+  providers:
+    kafka:
+      - id: my-kafka
+        brokers:
+          - "localhost:9092"
+        tls:
+          enabled: true
+          cert_file: "client.pem"
+
+`)
+
+	_, err := LoadConfig(f, "")
+	require.ErrorContains(t, err, "properties 'key_file' required, if 'cert_file' exists")
+}
+
 func createTempFileFromFixture(t *testing.T, fixture string) string {
 	t.Helper()
 
@@ -90,3 +90,72 @@ events:
 	_, err := LoadConfig(f, "")
 	require.NoError(t, err)
 }
+
+func TestValidAuthenticatedNatsProviderWithCredentialsFileAndTLS(t *testing.T) {
+	f := createTempFileFromFixture(t, `
+version: "1"
+
+graph:
+  token: "token"
+
+events:
+  providers:
+    nats:
+      - id: default
+        url: "tls://localhost:4222"
+        authentication:
+          credentials_file: "user.creds"
+        tls:
+          enabled: true
+          ca_file: "ca.pem"
+          cert_file: "client.pem"
+          key_file: "client-key.pem"
+        max_reconnects: -1
+        reconnect_wait: 5s
+        pending_buffer_size: 1024
+`)
+
+	_, err := LoadConfig(f, "")
+	require.NoError(t, err)
+}
+
+func TestInvalidAuthenticatedNatsProviderWithCredentialsFileAndNKeySeedFile(t *testing.T) {
+	f := createTempFileFromFixture(t, `
+version: "1"
+
+graph:
+  token: "token"
+
+events:
+  providers:
+    nats:
+      - id: default
+        url: "nats://localhost:4222"
+        authentication:
+          credentials_file: "user.creds"
+          nkey_seed_file: "user.nk"
+`)
+
+	_, err := LoadConfig(f, "")
+	require.ErrorContains(t, err, "maxProperties")
+}
+
+func TestInvalidNatsJetStreamDeliverPolicyWithoutStartSequence(t *testing.T) {
+	f := createTempFileFromFixture(t, `
+version: "1"
+
+graph:
+  token: "token"
+
+events:
+  providers:
+    nats:
+      - id: default
+        url: "nats://localhost:4222"
+        jetstream:
+          deliver_policy: by_start_sequence
+`)
+
+	_, err := LoadConfig(f, "")
+	require.ErrorContains(t, err, "missing property 'start_sequence'")
+}
@@ -461,3 +461,312 @@ execution_config:
 	require.Equal(t, js.Causes[0].Error(), "at '/execution_config': oneOf failed, none matched\n- at '/execution_config': additional properties 'storage' not allowed\n- at '/execution_config': additional properties 'file' not allowed")
 
 }
+
+func TestValidRateLimitRedisSentinelConfig(t *testing.T) {
+	f := createTempFileFromFixture(t, `
+version: "1"
+
+rate_limit:
+  enabled: true
+  storage:
+    cluster_addrs:
+      - "sentinel-1:26379"
+      - "sentinel-2:26379"
+    sentinel_master_name: "mymaster"
+    username: "router"
+    password: "secret"
+    tls:
+      enabled: true
+      ca_file: "ca.pem"
+`)
+	cfg, err := LoadConfig(f, "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"sentinel-1:26379", "sentinel-2:26379"}, cfg.Config.RateLimit.Storage.ClusterAddrs)
+	require.Equal(t, "ca.pem", cfg.Config.RateLimit.Storage.TLS.CaFile)
+}
+
+func TestValidCacheStorageRedisSentinelConfig(t *testing.T) {
+	f := createTempFileFromFixture(t, `
+version: "1"
+
+response_cache:
+  enabled: true
+  storage:
+    provider: redis
+    redis:
+      cluster_addrs:
+        - "sentinel-1:26379"
+      sentinel_master_name: "mymaster"
+      sentinel_password: "sentinel-secret"
+      tls:
+        enabled: true
+        ca_file: "ca.pem"
+`)
+	cfg, err := LoadConfig(f, "")
+	require.NoError(t, err)
+	require.Equal(t, "mymaster", cfg.Config.ResponseCache.Storage.Redis.SentinelMasterName)
+	require.Equal(t, "sentinel-secret", cfg.Config.ResponseCache.Storage.Redis.SentinelPassword)
+	require.Equal(t, "ca.pem", cfg.Config.ResponseCache.Storage.Redis.TLS.CaFile)
+}
+
+func TestInvalidRateLimitRedisSentinelConfig(t *testing.T) {
+	f := createTempFileFromFixture(t, `
+version: "1"
+
+rate_limit:
+  enabled: true
+  storage:
+    sentinel_master_name: "mymaster"
+`)
+	_, err := LoadConfig(f, "")
+	var js *jsonschema.ValidationError
+	require.ErrorAs(t, err, &js)
+	require.Equal(t, js.Causes[0].Error(), "at '/rate_limit/storage': properties 'cluster_addrs' required, if 'sentinel_master_name' exists")
+}
+
+func TestInvalidAuthenticationProviderConfig(t *testing.T) {
+	f := createTempFileFromFixture(t, `
+version: "1"
+
+authentication:
+  providers:
+    - name: "ambiguous"
+      shared_secret:
+        secret: "secret"
+      introspection:
+        url: "https://auth.example.com/oauth2/introspect"
+`)
+	_, err := LoadConfig(f, "")
+	var js *jsonschema.ValidationError
+	require.ErrorAs(t, err, &js)
+	require.Equal(t, js.Causes[0].Error(), "at '/authentication/providers/0': oneOf failed, subschemas 2, 3 matched")
+}
+
+func TestInvalidAPIKeyHashConfig(t *testing.T) {
+	f := createTempFileFromFixture(t, `
+version: "1"
+
+authentication:
+  providers:
+    - name: "api-keys"
+      api_keys:
+        keys:
+          - name: "ci"
+            hash: "plain-text-key"
+`)
+	_, err := LoadConfig(f, "")
+	var js *jsonschema.ValidationError
+	require.ErrorAs(t, err, &js)
+	require.Equal(t, js.Causes[0].Error(), "at '/authentication/providers/0/api_keys/keys/0/hash': 'plain-text-key' does not match pattern '^(sha256:)?[0-9a-fA-F]{64}$'")
+}
+
+func TestInvalidFieldPolicyConfig(t *testing.T) {
+	f := createTempFileFromFixture(t, `
+version: "1"
+
+authorization:
+  field_policies:
+    - name: "own-employee"
+      coordinate: "Query.employee"
+      allow:
+        - claims:
+            - path: "employee_id"
+              argument: "id"
+              values: ["1"]
+`)
+	_, err := LoadConfig(f, "")
+	var js *jsonschema.ValidationError
+	require.ErrorAs(t, err, &js)
+	require.Equal(t, js.Causes[0].Error(), "at '/authorization/field_policies/0/allow/0/claims/0': oneOf failed, subschemas 0, 1 matched")
+}
+
+func TestSubgraphTrafficShapingRules(t *testing.T) {
+	f := createTempFileFromFixture(t, `
+version: "1"
+
+traffic_shaping:
+  all:
+    request_timeout: 10s
+    dial_timeout: 5s
+    retry:
+      enabled: true
+      max_attempts: 3
+  subgraphs:
+    reporting:
+      request_timeout: 30s
+    edge-cache:
+      request_timeout: 500ms
+      retry:
+        enabled: false
+`)
+	cfg, err := LoadConfig(f, "")
+	require.NoError(t, err)
+
+	rules := cfg.Config.TrafficShaping
+
+	reporting := rules.SubgraphRule("reporting")
+	require.Equal(t, 30*time.Second, reporting.RequestTimeout)
+	require.Equal(t, 5*time.Second, reporting.DialTimeout)
+	require.Equal(t, rules.All.BackoffJitterRetry, reporting.BackoffJitterRetry)
+
+	edgeCache := rules.SubgraphRule("edge-cache")
+	require.Equal(t, 500*time.Millisecond, edgeCache.RequestTimeout)
+	require.False(t, edgeCache.BackoffJitterRetry.Enabled)
+	require.Equal(t, 3, edgeCache.BackoffJitterRetry.MaxAttempts)
+
+	// Subgraphs without rules use the rules of all
+	require.Equal(t, rules.All, rules.SubgraphRule("employees"))
+}
+
+func TestSubgraphCircuitBreakerRules(t *testing.T) {
+	f := createTempFileFromFixture(t, `
+version: "1"
+
+traffic_shaping:
+  all:
+    circuit_breaker:
+      enabled: true
+      minimum_requests: 10
+  subgraphs:
+    reporting:
+      circuit_breaker:
+        enabled: false
+    edge-cache:
+      circuit_breaker:
+        latency_threshold: 200ms
+`)
+	cfg, err := LoadConfig(f, "")
+	require.NoError(t, err)
+
+	rules := cfg.Config.TrafficShaping
+	require.Equal(t, CircuitBreaker{
+		Enabled:                  true,
+		ErrorThresholdPercentage: 50,
+		MinimumRequests:          10,
+		Window:                   time.Minute,
+		Cooldown:                 30 * time.Second,
+		HalfOpenRequests:         1,
+	}, rules.All.CircuitBreaker)
+
+	require.False(t, rules.SubgraphRule("reporting").CircuitBreaker.Enabled)
+
+	edgeCache := rules.SubgraphRule("edge-cache").CircuitBreaker
+	require.True(t, edgeCache.Enabled)
+	require.Equal(t, 200*time.Millisecond, edgeCache.LatencyThreshold)
+	require.Equal(t, 10, edgeCache.MinimumRequests)
+}
+
+func TestInvalidCircuitBreakerConfig(t *testing.T) {
+	f := createTempFileFromFixture(t, `
+version: "1"
+
+traffic_shaping:
+  all:
+    circuit_breaker:
+      enabled: true
+      error_threshold_percentage: 150
+`)
+	_, err := LoadConfig(f, "")
+	var js *jsonschema.ValidationError
+	require.ErrorAs(t, err, &js)
+	require.Equal(t, js.Causes[0].Error(), "at '/traffic_shaping/all/circuit_breaker/error_threshold_percentage': maximum: got 150, want 100")
+}
+
+func TestRetryConfig(t *testing.T) {
+	f := createTempFileFromFixture(t, `
+version: "1"
+
+traffic_shaping:
+  all:
+    retry:
+      status_codes: [502, 503]
+      errors:
+        - "connection refused"
+  subgraphs:
+    reporting:
+      retry:
+        status_codes: [429]
+  retry_budget:
+    enabled: true
+    percentage: 10
+`)
+	cfg, err := LoadConfig(f, "")
+	require.NoError(t, err)
+
+	rules := cfg.Config.TrafficShaping
+	require.Equal(t, []int{502, 503}, rules.All.BackoffJitterRetry.StatusCodes)
+
+	reporting := rules.SubgraphRule("reporting").BackoffJitterRetry
+	require.Equal(t, []int{429}, reporting.StatusCodes)
+	require.Equal(t, []string{"connection refused"}, reporting.Errors)
+
+	require.Equal(t, RetryBudget{
+		Enabled:    true,
+		Percentage: 10,
+		MinRetries: 10,
+		Window:     10 * time.Second,
+	}, rules.RetryBudget)
+}
+
+func TestInvalidRetryStatusCode(t *testing.T) {
+	f := createTempFileFromFixture(t, `
+version: "1"
+
+traffic_shaping:
+  all:
+    retry:
+      status_codes: [700]
+`)
+	_, err := LoadConfig(f, "")
+	var js *jsonschema.ValidationError
+	require.ErrorAs(t, err, &js)
+	require.Equal(t, js.Causes[0].Error(), "at '/traffic_shaping/all/retry/status_codes/0': maximum: got 700, want 599")
+}
+
+func TestInvalidSubgraphCacheStorageProvider(t *testing.T) {
+	f := createTempFileFromFixture(t, `
+version: "1"
+
+subgraph_cache:
+  enabled: true
+  storage:
+    provider: "memcached"
+`)
+	_, err := LoadConfig(f, "")
+	var js *jsonschema.ValidationError
+	require.ErrorAs(t, err, &js)
+	require.Equal(t, js.Causes[0].Error(), "at '/subgraph_cache/storage/provider': value must be one of 'memory', 'redis'")
+}
+
+func TestResponseCacheStorageFromEnvironment(t *testing.T) {
+	f := createTempFileFromFixture(t, `
+version: "1"
+
+response_cache:
+  enabled: true
+`)
+	t.Setenv("RESPONSE_CACHE_STORAGE_PROVIDER", "redis")
+	t.Setenv("RESPONSE_CACHE_REDIS_URL", "redis://cache:6379")
+
+	cfg, err := LoadConfig(f, "")
+	require.NoError(t, err)
+	require.Equal(t, "redis", cfg.Config.ResponseCache.Storage.Provider)
+	require.Equal(t, "redis://cache:6379", cfg.Config.ResponseCache.Storage.Redis.Url)
+	require.Equal(t, "memory", cfg.Config.SubgraphCache.Storage.Provider)
+	require.Equal(t, "redis://localhost:6379", cfg.Config.SubgraphCache.Storage.Redis.Url)
+}
+
+func TestAutomaticPersistedQueriesFromEnvironment(t *testing.T) {
+	f := createTempFileFromFixture(t, `
+version: "1"
+`)
+	t.Setenv("PERSISTED_OPERATIONS_APQ_ENABLED", "true")
+	t.Setenv("PERSISTED_OPERATIONS_APQ_TTL", "1h")
+	t.Setenv("PERSISTED_OPERATIONS_APQ_STORAGE_PROVIDER", "redis")
+
+	cfg, err := LoadConfig(f, "")
+	require.NoError(t, err)
+	require.True(t, cfg.Config.PersistedOperationsConfig.AutomaticPersistedQueries.Enabled)
+	require.Equal(t, time.Hour, cfg.Config.PersistedOperationsConfig.AutomaticPersistedQueries.TTL)
+	require.Equal(t, "redis", cfg.Config.PersistedOperationsConfig.AutomaticPersistedQueries.Storage.Provider)
+}
@@ -0,0 +1,144 @@
+package execution_config
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	nodev1 "github.com/wundergraph/cosmo/router/gen/proto/wg/cosmo/node/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var (
+	ErrMissingFallbackSignature = errors.New("signature of the fallback execution config not found")
+	ErrInvalidFallbackSignature = errors.New("invalid signature of the fallback execution config, potential tampering detected")
+)
+
+// FallbackStorage keeps the last known good execution config in a local file. The router starts with
+// this config when the storage provider of the execution config isn't available.
+type FallbackStorage struct {
+	path string
+	// signatureKey signs the stored config. The signature is verified when the config is loaded.
+	signatureKey []byte
+}
+
+type fallbackFile struct {
+	// Signature is the base64 encoded HMAC-SHA256 of the config. It's empty if no signature key is configured.
+	Signature string          `json:"signature,omitempty"`
+	Config    json.RawMessage `json:"config"`
+}
+
+func NewFallbackStorage(path string, signatureKey string) (*FallbackStorage, error) {
+	if path == "" {
+		return nil, errors.New("path is required for the execution config fallback storage")
+	}
+
+	s := &FallbackStorage{
+		path: path,
+	}
+	if signatureKey != "" {
+		s.signatureKey = []byte(signatureKey)
+	}
+
+	return s, nil
+}
+
+func (s *FallbackStorage) Path() string {
+	return s.path
+}
+
+// Store writes the config atomically. A concurrent Load or a crash while writing never sees a partial file.
+func (s *FallbackStorage) Store(cfg *nodev1.RouterConfig) error {
+	marshaled, err := protojson.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("could not marshal execution config: %w", err)
+	}
+	// The config is embedded compacted, the signature has to match the stored bytes
+	buf := &bytes.Buffer{}
+	if err := json.Compact(buf, marshaled); err != nil {
+		return err
+	}
+	data := buf.Bytes()
+
+	file := fallbackFile{
+		Config: data,
+	}
+	if s.signatureKey != nil {
+		file.Signature = base64.StdEncoding.EncodeToString(s.sign(data))
+	}
+
+	// HTML escaping would modify the signed config
+	content := &bytes.Buffer{}
+	enc := json.NewEncoder(content)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(file); err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	// Removing the file fails after the rename, this is expected
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// Load reads the stored config and verifies its signature if a signature key is configured
+func (s *FallbackStorage) Load() (*nodev1.RouterConfig, error) {
+	content, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file fallbackFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("could not unmarshal fallback execution config: %w", err)
+	}
+
+	if s.signatureKey != nil {
+		if file.Signature == "" {
+			return nil, ErrMissingFallbackSignature
+		}
+		signature, err := base64.StdEncoding.DecodeString(file.Signature)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode signature of the fallback execution config: %w", err)
+		}
+		if subtle.ConstantTimeCompare(signature, s.sign(file.Config)) != 1 {
+			return nil, ErrInvalidFallbackSignature
+		}
+	}
+
+	return UnmarshalConfig(file.Config)
+}
+
+func (s *FallbackStorage) sign(data []byte) []byte {
+	h := hmac.New(sha256.New, s.signatureKey)
+	h.Write(data)
+	return h.Sum(nil)
+}
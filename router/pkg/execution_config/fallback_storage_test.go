@@ -0,0 +1,67 @@
+package execution_config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	nodev1 "github.com/wundergraph/cosmo/router/gen/proto/wg/cosmo/node/v1"
+)
+
+func TestFallbackStorage(t *testing.T) {
+	cfg := &nodev1.RouterConfig{
+		Version: "1",
+		Subgraphs: []*nodev1.Subgraph{
+			// Characters that are escaped by encoding/json
+			{Name: "<a & b>"},
+		},
+	}
+
+	t.Run("store and load", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "nested", "config.json")
+		s, err := NewFallbackStorage(path, "")
+		require.NoError(t, err)
+
+		require.NoError(t, s.Store(cfg))
+
+		loaded, err := s.Load()
+		require.NoError(t, err)
+		require.Equal(t, "1", loaded.GetVersion())
+		require.Equal(t, "<a & b>", loaded.GetSubgraphs()[0].GetName())
+	})
+
+	t.Run("signature is verified", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		s, err := NewFallbackStorage(path, "secret")
+		require.NoError(t, err)
+
+		require.NoError(t, s.Store(cfg))
+
+		loaded, err := s.Load()
+		require.NoError(t, err)
+		require.Equal(t, "1", loaded.GetVersion())
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, []byte(strings.Replace(string(content), `"version":"1"`, `"version":"2"`, 1)), 0o644))
+
+		_, err = s.Load()
+		require.ErrorIs(t, err, ErrInvalidFallbackSignature)
+	})
+
+	t.Run("unsigned config is rejected if a signature key is configured", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		unsigned, err := NewFallbackStorage(path, "")
+		require.NoError(t, err)
+		require.NoError(t, unsigned.Store(cfg))
+
+		s, err := NewFallbackStorage(path, "secret")
+		require.NoError(t, err)
+
+		_, err = s.Load()
+		require.ErrorIs(t, err, ErrMissingFallbackSignature)
+	})
+}
@@ -2,6 +2,9 @@ package health
 
 import (
 	"net/http"
+	"sort"
+	"strings"
+	"sync"
 	"sync/atomic"
 
 	"go.uber.org/zap"
@@ -21,11 +24,22 @@ type Checker interface {
 	SetReady(isReady bool)
 }
 
+// DetailReporter is implemented by checkers that report details about the state of the router,
+// e.g. if the router serves a fallback execution config.
+type DetailReporter interface {
+	// SetDetail adds a detail to the readiness response. An empty value removes the detail.
+	SetDetail(key, value string)
+}
+
 var _ Checker = (*Checks)(nil)
+var _ DetailReporter = (*Checks)(nil)
 
 type Checks struct {
 	options *Options
 	isReady atomic.Bool
+
+	detailsMu sync.RWMutex
+	details   map[string]string
 }
 
 type Options struct {
@@ -59,8 +73,48 @@ func (c *Checks) Readiness() http.HandlerFunc {
 
 		w.WriteHeader(http.StatusOK)
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		_, _ = w.Write([]byte("OK"))
+		_, _ = w.Write([]byte("OK" + c.formatDetails()))
+	}
+}
+
+// SetDetail adds a detail to the readiness response. An empty value removes the detail.
+func (c *Checks) SetDetail(key, value string) {
+	c.detailsMu.Lock()
+	defer c.detailsMu.Unlock()
+
+	if value == "" {
+		delete(c.details, key)
+		return
+	}
+	if c.details == nil {
+		c.details = make(map[string]string)
+	}
+	c.details[key] = value
+}
+
+// formatDetails returns one line per detail, sorted by key
+func (c *Checks) formatDetails() string {
+	c.detailsMu.RLock()
+	defer c.detailsMu.RUnlock()
+
+	if len(c.details) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(c.details))
+	for key := range c.details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		b.WriteString("\n")
+		b.WriteString(key)
+		b.WriteString(": ")
+		b.WriteString(c.details[key])
 	}
+	return b.String()
 }
 
 // SetReady sets the readiness state to the given value
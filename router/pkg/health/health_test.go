@@ -41,3 +41,24 @@ func TestReadinessCheckHandler(t *testing.T) {
 	assert.Equal(t, "text/plain; charset=utf-8", rec.Header().Get("Content-Type"))
 	assert.Equal(t, "OK", rec.Body.String())
 }
+
+func TestReadinessCheckDetails(t *testing.T) {
+	handler := New(&Options{
+		Logger: zap.NewNop(),
+	})
+	handler.SetReady(true)
+	handler.SetDetail("execution_config", "fallback")
+
+	rec := httptest.NewRecorder()
+	handler.Readiness()(rec, test.NewRequest(http.MethodGet, "/health/ready"))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "OK\nexecution_config: fallback", rec.Body.String())
+
+	handler.SetDetail("execution_config", "")
+
+	rec = httptest.NewRecorder()
+	handler.Readiness()(rec, test.NewRequest(http.MethodGet, "/health/ready"))
+
+	assert.Equal(t, "OK", rec.Body.String())
+}
@@ -81,5 +81,45 @@ func createMeasures(meter otelmetric.Meter) (*Measurements, error) {
 
 	h.upDownCounters[InFlightRequestsUpDownCounter] = inFlightRequestsGauge
 
+	circuitBreakerStateGauge, err := meter.Int64UpDownCounter(
+		CircuitBreakerStateUpDownCounter,
+		CircuitBreakerStateUpDownCounterOptions...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create circuit breaker state gauge: %w", err)
+	}
+
+	h.upDownCounters[CircuitBreakerStateUpDownCounter] = circuitBreakerStateGauge
+
+	circuitBreakerShortCircuitCounter, err := meter.Int64Counter(
+		CircuitBreakerShortCircuitCounter,
+		CircuitBreakerShortCircuitCounterOptions...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create circuit breaker short circuit counter: %w", err)
+	}
+
+	h.counters[CircuitBreakerShortCircuitCounter] = circuitBreakerShortCircuitCounter
+
+	executionConfigFallbackGauge, err := meter.Int64UpDownCounter(
+		ExecutionConfigFallbackUpDownCounter,
+		ExecutionConfigFallbackUpDownCounterOptions...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create execution config fallback gauge: %w", err)
+	}
+
+	h.upDownCounters[ExecutionConfigFallbackUpDownCounter] = executionConfigFallbackGauge
+
+	jetStreamConsumerPending, err := meter.Int64UpDownCounter(
+		JetStreamConsumerPendingUpDownCounter,
+		JetStreamConsumerPendingUpDownCounterOptions...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create jetstream consumer pending gauge: %w", err)
+	}
+
+	h.upDownCounters[JetStreamConsumerPendingUpDownCounter] = jetStreamConsumerPending
+
 	return h, nil
 }
@@ -21,6 +21,13 @@ const (
 	InFlightRequestsUpDownCounter = "router.http.requests.in_flight"            // Number of requests in flight
 	RequestError                  = "router.http.requests.error"                // Total request error count
 
+	CircuitBreakerStateUpDownCounter  = "router.circuit_breaker.state"          // Number of subgraph circuits per state
+	CircuitBreakerShortCircuitCounter = "router.circuit_breaker.short_circuits" // Total requests rejected by an open circuit
+
+	ExecutionConfigFallbackUpDownCounter = "router.execution_config.fallback" // Number of graph servers that serve the fallback execution config
+
+	JetStreamConsumerPendingUpDownCounter = "router.nats.jetstream.consumer.pending" // Number of messages pending per JetStream consumer
+
 	unitBytes        = "bytes"
 	unitMilliseconds = "ms"
 )
@@ -55,6 +62,22 @@ var (
 	InFlightRequestsUpDownCounterOptions     = []otelmetric.Int64UpDownCounterOption{
 		otelmetric.WithDescription(InFlightRequestsUpDownCounterDescription),
 	}
+	CircuitBreakerStateUpDownCounterDescription = "Number of subgraph circuits per state"
+	CircuitBreakerStateUpDownCounterOptions     = []otelmetric.Int64UpDownCounterOption{
+		otelmetric.WithDescription(CircuitBreakerStateUpDownCounterDescription),
+	}
+	CircuitBreakerShortCircuitCounterDescription = "Total number of subgraph requests rejected by an open circuit"
+	CircuitBreakerShortCircuitCounterOptions     = []otelmetric.Int64CounterOption{
+		otelmetric.WithDescription(CircuitBreakerShortCircuitCounterDescription),
+	}
+	ExecutionConfigFallbackUpDownCounterDescription = "Number of graph servers that serve the execution config of the fallback storage because the storage provider wasn't available"
+	ExecutionConfigFallbackUpDownCounterOptions     = []otelmetric.Int64UpDownCounterOption{
+		otelmetric.WithDescription(ExecutionConfigFallbackUpDownCounterDescription),
+	}
+	JetStreamConsumerPendingUpDownCounterDescription = "Number of messages of the stream that weren't delivered to the JetStream consumer of the subscriptions yet"
+	JetStreamConsumerPendingUpDownCounterOptions     = []otelmetric.Int64UpDownCounterOption{
+		otelmetric.WithDescription(JetStreamConsumerPendingUpDownCounterDescription),
+	}
 )
 
 type (
@@ -80,6 +103,10 @@ type (
 		MeasureResponseSize(ctx context.Context, size int64, attr ...attribute.KeyValue)
 		MeasureLatency(ctx context.Context, requestStartTime time.Time, attr ...attribute.KeyValue)
 		MeasureRequestError(ctx context.Context, attr ...attribute.KeyValue)
+		MeasureCircuitBreakerState(ctx context.Context, delta int64, attr ...attribute.KeyValue)
+		MeasureCircuitBreakerShortCircuit(ctx context.Context, attr ...attribute.KeyValue)
+		MeasureExecutionConfigFallback(ctx context.Context, delta int64, attr ...attribute.KeyValue)
+		MeasureJetStreamConsumerPending(ctx context.Context, delta int64, attr ...attribute.KeyValue)
 		Flush(ctx context.Context) error
 	}
 
@@ -152,6 +179,26 @@ func (h *Metrics) MeasureRequestError(ctx context.Context, attr ...attribute.Key
 	h.promRequestMetrics.MeasureRequestError(ctx, attr...)
 }
 
+func (h *Metrics) MeasureCircuitBreakerState(ctx context.Context, delta int64, attr ...attribute.KeyValue) {
+	h.otlpRequestMetrics.MeasureCircuitBreakerState(ctx, delta, attr...)
+	h.promRequestMetrics.MeasureCircuitBreakerState(ctx, delta, attr...)
+}
+
+func (h *Metrics) MeasureCircuitBreakerShortCircuit(ctx context.Context, attr ...attribute.KeyValue) {
+	h.otlpRequestMetrics.MeasureCircuitBreakerShortCircuit(ctx, attr...)
+	h.promRequestMetrics.MeasureCircuitBreakerShortCircuit(ctx, attr...)
+}
+
+func (h *Metrics) MeasureExecutionConfigFallback(ctx context.Context, delta int64, attr ...attribute.KeyValue) {
+	h.otlpRequestMetrics.MeasureExecutionConfigFallback(ctx, delta, attr...)
+	h.promRequestMetrics.MeasureExecutionConfigFallback(ctx, delta, attr...)
+}
+
+func (h *Metrics) MeasureJetStreamConsumerPending(ctx context.Context, delta int64, attr ...attribute.KeyValue) {
+	h.otlpRequestMetrics.MeasureJetStreamConsumerPending(ctx, delta, attr...)
+	h.promRequestMetrics.MeasureJetStreamConsumerPending(ctx, delta, attr...)
+}
+
 // Flush flushes the metrics to the backend synchronously.
 func (h *Metrics) Flush(ctx context.Context) error {
 
@@ -32,6 +32,18 @@ func (n NoopMetrics) MeasureResponseSize(ctx context.Context, size int64, attr .
 func (n NoopMetrics) MeasureLatency(ctx context.Context, requestStartTime time.Time, attr ...attribute.KeyValue) {
 }
 
+func (n NoopMetrics) MeasureCircuitBreakerState(ctx context.Context, delta int64, attr ...attribute.KeyValue) {
+}
+
+func (n NoopMetrics) MeasureCircuitBreakerShortCircuit(ctx context.Context, attr ...attribute.KeyValue) {
+}
+
+func (n NoopMetrics) MeasureExecutionConfigFallback(ctx context.Context, delta int64, attr ...attribute.KeyValue) {
+}
+
+func (n NoopMetrics) MeasureJetStreamConsumerPending(ctx context.Context, delta int64, attr ...attribute.KeyValue) {
+}
+
 func (n NoopMetrics) Flush(ctx context.Context) error {
 	return nil
 }
@@ -133,6 +133,58 @@ func (h *PromMetricStore) MeasureRequestError(ctx context.Context, attr ...attri
 	}
 }
 
+func (h *PromMetricStore) MeasureCircuitBreakerState(ctx context.Context, delta int64, attr ...attribute.KeyValue) {
+	var baseKeys []attribute.KeyValue
+
+	baseKeys = append(baseKeys, h.baseAttributes...)
+	baseKeys = append(baseKeys, attr...)
+
+	baseAttributes := otelmetric.WithAttributes(baseKeys...)
+
+	if c, ok := h.measurements.upDownCounters[CircuitBreakerStateUpDownCounter]; ok {
+		c.Add(ctx, delta, baseAttributes)
+	}
+}
+
+func (h *PromMetricStore) MeasureCircuitBreakerShortCircuit(ctx context.Context, attr ...attribute.KeyValue) {
+	var baseKeys []attribute.KeyValue
+
+	baseKeys = append(baseKeys, h.baseAttributes...)
+	baseKeys = append(baseKeys, attr...)
+
+	baseAttributes := otelmetric.WithAttributes(baseKeys...)
+
+	if c, ok := h.measurements.counters[CircuitBreakerShortCircuitCounter]; ok {
+		c.Add(ctx, 1, baseAttributes)
+	}
+}
+
+func (h *PromMetricStore) MeasureExecutionConfigFallback(ctx context.Context, delta int64, attr ...attribute.KeyValue) {
+	var baseKeys []attribute.KeyValue
+
+	baseKeys = append(baseKeys, h.baseAttributes...)
+	baseKeys = append(baseKeys, attr...)
+
+	baseAttributes := otelmetric.WithAttributes(baseKeys...)
+
+	if c, ok := h.measurements.upDownCounters[ExecutionConfigFallbackUpDownCounter]; ok {
+		c.Add(ctx, delta, baseAttributes)
+	}
+}
+
+func (h *PromMetricStore) MeasureJetStreamConsumerPending(ctx context.Context, delta int64, attr ...attribute.KeyValue) {
+	var baseKeys []attribute.KeyValue
+
+	baseKeys = append(baseKeys, h.baseAttributes...)
+	baseKeys = append(baseKeys, attr...)
+
+	baseAttributes := otelmetric.WithAttributes(baseKeys...)
+
+	if c, ok := h.measurements.upDownCounters[JetStreamConsumerPendingUpDownCounter]; ok {
+		c.Add(ctx, delta, baseAttributes)
+	}
+}
+
 func (h *PromMetricStore) Flush(ctx context.Context) error {
 	return h.meterProvider.ForceFlush(ctx)
 }
@@ -35,9 +35,27 @@ const (
 	WgVariablesValidationSkipped       = attribute.Key("wg.engine.variables_validation_skipped")
 	WgQueryDepth                       = attribute.Key("wg.operation.query_depth")
 	WgQueryDepthCacheHit               = attribute.Key("wg.operation.query_depth_cache_hit")
+	WgQueryCost                        = attribute.Key("wg.operation.query_cost")
+	WgQueryCostCacheHit                = attribute.Key("wg.operation.query_cost_cache_hit")
+	WgQueryFieldCount                  = attribute.Key("wg.operation.query_field_count")
+	WgQueryFieldCountCacheHit          = attribute.Key("wg.operation.query_field_count_cache_hit")
+	WgQueryAliasCount                  = attribute.Key("wg.operation.query_alias_count")
+	WgQueryAliasCountCacheHit          = attribute.Key("wg.operation.query_alias_count_cache_hit")
+	WgQueryRootFieldCount              = attribute.Key("wg.operation.query_root_field_count")
+	WgQueryRootFieldCountCacheHit      = attribute.Key("wg.operation.query_root_field_count_cache_hit")
+	WgQueryDirectiveCount              = attribute.Key("wg.operation.query_directive_count")
+	WgQueryDirectiveCountCacheHit      = attribute.Key("wg.operation.query_directive_count_cache_hit")
+	WgQueryTokenCount                  = attribute.Key("wg.operation.query_token_count")
+	WgQueryTokenCountCacheHit          = attribute.Key("wg.operation.query_token_count_cache_hit")
 	WgResponseCacheControlReasons      = attribute.Key("wg.operation.cache_control_reasons")
 	WgResponseCacheControlWarnings     = attribute.Key("wg.operation.cache_control_warnings")
 	WgResponseCacheControlExpiration   = attribute.Key("wg.operation.cache_control_expiration")
+	WgSubgraphCircuitBreakerState      = attribute.Key("wg.subgraph.circuit_breaker.state")
+	WgSubgraphCacheStatus              = attribute.Key("wg.subgraph.cache.status")
+	WgResponseCacheHit                 = attribute.Key("wg.operation.response_cache_hit")
+	WgEventProviderID                  = attribute.Key("wg.event.provider.id")
+	WgNatsStream                       = attribute.Key("wg.nats.stream")
+	WgNatsConsumer                     = attribute.Key("wg.nats.consumer")
 	// HTTPRequestUploadFileCount is the number of files uploaded in a request (Not specified in the OpenTelemetry specification)
 	HTTPRequestUploadFileCount = attribute.Key("http.request.upload.file_count")
 )
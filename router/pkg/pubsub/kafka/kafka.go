@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/cloudflare/backoff"
+	"github.com/nats-io/nats.go"
 	"github.com/twmb/franz-go/pkg/kerr"
 	"github.com/twmb/franz-go/pkg/kgo"
 	"github.com/wundergraph/cosmo/router/pkg/pubsub"
@@ -23,15 +25,87 @@ var (
 	errClientClosed = errors.New("client closed")
 )
 
+type StartOffset string
+
+const (
+	// StartOffsetLatest consumes the records produced after the subscription was created
+	StartOffsetLatest StartOffset = "latest"
+	// StartOffsetEarliest consumes all records of the topics
+	StartOffsetEarliest StartOffset = "earliest"
+	// StartOffsetTimestamp consumes the records produced after the start timestamp
+	StartOffsetTimestamp StartOffset = "timestamp"
+)
+
+type CommitMode string
+
+const (
+	// CommitAfterFanOut commits the offsets after the records were published to NATS (at-least-once)
+	CommitAfterFanOut CommitMode = "after_fan_out"
+	// CommitAuto commits the offsets periodically in the background (at-most-once)
+	CommitAuto CommitMode = "auto"
+)
+
+const (
+	defaultFanOutSubjectPrefix = "cosmo.kafka"
+	// defaultFanOutPendingBufferSize is used if the fan out provider doesn't configure a pending buffer size.
+	// The connection drops messages that don't fit into the buffer of a subscription.
+	defaultFanOutPendingBufferSize = 1024
+)
+
+type ConsumerOptions struct {
+	StartOffset    StartOffset
+	StartTimestamp time.Time
+	// Group is set to consume the topics with a consumer group instead of a client per subscription
+	Group *ConsumerGroupOptions
+}
+
+// ConsumerGroupOptions configures the consumer group mode. Every record is consumed by a single router of the
+// group and published to NATS. The subscriptions of all routers receive the records from NATS.
+type ConsumerGroupOptions struct {
+	ID     string
+	Commit CommitMode
+	// FanOut is the NATS connection the records are published to and the subscriptions receive them from
+	FanOut *nats.Conn
+	// SubjectPrefix is prepended to the topic to build the NATS subject
+	SubjectPrefix string
+	// PendingBufferSize is the number of records buffered per subscription, it's taken from the fan out provider
+	PendingBufferSize int
+}
+
 type connector struct {
 	writeClient *kgo.Client
 	opts        []kgo.Opt
+	consumer    ConsumerOptions
+	producer    ProducerOptions
 	logger      *zap.Logger
 }
 
-func NewConnector(logger *zap.Logger, opts []kgo.Opt) (pubsub_datasource.KafkaConnector, error) {
+func NewConnector(logger *zap.Logger, opts []kgo.Opt, consumer ConsumerOptions, producer ProducerOptions) (pubsub_datasource.KafkaConnector, error) {
+
+	if consumer.Group != nil {
+		if consumer.Group.FanOut == nil {
+			return nil, errors.New("consumer group requires a NATS connection to fan out the records")
+		}
+		if consumer.Group.SubjectPrefix == "" {
+			consumer.Group.SubjectPrefix = defaultFanOutSubjectPrefix
+		}
+		if consumer.Group.Commit == "" {
+			consumer.Group.Commit = CommitAfterFanOut
+		}
+		if consumer.Group.PendingBufferSize <= 0 {
+			consumer.Group.PendingBufferSize = defaultFanOutPendingBufferSize
+		}
+	}
+
+	producerOpts, err := producer.kgoOptions()
+	if err != nil {
+		return nil, fmt.Errorf("invalid producer options: %w", err)
+	}
 
-	writeClient, err := kgo.NewClient(append(opts,
+	writeOpts := append([]kgo.Opt{}, opts...)
+	writeOpts = append(writeOpts, producerOpts...)
+
+	writeClient, err := kgo.NewClient(append(writeOpts,
 		// For observability, we set the client ID to "router"
 		kgo.ClientID("cosmo.router.producer"))...,
 	)
@@ -42,6 +116,8 @@ func NewConnector(logger *zap.Logger, opts []kgo.Opt) (pubsub_datasource.KafkaCo
 	return &connector{
 		writeClient: writeClient,
 		opts:        opts,
+		consumer:    consumer,
+		producer:    producer,
 		logger:      logger,
 	}, nil
 }
@@ -54,6 +130,8 @@ func (c *connector) New(ctx context.Context) pubsub_datasource.KafkaPubSub {
 		ctx:         ctx,
 		logger:      c.logger.With(zap.String("pubsub", "kafka")),
 		opts:        c.opts,
+		consumer:    c.consumer,
+		producer:    c.producer,
 		writeClient: c.writeClient,
 		closeWg:     sync.WaitGroup{},
 		cancel:      cancel,
@@ -67,13 +145,68 @@ func (c *connector) New(ctx context.Context) pubsub_datasource.KafkaPubSub {
 // The pubsub is stateless and does not store any messages.
 // It uses a single write client to produce messages and a client per topic to consume messages.
 // Each client polls the Kafka topic for new records and updates the subscriptions with the new data.
+// In the consumer group mode, a single group client consumes all subscribed topics and publishes the
+// records to NATS, the subscriptions receive the records from NATS.
 type kafkaPubSub struct {
 	ctx         context.Context
 	opts        []kgo.Opt
+	consumer    ConsumerOptions
+	producer    ProducerOptions
 	logger      *zap.Logger
 	writeClient *kgo.Client
 	closeWg     sync.WaitGroup
 	cancel      context.CancelFunc
+
+	groupMu     sync.Mutex
+	groupClient *kgo.Client
+	groupTopics map[string]struct{}
+}
+
+// resetOffset returns the offset a client starts at if the group has no committed offsets.
+// A client per subscription never commits offsets, so it always starts at the reset offset.
+func (p *kafkaPubSub) resetOffset() kgo.Offset {
+	switch p.consumer.StartOffset {
+	case StartOffsetEarliest:
+		return kgo.NewOffset().AtStart()
+	case StartOffsetTimestamp:
+		return kgo.NewOffset().AfterMilli(p.consumer.StartTimestamp.UnixMilli())
+	default:
+		if p.consumer.Group != nil {
+			return kgo.NewOffset().AtEnd()
+		}
+		// We want to consume the events produced after the first subscription was created
+		// Messages are shared among all subscriptions, therefore old events are not redelivered
+		// This replicates a stateless publish-subscribe model
+		return kgo.NewOffset().AfterMilli(time.Now().UnixMilli())
+	}
+}
+
+// handleFetchErrors logs the fetch errors and returns an error if the poller has to be aborted
+func (p *kafkaPubSub) handleFetchErrors(fetches kgo.Fetches) error {
+	for _, fetchError := range fetches.Errors() {
+
+		// If the context was canceled, the error is wrapped in a fetch error
+		if errors.Is(fetchError.Err, context.Canceled) {
+			return fetchError.Err
+		}
+
+		var kErr *kerr.Error
+		if errors.As(fetchError.Err, &kErr) {
+			if !kErr.Retriable {
+				p.logger.Error("unrecoverable fetch error",
+					zap.Error(fetchError.Err),
+					zap.String("topic", fetchError.Topic),
+				)
+
+				// If the error is not recoverable, return it and abort the poller
+				return fetchError.Err
+			}
+		} else {
+			p.logger.Error("fetch error", zap.Error(fetchError.Err), zap.String("topic", fetchError.Topic))
+		}
+	}
+
+	return nil
 }
 
 // topicPoller polls the Kafka topic for new records and calls the updateTriggers function.
@@ -95,30 +228,8 @@ func (p *kafkaPubSub) topicPoller(ctx context.Context, client *kgo.Client, updat
 				return errClientClosed
 			}
 
-			if errs := fetches.Errors(); len(errs) > 0 {
-
-				for _, fetchError := range errs {
-
-					// If the context was canceled, the error is wrapped in a fetch error
-					if errors.Is(fetchError.Err, context.Canceled) {
-						return fetchError.Err
-					}
-
-					var kErr *kerr.Error
-					if errors.As(fetchError.Err, &kErr) {
-						if !kErr.Retriable {
-							p.logger.Error("unrecoverable fetch error",
-								zap.Error(fetchError.Err),
-								zap.String("topic", fetchError.Topic),
-							)
-
-							// If the error is not recoverable, return it and abort the poller
-							return fetchError.Err
-						}
-					} else {
-						p.logger.Error("fetch error", zap.Error(fetchError.Err), zap.String("topic", fetchError.Topic))
-					}
-				}
+			if err := p.handleFetchErrors(fetches); err != nil {
+				return err
 			}
 
 			iter := fetches.RecordIter()
@@ -144,13 +255,14 @@ func (p *kafkaPubSub) Subscribe(ctx context.Context, event pubsub_datasource.Kaf
 
 	log.Debug("subscribe")
 
+	if p.consumer.Group != nil {
+		return p.subscribeGroup(ctx, event, updater, log)
+	}
+
 	// Create a new client for the topic
 	client, err := kgo.NewClient(append(p.opts,
 		kgo.ConsumeTopics(event.Topics...),
-		// We want to consume the events produced after the first subscription was created
-		// Messages are shared among all subscriptions, therefore old events are not redelivered
-		// This replicates a stateless publish-subscribe model
-		kgo.ConsumeResetOffset(kgo.NewOffset().AfterMilli(time.Now().UnixMilli())),
+		kgo.ConsumeResetOffset(p.resetOffset()),
 		// For observability, we set the client ID to "router"
 		kgo.ClientID(fmt.Sprintf("cosmo.router.consumer.%s", strings.Join(event.Topics, "-"))),
 	)...)
@@ -182,7 +294,8 @@ func (p *kafkaPubSub) Subscribe(ctx context.Context, event pubsub_datasource.Kaf
 
 // Publish publishes the given event to the Kafka topic in a non-blocking way.
 // Publish errors are logged and returned as a pubsub error.
-// The event is written with a dedicated write client.
+// The event is written with a dedicated write client. The record key is taken from the event data
+// if a key is configured for the topic, the headers carry the trace context of the request.
 func (p *kafkaPubSub) Publish(ctx context.Context, event pubsub_datasource.KafkaPublishEventConfiguration) error {
 	log := p.logger.With(
 		zap.String("provider_id", event.ProviderID),
@@ -198,8 +311,10 @@ func (p *kafkaPubSub) Publish(ctx context.Context, event pubsub_datasource.Kafka
 	var pErr error
 
 	p.writeClient.Produce(ctx, &kgo.Record{
-		Topic: event.Topic,
-		Value: event.Data,
+		Topic:   event.Topic,
+		Key:     p.producer.recordKey(event.Topic, event.Data),
+		Value:   event.Data,
+		Headers: p.producer.recordHeaders(ctx),
 	}, func(record *kgo.Record, err error) {
 		defer wg.Done()
 		if err != nil {
@@ -217,6 +332,229 @@ func (p *kafkaPubSub) Publish(ctx context.Context, event pubsub_datasource.Kafka
 	return nil
 }
 
+// subscribeGroup adds the topics to the group client and subscribes to the NATS subjects of the topics
+func (p *kafkaPubSub) subscribeGroup(ctx context.Context, event pubsub_datasource.KafkaSubscriptionEventConfiguration, updater resolve.SubscriptionUpdater, log *zap.Logger) error {
+
+	// Subscribe to NATS first, records consumed in the meantime must not be missed. The connection doesn't block
+	// on the channel, records that arrive while the updater is busy are buffered.
+	msgChan := make(chan *nats.Msg, p.consumer.Group.PendingBufferSize)
+	subscriptions := make([]*nats.Subscription, 0, len(event.Topics))
+
+	unsubscribe := func() {
+		for _, subscription := range subscriptions {
+			if err := subscription.Unsubscribe(); err != nil {
+				log.Error("error unsubscribing from NATS fan out subject",
+					zap.Error(err), zap.String("subject", subscription.Subject),
+				)
+			}
+		}
+	}
+
+	for _, topic := range event.Topics {
+		subscription, err := p.consumer.Group.FanOut.ChanSubscribe(p.fanOutSubject(topic), msgChan)
+		if err != nil {
+			unsubscribe()
+			log.Error("error subscribing to NATS fan out subject", zap.Error(err), zap.String("topic", topic))
+			return pubsub.NewError(fmt.Sprintf(`failed to subscribe to records of Kafka topic "%s"`, topic), err)
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	if err := p.consumeGroupTopics(event.Topics); err != nil {
+		unsubscribe()
+		log.Error("failed to create group client", zap.Error(err))
+		return err
+	}
+
+	p.closeWg.Add(1)
+
+	go func() {
+		defer p.closeWg.Done()
+		defer unsubscribe()
+
+		for {
+			select {
+			case msg := <-msgChan:
+				log.Debug("subscription update", zap.String("message_subject", msg.Subject), zap.ByteString("data", msg.Data))
+
+				updater.Update(msg.Data)
+			case <-p.ctx.Done():
+				// When the application context is done, we stop the subscription
+				return
+			case <-ctx.Done():
+				// When the subscription context is done, we stop the subscription
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// consumeGroupTopics creates the group client with the first subscription and adds the topics of later subscriptions.
+// Topics are consumed until the pubsub is shut down, other routers of the group may still have subscriptions for them.
+func (p *kafkaPubSub) consumeGroupTopics(topics []string) error {
+	p.groupMu.Lock()
+	defer p.groupMu.Unlock()
+
+	if p.groupClient != nil {
+		var added []string
+		for _, topic := range topics {
+			if _, ok := p.groupTopics[topic]; !ok {
+				p.groupTopics[topic] = struct{}{}
+				added = append(added, topic)
+			}
+		}
+		if len(added) > 0 {
+			p.groupClient.AddConsumeTopics(added...)
+		}
+		return nil
+	}
+
+	group := p.consumer.Group
+	log := p.logger.With(zap.String("consumer_group", group.ID))
+
+	opts := append(p.opts,
+		kgo.ConsumerGroup(group.ID),
+		kgo.ConsumeTopics(topics...),
+		kgo.ConsumeResetOffset(p.resetOffset()),
+		// Partitions are not revoked while the polled records are published and committed
+		kgo.BlockRebalanceOnPoll(),
+		kgo.OnPartitionsAssigned(func(_ context.Context, _ *kgo.Client, assigned map[string][]int32) {
+			log.Info("Kafka partitions assigned", zap.Any("partitions", assigned))
+		}),
+		kgo.OnPartitionsRevoked(func(_ context.Context, _ *kgo.Client, revoked map[string][]int32) {
+			log.Info("Kafka partitions revoked", zap.Any("partitions", revoked))
+		}),
+		kgo.OnPartitionsLost(func(_ context.Context, _ *kgo.Client, lost map[string][]int32) {
+			log.Warn("Kafka partitions lost, uncommitted records are consumed again by the new owner", zap.Any("partitions", lost))
+		}),
+		// For observability, we set the client ID to "router"
+		kgo.ClientID(fmt.Sprintf("cosmo.router.consumer.group.%s", group.ID)),
+	)
+	if group.Commit == CommitAfterFanOut {
+		opts = append(opts, kgo.DisableAutoCommit())
+	}
+
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return err
+	}
+
+	p.groupClient = client
+	p.groupTopics = make(map[string]struct{}, len(topics))
+	for _, topic := range topics {
+		p.groupTopics[topic] = struct{}{}
+	}
+
+	p.closeWg.Add(1)
+
+	go func() {
+		defer p.closeWg.Done()
+
+		err := p.groupPoller(client, log)
+		if err != nil {
+			if errors.Is(err, errClientClosed) || errors.Is(err, context.Canceled) {
+				log.Debug("group poller canceled", zap.Error(err))
+				return
+			}
+			log.Error("group poller error, the group client is recreated with the next subscription", zap.Error(err))
+			p.resetGroupClient(client)
+		}
+	}()
+
+	return nil
+}
+
+// resetGroupClient closes the group client after its poller failed, so that the next subscription doesn't
+// attach to a client that no longer consumes the topics
+func (p *kafkaPubSub) resetGroupClient(client *kgo.Client) {
+	p.groupMu.Lock()
+	if p.groupClient == client {
+		p.groupClient = nil
+		p.groupTopics = nil
+	}
+	p.groupMu.Unlock()
+
+	client.Close()
+}
+
+// groupPoller publishes the records of the group client to NATS. With the after fan out commit mode,
+// the offsets are committed after NATS has received the records. Records that weren't committed because
+// of a crash or a lost partition are consumed again, the records are delivered at least once.
+func (p *kafkaPubSub) groupPoller(client *kgo.Client, log *zap.Logger) error {
+
+	for {
+		if err := p.ctx.Err(); err != nil {
+			return err
+		}
+
+		fetches := client.PollRecords(p.ctx, 10_000)
+		if fetches.IsClientClosed() {
+			return errClientClosed
+		}
+
+		if err := p.handleFetchErrors(fetches); err != nil {
+			client.AllowRebalance()
+			return err
+		}
+
+		iter := fetches.RecordIter()
+		for !iter.Done() {
+			r := iter.Next()
+
+			log.Debug("fan out record", zap.String("topic", r.Topic), zap.Int32("partition", r.Partition), zap.Int64("offset", r.Offset))
+
+			if err := p.fanOut(r, log); err != nil {
+				client.AllowRebalance()
+				return err
+			}
+		}
+
+		if p.consumer.Group.Commit == CommitAfterFanOut && !fetches.Empty() {
+			// Wait until NATS has received the records before they are committed
+			if err := p.consumer.Group.FanOut.FlushWithContext(p.ctx); err != nil {
+				client.AllowRebalance()
+				return err
+			}
+			if err := client.CommitUncommittedOffsets(p.ctx); err != nil {
+				// The records are consumed again after a rebalance or a restart
+				log.Error("failed to commit offsets", zap.Error(err))
+			}
+		}
+
+		client.AllowRebalance()
+	}
+}
+
+// fanOut publishes the record to NATS. It retries until the record is published or the pubsub is shut down,
+// a record is never skipped.
+func (p *kafkaPubSub) fanOut(r *kgo.Record, log *zap.Logger) error {
+	b := backoff.New(5*time.Second, 100*time.Millisecond)
+	defer b.Reset()
+
+	subject := p.fanOutSubject(r.Topic)
+
+	for {
+		err := p.consumer.Group.FanOut.Publish(subject, r.Value)
+		if err == nil {
+			return nil
+		}
+
+		log.Error("failed to fan out record, retrying", zap.Error(err), zap.String("subject", subject))
+
+		select {
+		case <-p.ctx.Done():
+			return p.ctx.Err()
+		case <-time.After(b.Duration()):
+		}
+	}
+}
+
+func (p *kafkaPubSub) fanOutSubject(topic string) string {
+	return p.consumer.Group.SubjectPrefix + "." + topic
+}
+
 func (p *kafkaPubSub) Shutdown(ctx context.Context) error {
 
 	err := p.writeClient.Flush(ctx)
@@ -232,5 +570,16 @@ func (p *kafkaPubSub) Shutdown(ctx context.Context) error {
 	// Wait until all pollers are closed
 	p.closeWg.Wait()
 
+	p.groupMu.Lock()
+	if p.groupClient != nil {
+		// Leaves the group, the partitions are assigned to the other routers
+		p.groupClient.Close()
+	}
+	p.groupMu.Unlock()
+
+	if p.consumer.Group != nil {
+		p.consumer.Group.FanOut.Close()
+	}
+
 	return err
 }
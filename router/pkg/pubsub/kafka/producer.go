@@ -0,0 +1,178 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/buger/jsonparser"
+	"github.com/tidwall/gjson"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+type Acks string
+
+const (
+	AcksAll    Acks = "all"
+	AcksLeader Acks = "leader"
+	AcksNone   Acks = "none"
+)
+
+type ProducerOptions struct {
+	Acks Acks
+	// Idempotent is nil to enable the idempotent producer if all acks are required
+	Idempotent  *bool
+	Compression string
+	Linger      time.Duration
+	// Keys are the record keys by topic
+	Keys map[string]RecordKey
+	// PropagateHeaders are the names of the request headers that are added to the records
+	PropagateHeaders []string
+	// RequestHeaders returns the headers of the client request that published the event
+	RequestHeaders func(ctx context.Context) http.Header
+}
+
+// RecordKey takes the key from a top-level argument or a JSON path into the arguments of the publish event
+type RecordKey struct {
+	Argument string
+	Path     string
+}
+
+// traceContext adds the W3C trace context of the publishing request to the records
+var traceContext = propagation.TraceContext{}
+
+// kgoOptions returns the options of the write client
+func (o ProducerOptions) kgoOptions() ([]kgo.Opt, error) {
+	var opts []kgo.Opt
+
+	switch o.Acks {
+	case "", AcksAll:
+		opts = append(opts, kgo.RequiredAcks(kgo.AllISRAcks()))
+	case AcksLeader:
+		opts = append(opts, kgo.RequiredAcks(kgo.LeaderAck()))
+	case AcksNone:
+		opts = append(opts, kgo.RequiredAcks(kgo.NoAck()))
+	default:
+		return nil, fmt.Errorf("unknown acks \"%s\"", o.Acks)
+	}
+
+	allAcks := o.Acks == "" || o.Acks == AcksAll
+	if o.Idempotent != nil && *o.Idempotent && !allAcks {
+		return nil, errors.New("the idempotent producer requires all acks")
+	}
+	if (o.Idempotent != nil && !*o.Idempotent) || !allAcks {
+		opts = append(opts, kgo.DisableIdempotentWrite())
+	}
+
+	switch o.Compression {
+	case "":
+	case "none":
+		opts = append(opts, kgo.ProducerBatchCompression(kgo.NoCompression()))
+	case "gzip":
+		opts = append(opts, kgo.ProducerBatchCompression(kgo.GzipCompression()))
+	case "snappy":
+		opts = append(opts, kgo.ProducerBatchCompression(kgo.SnappyCompression()))
+	case "lz4":
+		opts = append(opts, kgo.ProducerBatchCompression(kgo.Lz4Compression()))
+	case "zstd":
+		opts = append(opts, kgo.ProducerBatchCompression(kgo.ZstdCompression()))
+	default:
+		return nil, fmt.Errorf("unknown compression \"%s\"", o.Compression)
+	}
+
+	if o.Linger > 0 {
+		opts = append(opts, kgo.ProducerLinger(o.Linger))
+	}
+
+	return opts, nil
+}
+
+// recordKey returns the key of a record published to the topic. It's nil if no key is configured for the topic
+// or the argument is missing, the record is then written to any partition.
+func (o ProducerOptions) recordKey(topic string, data []byte) []byte {
+	key, ok := o.Keys[topic]
+	if !ok {
+		return nil
+	}
+
+	if key.Argument != "" {
+		value, dataType, _, err := jsonparser.Get(data, key.Argument)
+		if err != nil || dataType == jsonparser.Null {
+			return nil
+		}
+		// Strings are returned without quotes, other values as their JSON representation
+		return value
+	}
+
+	result := gjson.GetBytes(data, key.Path)
+	if !result.Exists() || result.Type == gjson.Null {
+		return nil
+	}
+	if result.Type == gjson.String {
+		return []byte(result.Str)
+	}
+	return []byte(result.Raw)
+}
+
+// recordHeaders returns the trace context and the propagated request headers of the publishing request
+func (o ProducerOptions) recordHeaders(ctx context.Context) []kgo.RecordHeader {
+	carrier := &recordHeaderCarrier{}
+	traceContext.Inject(ctx, carrier)
+
+	if len(o.PropagateHeaders) == 0 || o.RequestHeaders == nil {
+		return carrier.headers
+	}
+
+	requestHeaders := o.RequestHeaders(ctx)
+	if requestHeaders == nil {
+		return carrier.headers
+	}
+
+	for _, name := range o.PropagateHeaders {
+		for _, value := range requestHeaders.Values(name) {
+			carrier.headers = append(carrier.headers, kgo.RecordHeader{
+				Key:   name,
+				Value: []byte(value),
+			})
+		}
+	}
+
+	return carrier.headers
+}
+
+// assert that recordHeaderCarrier implements the TextMapCarrier interface
+var _ propagation.TextMapCarrier = (*recordHeaderCarrier)(nil)
+
+type recordHeaderCarrier struct {
+	headers []kgo.RecordHeader
+}
+
+func (c *recordHeaderCarrier) Get(key string) string {
+	for _, header := range c.headers {
+		if header.Key == key {
+			return string(header.Value)
+		}
+	}
+	return ""
+}
+
+func (c *recordHeaderCarrier) Set(key, value string) {
+	for i, header := range c.headers {
+		if header.Key == key {
+			c.headers[i].Value = []byte(value)
+			return
+		}
+	}
+	c.headers = append(c.headers, kgo.RecordHeader{Key: key, Value: []byte(value)})
+}
+
+func (c *recordHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(c.headers))
+	for _, header := range c.headers {
+		keys = append(keys, header.Key)
+	}
+	return keys
+}
@@ -0,0 +1,378 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/cloudflare/backoff"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/wundergraph/cosmo/router/pkg/otel"
+	"github.com/wundergraph/graphql-go-tools/v2/pkg/engine/datasource/pubsub_datasource"
+	"github.com/wundergraph/graphql-go-tools/v2/pkg/engine/resolve"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFetchBatchSize    = 100
+	defaultFetchMaxWait      = 5 * time.Second
+	defaultInactiveThreshold = 5 * time.Minute
+	// maxUnackedSequences bounds the sequences remembered for failed acknowledgements of a subscription
+	maxUnackedSequences = 1024
+)
+
+// JetStreamOptions configures the consumers of the subscriptions to JetStream streams
+type JetStreamOptions struct {
+	// EphemeralConsumers creates a consumer per subscription. Otherwise, the subscriptions share the durable
+	// consumer of the stream configuration and every message is delivered to one of them.
+	EphemeralConsumers bool
+	InactiveThreshold  time.Duration
+	AckPolicy          jetstream.AckPolicy
+	AckWait            time.Duration
+	MaxDeliver         int
+	DeliverPolicy      jetstream.DeliverPolicy
+	StartSequence      uint64
+	StartTime          time.Time
+	FetchBatchSize     int
+	FetchMaxWait       time.Duration
+}
+
+// ConsumerMetrics records the number of pending messages of the JetStream consumers
+type ConsumerMetrics interface {
+	MeasureJetStreamConsumerPending(ctx context.Context, delta int64, attr ...attribute.KeyValue)
+}
+
+func (o JetStreamOptions) consumerConfig(event pubsub_datasource.NatsSubscriptionEventConfiguration) jetstream.ConsumerConfig {
+	cfg := jetstream.ConsumerConfig{
+		FilterSubjects: event.Subjects,
+		AckPolicy:      o.AckPolicy,
+		AckWait:        o.AckWait,
+		MaxDeliver:     o.MaxDeliver,
+		DeliverPolicy:  o.DeliverPolicy,
+	}
+
+	switch o.DeliverPolicy {
+	case jetstream.DeliverByStartSequencePolicy:
+		cfg.OptStartSeq = o.StartSequence
+	case jetstream.DeliverByStartTimePolicy:
+		startTime := o.StartTime
+		cfg.OptStartTime = &startTime
+	}
+
+	if o.EphemeralConsumers {
+		cfg.InactiveThreshold = o.InactiveThreshold
+		if cfg.InactiveThreshold <= 0 {
+			cfg.InactiveThreshold = defaultInactiveThreshold
+		}
+	} else {
+		// Durable consumers are not removed automatically regardless of the InactiveThreshold
+		cfg.Durable = event.StreamConfiguration.Consumer
+	}
+
+	return cfg
+}
+
+// consumerKey identifies a consumer for the pending messages metric
+type consumerKey struct {
+	providerID string
+	stream     string
+	consumer   string
+}
+
+func (k consumerKey) attributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		otel.WgEventProviderID.String(k.providerID),
+		otel.WgNatsStream.String(k.stream),
+		otel.WgNatsConsumer.String(k.consumer),
+	}
+}
+
+// consumerPending tracks the last reported number of pending messages per consumer. Subscriptions that share
+// a durable consumer report the same value, so only the difference to the last report is added to the metric.
+type consumerPending struct {
+	metrics ConsumerMetrics
+	mu      sync.Mutex
+	pending map[consumerKey]int64
+}
+
+func (c *consumerPending) report(key consumerKey, pending int64) {
+	if c.metrics == nil {
+		return
+	}
+
+	c.mu.Lock()
+	delta := pending - c.pending[key]
+	c.pending[key] = pending
+	c.mu.Unlock()
+
+	if delta != 0 {
+		c.metrics.MeasureJetStreamConsumerPending(context.Background(), delta, key.attributes()...)
+	}
+}
+
+func (c *consumerPending) remove(key consumerKey) {
+	if c.metrics == nil {
+		return
+	}
+
+	c.mu.Lock()
+	pending, ok := c.pending[key]
+	delete(c.pending, key)
+	c.mu.Unlock()
+
+	if ok && pending != 0 {
+		c.metrics.MeasureJetStreamConsumerPending(context.Background(), -pending, key.attributes()...)
+	}
+}
+
+func (c *consumerPending) removeAll() {
+	c.mu.Lock()
+	keys := make([]consumerKey, 0, len(c.pending))
+	for key := range c.pending {
+		keys = append(keys, key)
+	}
+	c.mu.Unlock()
+
+	for _, key := range keys {
+		c.remove(key)
+	}
+}
+
+// jetStreamSubscription fetches the messages of a consumer and delivers them to the subscription updater.
+// The messages are acknowledged after they were delivered. If the consumer was deleted or the fetch failed,
+// the consumer is recreated with a backoff until the subscription ends.
+type jetStreamSubscription struct {
+	p        *natsPubSub
+	ctx      context.Context
+	event    pubsub_datasource.NatsSubscriptionEventConfiguration
+	config   jetstream.ConsumerConfig
+	updater  resolve.SubscriptionUpdater
+	log      *zap.Logger
+	consumer jetstream.Consumer
+	key      consumerKey
+	// unacked are the stream sequences of delivered messages whose acknowledgement failed. The server redelivers
+	// them, but the subscription has already received them. It is bounded by maxUnackedSequences.
+	unacked map[uint64]struct{}
+}
+
+func (p *natsPubSub) subscribeJetStream(ctx context.Context, event pubsub_datasource.NatsSubscriptionEventConfiguration, updater resolve.SubscriptionUpdater, log *zap.Logger) error {
+	s := &jetStreamSubscription{
+		p:       p,
+		ctx:     ctx,
+		event:   event,
+		config:  p.options.JetStream.consumerConfig(event),
+		updater: updater,
+		log:     log.With(zap.String("stream", event.StreamConfiguration.StreamName)),
+		unacked: make(map[uint64]struct{}),
+	}
+
+	if err := s.createConsumer(ctx); err != nil {
+		log.Error("error creating or updating consumer", zap.Error(err))
+		return err
+	}
+
+	p.closeWg.Add(1)
+
+	go func() {
+		defer p.closeWg.Done()
+
+		s.run()
+	}()
+
+	return nil
+}
+
+func (s *jetStreamSubscription) createConsumer(ctx context.Context) error {
+	consumer, err := s.p.js.CreateOrUpdateConsumer(ctx, s.event.StreamConfiguration.StreamName, s.config)
+	if err != nil {
+		return fmt.Errorf(`failed to create or update consumer for stream "%s": %w`, s.event.StreamConfiguration.StreamName, err)
+	}
+
+	s.consumer = consumer
+	s.key = consumerKey{
+		providerID: s.event.ProviderID,
+		stream:     s.event.StreamConfiguration.StreamName,
+		consumer:   consumer.CachedInfo().Name,
+	}
+
+	return nil
+}
+
+func (s *jetStreamSubscription) done() bool {
+	return s.p.ctx.Err() != nil || s.ctx.Err() != nil
+}
+
+// wait waits for the duration and returns false if the subscription ended in the meantime
+func (s *jetStreamSubscription) wait(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-s.p.ctx.Done():
+		return false
+	case <-s.ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func (s *jetStreamSubscription) run() {
+	defer s.close()
+
+	b := backoff.New(10*time.Second, 100*time.Millisecond)
+	defer b.Reset()
+
+	batchSize := s.p.options.JetStream.FetchBatchSize
+	if batchSize <= 0 {
+		batchSize = defaultFetchBatchSize
+	}
+	maxWait := s.p.options.JetStream.FetchMaxWait
+	if maxWait <= 0 {
+		maxWait = defaultFetchMaxWait
+	}
+
+	for !s.done() {
+		// The fetch waits until messages are available or the max wait expired
+		batch, err := s.consumer.Fetch(batchSize, jetstream.FetchMaxWait(maxWait))
+		if err == nil {
+			s.deliver(batch)
+			err = batch.Error()
+		}
+
+		if err == nil || errors.Is(err, nats.ErrTimeout) {
+			b.Reset()
+			continue
+		}
+
+		if errors.Is(err, nats.ErrConnectionClosed) || s.done() {
+			return
+		}
+
+		s.log.Warn("error fetching messages, recreating consumer", zap.Error(err))
+
+		if !s.wait(b.Duration()) {
+			return
+		}
+
+		s.p.pending.remove(s.key)
+
+		ctx, cancel := context.WithTimeout(s.ctx, maxWait)
+		err = s.createConsumer(ctx)
+		cancel()
+		if err != nil {
+			// The fetch of the previous consumer fails again and the consumer is recreated after the next backoff
+			s.log.Error("error recreating consumer", zap.Error(err))
+		}
+	}
+}
+
+func (s *jetStreamSubscription) deliver(batch jetstream.MessageBatch) {
+	var (
+		lastMsg   jetstream.Msg
+		lastMeta  *jetstream.MsgMetadata
+		sequences []uint64
+	)
+
+	for msg := range batch.Messages() {
+		meta, err := msg.Metadata()
+		if err != nil {
+			s.log.Error("error reading message metadata", zap.String("message_subject", msg.Subject()), zap.Error(err))
+		}
+
+		if meta != nil && meta.NumDelivered > 1 {
+			if _, ok := s.unacked[meta.Sequence.Stream]; ok {
+				// Only the acknowledgement was lost, the subscription has already received the message
+				delete(s.unacked, meta.Sequence.Stream)
+				s.ack(msg, meta.Sequence.Stream)
+				continue
+			}
+			s.log.Debug("message redelivered", zap.String("message_subject", msg.Subject()), zap.Uint64("num_delivered", meta.NumDelivered))
+		}
+
+		s.log.Debug("subscription update", zap.String("message_subject", msg.Subject()), zap.ByteString("data", msg.Data()))
+
+		s.updater.Update(msg.Data())
+
+		var sequence uint64
+		if meta != nil {
+			sequence = meta.Sequence.Stream
+			sequences = append(sequences, sequence)
+			lastMeta = meta
+		}
+
+		switch s.config.AckPolicy {
+		case jetstream.AckExplicitPolicy:
+			s.ack(msg, sequence)
+		case jetstream.AckAllPolicy:
+			lastMsg = msg
+		}
+	}
+
+	// Acknowledging the last message acknowledges all previous messages of the batch
+	if lastMsg != nil {
+		if err := lastMsg.Ack(); err != nil {
+			s.log.Error("error acknowledging messages", zap.Error(err))
+			for _, sequence := range sequences {
+				s.markUnacked(sequence)
+			}
+		}
+	}
+
+	if lastMeta != nil {
+		s.p.pending.report(s.key, int64(lastMeta.NumPending))
+	}
+}
+
+func (s *jetStreamSubscription) ack(msg jetstream.Msg, sequence uint64) {
+	if err := msg.Ack(); err != nil {
+		s.log.Error("error acknowledging message", zap.String("message_subject", msg.Subject()), zap.Error(err))
+		if sequence > 0 {
+			s.markUnacked(sequence)
+		}
+	}
+}
+
+// markUnacked remembers the sequence of a message whose acknowledgement failed. Messages whose acknowledgement
+// reached the server after all, or that reached the max deliveries, are never redelivered. To keep their sequences
+// from piling up, the oldest half is dropped once the limit is exceeded. A dropped message that is redelivered
+// is delivered to the subscription again.
+func (s *jetStreamSubscription) markUnacked(sequence uint64) {
+	s.unacked[sequence] = struct{}{}
+	if len(s.unacked) <= maxUnackedSequences {
+		return
+	}
+	sequences := make([]uint64, 0, len(s.unacked))
+	for unacked := range s.unacked {
+		sequences = append(sequences, unacked)
+	}
+	slices.Sort(sequences)
+	for _, unacked := range sequences[:len(sequences)-maxUnackedSequences/2] {
+		delete(s.unacked, unacked)
+	}
+}
+
+func (s *jetStreamSubscription) close() {
+	if !s.p.options.JetStream.EphemeralConsumers {
+		return
+	}
+
+	// The ephemeral consumer is only used by this subscription
+	s.p.pending.remove(s.key)
+
+	if s.p.conn.IsClosed() {
+		// The server removes the consumer after the inactive threshold
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.p.js.DeleteConsumer(ctx, s.key.stream, s.key.consumer); err != nil && !errors.Is(err, jetstream.ErrConsumerNotFound) {
+		s.log.Debug("error deleting ephemeral consumer", zap.String("consumer", s.key.consumer), zap.Error(err))
+	}
+}
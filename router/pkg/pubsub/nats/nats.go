@@ -20,17 +20,28 @@ var (
 	_ pubsub.Lifecycle                = (*natsPubSub)(nil)
 )
 
+type Options struct {
+	// PendingBufferSize is the number of messages buffered per subscription. Messages that don't fit into the
+	// buffer are dropped and reported as a slow consumer by the connection.
+	PendingBufferSize int
+	JetStream         JetStreamOptions
+	// Metrics records the pending messages of the JetStream consumers, it's optional
+	Metrics ConsumerMetrics
+}
+
 type connector struct {
-	conn   *nats.Conn
-	logger *zap.Logger
-	js     jetstream.JetStream
+	conn    *nats.Conn
+	logger  *zap.Logger
+	js      jetstream.JetStream
+	options Options
 }
 
-func NewConnector(logger *zap.Logger, conn *nats.Conn, js jetstream.JetStream) pubsub_datasource.NatsConnector {
+func NewConnector(logger *zap.Logger, conn *nats.Conn, js jetstream.JetStream, options Options) pubsub_datasource.NatsConnector {
 	return &connector{
-		conn:   conn,
-		logger: logger,
-		js:     js,
+		conn:    conn,
+		logger:  logger,
+		js:      js,
+		options: options,
 	}
 }
 
@@ -39,6 +50,11 @@ func (c *connector) New(ctx context.Context) pubsub_datasource.NatsPubSub {
 		ctx:     ctx,
 		conn:    c.conn,
 		js:      c.js,
+		options: c.options,
+		pending: &consumerPending{
+			metrics: c.options.Metrics,
+			pending: make(map[consumerKey]int64),
+		},
 		logger:  c.logger.With(zap.String("pubsub", "nats")),
 		closeWg: sync.WaitGroup{},
 	}
@@ -49,6 +65,8 @@ type natsPubSub struct {
 	conn    *nats.Conn
 	logger  *zap.Logger
 	js      jetstream.JetStream
+	options Options
+	pending *consumerPending
 	closeWg sync.WaitGroup
 }
 
@@ -60,58 +78,13 @@ func (p *natsPubSub) Subscribe(ctx context.Context, event pubsub_datasource.Nats
 	)
 
 	if event.StreamConfiguration != nil {
-		consumer, err := p.js.CreateOrUpdateConsumer(ctx, event.StreamConfiguration.StreamName, jetstream.ConsumerConfig{
-			Durable:        event.StreamConfiguration.Consumer, // Durable consumers are not removed automatically regardless of the InactiveThreshold
-			FilterSubjects: event.Subjects,
-		})
-		if err != nil {
-			log.Error("error creating or updating consumer", zap.Error(err))
+		if err := p.subscribeJetStream(ctx, event, updater, log); err != nil {
 			return pubsub.NewError(fmt.Sprintf(`failed to create or update consumer for stream "%s"`, event.StreamConfiguration.StreamName), err)
 		}
-
-		p.closeWg.Add(1)
-
-		go func() {
-
-			defer p.closeWg.Done()
-
-			for {
-				select {
-				case <-p.ctx.Done():
-					// When the application context is done, we stop the subscription
-					return
-
-				case <-ctx.Done():
-					// When the subscription context is done, we stop the subscription
-					return
-				default:
-					msgBatch, consumerFetchErr := consumer.FetchNoWait(300)
-					if consumerFetchErr != nil {
-						log.Error("error fetching messages", zap.Error(consumerFetchErr))
-						return
-					}
-
-					for msg := range msgBatch.Messages() {
-						log.Debug("subscription update", zap.String("message_subject", msg.Subject()), zap.ByteString("data", msg.Data()))
-
-						updater.Update(msg.Data())
-
-						// Acknowledge the message after it has been processed
-						ackErr := msg.Ack()
-						if ackErr != nil {
-							log.Error("error acknowledging message", zap.String("message_subject", msg.Subject()), zap.Error(ackErr))
-							return
-						}
-					}
-				}
-
-			}
-		}()
-
 		return nil
 	}
 
-	msgChan := make(chan *nats.Msg)
+	msgChan := make(chan *nats.Msg, p.options.PendingBufferSize)
 	subscriptions := make([]*nats.Subscription, len(event.Subjects))
 	for i, subject := range event.Subjects {
 		subscription, err := p.conn.ChanSubscribe(subject, msgChan)
@@ -225,5 +198,7 @@ func (p *natsPubSub) Shutdown(ctx context.Context) error {
 	// Wait for all subscriptions to be closed
 	p.closeWg.Wait()
 
+	p.pending.removeAll()
+
 	return err
 }